@@ -39,6 +39,7 @@ func newConfigureCmd() *cobra.Command {
 	cmd.Flags().String("cert", "", "certificate for mtls connection")
 	cmd.Flags().String("cert-key", "", "certificate key for mtls connection")
 	cmd.Flags().BoolP("insecure", "k", false, "insecure connection for mtls connection")
+	cmd.Flags().String("apisix-version", "", "APISIX version, e.g. 2.15.3, detected by sync if empty")
 
 	return cmd
 }
@@ -88,6 +89,11 @@ func saveConfiguration(cmd *cobra.Command) error {
 		color.Red("Failed to get insecure option: %v", err)
 		return err
 	}
+	rootConfig.Version, err = cmd.Flags().GetString("apisix-version")
+	if err != nil {
+		color.Red("Failed to get APISIX version: %v", err)
+		return err
+	}
 
 	if rootConfig.CAPath != "" {
 		if rootConfig.Certificate != "" && rootConfig.CertificateKey == "" {
@@ -197,6 +203,9 @@ func saveConfiguration(cmd *cobra.Command) error {
 	viper.Set("cert", rootConfig.Certificate)
 	viper.Set("cert-key", rootConfig.CertificateKey)
 	viper.Set("insecure", rootConfig.Insecure)
+	if rootConfig.Version != "" {
+		viper.Set("apisix-version", rootConfig.Version)
+	}
 
 	if overwrite {
 		// because WriteConfig fails to write if the file does not exist
@@ -23,5 +23,17 @@ func newDiffCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringArrayP("file", "f", []string{"apisix.yaml"}, "configuration file path")
+	cmd.Flags().StringSlice("only", nil, "only diff the resources of these types, e.g. route,service")
+	cmd.Flags().String("name", "", "only diff the resources whose identifiers match the glob, e.g. users-*")
+	cmd.Flags().String("name-regexp", "", "only diff the resources whose identifiers match the regular expression")
+	cmd.Flags().Duration("cache-ttl", 0, "reuse the remote configuration fetched within this duration, e.g. 5m, it is not cached by default")
+	cmd.Flags().Bool("refresh", false, "fetch the remote configuration even if it is cached")
+	cmd.Flags().StringToStringP("labels", "l", map[string]string{}, "only diff the resources with these labels, e.g. team=payments")
+	cmd.Flags().StringToString("prune-labels", map[string]string{}, "only delete the remote resources missing from the configuration with these labels, e.g. managed-by=adc")
+	cmd.Flags().Bool("phases", false, "print the differences in the order they would be applied, grouped by phase")
+	cmd.Flags().Bool("show-unchanged", false, "print the unchanged resources too")
+	cmd.Flags().Bool("split-plugins", false, "print the differences of each changed plugin separately")
+	cmd.Flags().Bool("side-by-side", false, "print the differences of updates in two columns, remote on the left and local on the right")
+	cmd.Flags().String("annotations", "auto", "emit GitHub Actions annotations for the changes and errors: auto, github or none, auto detects GitHub Actions")
 	return cmd
 }
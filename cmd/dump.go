@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/fatih/color"
@@ -130,6 +131,18 @@ func dumpConfiguration(cmd *cobra.Command) error {
 
 	upstreams = types.FilterResources(labels, upstreams)
 
+	secrets, err := cluster.Secret().List(context.Background())
+	if err != nil && !errors.Is(err, apisix.ErrNotFound) {
+		return err
+	}
+
+	protos, err := cluster.Proto().List(context.Background())
+	if err != nil {
+		return err
+	}
+
+	protos = types.FilterResources(labels, protos)
+
 	conf := &types.Configuration{
 		Routes:          routes,
 		Services:        svcs,
@@ -141,6 +154,8 @@ func dumpConfiguration(cmd *cobra.Command) error {
 		PluginMetadatas: pluginMetadatas,
 		StreamRoutes:    streamRoutes,
 		Upstreams:       upstreams,
+		Secrets:         secrets,
+		Protos:          protos,
 	}
 
 	if len(labels) > 0 {
@@ -23,6 +23,7 @@ type Config struct {
 
 var (
 	cfgFile    string
+	noColor    bool
 	rootConfig Config
 )
 
@@ -38,6 +39,7 @@ It can be used to validate, dump, diff, and sync configurations with an APISIX i
 	}
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.adc.yaml)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output, it's also disabled when NO_COLOR is set or the output is not a terminal")
 
 	rootCmd.AddCommand(newConfigureCmd())
 	rootCmd.AddCommand(newPingCmd())
@@ -61,6 +63,9 @@ func Execute() {
 }
 
 func initConfig() {
+	if noColor {
+		color.NoColor = true
+	}
 	if cfgFile == "" {
 		home, err := homedir.Dir()
 		if err != nil {
@@ -99,6 +104,7 @@ func initConfig() {
 	rootConfig.Certificate = viper.GetString("cert")
 	rootConfig.CertificateKey = viper.GetString("cert-key")
 	rootConfig.Insecure = viper.GetBool("insecure")
+	rootConfig.Version = viper.GetString("apisix-version")
 	cluster, err := apisix.NewCluster(context.Background(), rootConfig.ClientConfig)
 	if err != nil {
 		color.RedString("Failed to create a new cluster: %v", err.Error())
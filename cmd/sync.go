@@ -4,14 +4,18 @@ Copyright © 2023 API7.ai
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"strings"
-	"time"
+	"os"
+	"regexp"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"go.uber.org/multierr"
+	"golang.org/x/term"
 
 	"github.com/api7/adc/internal/pkg/differ"
+	"github.com/api7/adc/pkg/api/apisix"
 	"github.com/api7/adc/pkg/api/apisix/types"
 	"github.com/api7/adc/pkg/common"
 	"github.com/api7/adc/pkg/data"
@@ -34,6 +38,19 @@ func newSyncCmd() *cobra.Command {
 
 	cmd.Flags().StringArrayP("file", "f", []string{"apisix.yaml"}, "configuration file path")
 	cmd.Flags().BoolP("partial", "p", false, "partial apply mode. In partial mode, only add and update event will be applied.")
+	cmd.Flags().StringSlice("only", nil, "only sync the resources of these types, e.g. route,service")
+	cmd.Flags().String("name", "", "only sync the resources whose identifiers match the glob, e.g. users-*")
+	cmd.Flags().String("name-regexp", "", "only sync the resources whose identifiers match the regular expression")
+	cmd.Flags().StringToStringP("labels", "l", map[string]string{}, "only sync the resources with these labels, e.g. team=payments")
+	cmd.Flags().String("annotations", "auto", "emit GitHub Actions annotations for the changes and errors: auto, github or none, auto detects GitHub Actions")
+	cmd.Flags().String("webhook-url", "", "post a summary of the changes to this webhook after syncing")
+	cmd.Flags().String("webhook-template", "", "the text/template file of the webhook request body, the summary is posted as JSON by default")
+	cmd.Flags().StringToString("prune-labels", map[string]string{}, "only delete the remote resources missing from the configuration with these labels, e.g. managed-by=adc")
+	cmd.Flags().String("delete-mode", string(data.HardDelete), "how the removed resources are applied: delete, or disable to disable the routes and SSLs instead of deleting them")
+	cmd.Flags().Int("concurrency", data.DefaultConcurrency, "the maximum number of resources applied in parallel, 1 applies them one by one")
+	cmd.Flags().Float64("rate-limit", data.DefaultRateLimits.Default, "the maximum writes per second to the admin API, 0 disables the limit")
+	cmd.Flags().Float64("ssl-rate-limit", data.DefaultRateLimits.PerType[data.SSLResourceType], "the maximum SSL writes per second to the admin API, 0 disables the limit")
+	cmd.Flags().Bool("force", false, "update the unchanged resources too, e.g. to make APISIX re-read them after upgrading a plugin")
 
 	return cmd
 }
@@ -42,9 +59,94 @@ type summary struct {
 	created int
 	updated int
 	deleted int
+	// applied are the events applied to the cluster, and failure the error
+	// of the event that failed if any, for the notification of the sync
+	applied []*data.Event
+	failure error
 }
 
-func syncFile(dryRun, partial bool, file string) (*summary, error) {
+// syncOptions are the options of syncFile.
+type syncOptions struct {
+	dryRun  bool
+	partial bool
+	filters []data.Filter
+	// pruneLabels are the labels of the remote resources deleted when they
+	// are missing from the configuration, all of them are deleted if empty
+	pruneLabels map[string]string
+	// cache is the cache of the remote configuration, it is invalidated
+	// after applying the changes
+	cache   *common.RemoteCache
+	refresh bool
+	// phases prints the dry run plan by phase, see data.FormatPlan
+	phases bool
+	// unchanged prints the unchanged resources of the dry run too
+	unchanged bool
+	// force updates the unchanged resources too, instead of skipping them
+	force bool
+	// deleteMode is how the removed resources are applied, see
+	// data.DeleteMode
+	deleteMode data.DeleteMode
+	// splitPlugins prints the diff of each changed plugin separately
+	splitPlugins bool
+	// sideBySide prints the diffs in two columns, in the width of the
+	// terminal if it's one
+	sideBySide bool
+	// concurrency is the maximum number of events applied in parallel, see
+	// data.ApplyOptions
+	concurrency int
+	// rateLimiter limits the writes to the admin API, nil doesn't limit
+	// them
+	rateLimiter *data.RateLimiter
+	// version is the version of APISIX if versionDetected, see getVersion
+	version         data.Version
+	versionDetected bool
+	// color colors the output of the events, see data.OutputOptions
+	color bool
+	// notifier is notified of the applied changes if not nil
+	notifier *data.WebhookNotifier
+	// annotations prints the GitHub Actions annotations of the changes and
+	// errors, to stderr so that the output of the changes is unchanged, the
+	// runner reads the workflow commands of both
+	annotations bool
+}
+
+// annotate prints the GitHub Actions annotation of the event, if enabled.
+func (opts syncOptions) annotate(event *data.Event) {
+	if !opts.annotations {
+		return
+	}
+	annotation, err := event.Annotation()
+	if err != nil {
+		color.Yellow("Failed to get the annotation of the event: %v", err)
+		return
+	}
+	if annotation != "" {
+		fmt.Fprintln(os.Stderr, annotation)
+	}
+}
+
+// annotateError prints the GitHub Actions annotation of the error, if
+// enabled.
+func (opts syncOptions) annotateError(err error) {
+	if opts.annotations {
+		fmt.Fprintln(os.Stderr, data.ErrorAnnotation(err))
+	}
+}
+
+// notify sends the notification of the events applied by the sync, failing
+// to send it is only a warning.
+func (opts syncOptions) notify(applied []*data.Event, err error) {
+	if opts.notifier == nil {
+		return
+	}
+	if err := opts.notifier.Notify(context.Background(), data.NewNotification(applied, err)); err != nil {
+		color.Yellow("Failed to send the webhook notification: %v", err)
+	}
+}
+
+func syncFile(file string, opts syncOptions) (*summary, error) {
+	dryRun, partial := opts.dryRun, opts.partial
+
 	config, err := common.GetContentFromFile(file)
 	if err != nil {
 		color.Red("Failed to read configuration file: %v", err)
@@ -72,7 +174,7 @@ func syncFile(dryRun, partial bool, file string) (*summary, error) {
 		}
 	}
 
-	remoteConfig, err := common.GetContentFromRemote(rootConfig.APISIXCluster)
+	remoteConfig, err := getRemoteConfig(opts)
 	if err != nil {
 		color.Red("Failed to get remote configuration: %v", err)
 		return nil, err
@@ -83,12 +185,20 @@ func syncFile(dryRun, partial bool, file string) (*summary, error) {
 		color.Red("Failed to create a Differ object: %v", err)
 		return nil, err
 	}
+	d.ShowUnchanged = opts.unchanged || opts.force
+	// the remote resources missing from the configuration are deleted,
+	// unless in partial mode
+	d.Prune = !partial
+	if len(opts.pruneLabels) > 0 {
+		d.PruneFilter = data.ByLabels(opts.pruneLabels)
+	}
 
 	events, err := d.Diff()
 	if err != nil {
 		color.Red("Failed to compare local and remote configuration: %v", err)
 		return nil, err
 	}
+	events = data.FilterEvents(events, opts.filters...)
 
 	summary := &summary{
 		created: 0,
@@ -96,47 +206,183 @@ func syncFile(dryRun, partial bool, file string) (*summary, error) {
 		deleted: 0,
 	}
 
+	if !dryRun && opts.cache != nil {
+		// the cluster is changed, even if applying fails halfway
+		defer func() {
+			if err := opts.cache.Invalidate(rootConfig.Server); err != nil {
+				color.Yellow("Failed to invalidate the cache of the remote configuration: %v", err)
+			}
+		}()
+	}
+
+	cluster := data.NewRateLimitedCluster(rootConfig.APISIXCluster, opts.rateLimiter)
+	if opts.versionDetected {
+		cluster = data.NewVersionedCluster(cluster, opts.version)
+	}
+	var planned, pending []*data.Event
+	outputs := make(map[*data.Event]string)
 	for _, event := range events {
+		noop, err := event.IsNoOp()
+		if err != nil {
+			color.Red("Failed to compare the event: %v", err)
+			return nil, err
+		}
+		forced := noop && opts.force
+		if noop {
+			if !opts.unchanged && !forced {
+				continue
+			}
+			event = &data.Event{
+				ResourceType: event.ResourceType,
+				Option:       data.NoOpOption,
+				OldValue:     event.OldValue,
+				Value:        event.Value,
+			}
+		}
+
 		if event.Option == data.CreateOption {
 			summary.created++
-		} else if event.Option == data.UpdateOption {
+		} else if event.Option == data.UpdateOption || forced {
 			summary.updated++
 		} else if event.Option == data.DeleteOption {
 			if partial {
 				continue
 			}
-			summary.deleted++
+			if disabled, ok := event.Disable(); ok && opts.deleteMode == data.DisableDelete {
+				event = &disabled
+				summary.updated++
+			} else {
+				summary.deleted++
+			}
 		}
 
-		str, err := event.Output(dryRun)
+		for _, warning := range event.Warnings() {
+			color.Yellow(warning)
+		}
+
+		if dryRun && opts.phases {
+			planned = append(planned, event)
+			continue
+		}
+
+		str, err := event.OutputWithOptions(&data.OutputOptions{
+			DiffOnly:     dryRun,
+			SplitPlugins: opts.splitPlugins,
+			SideBySide:   opts.sideBySide,
+			Width:        terminalWidth(),
+			Color:        opts.color,
+		})
 		if err != nil {
 			color.Red("Failed to get output of the event: %v", err)
 			return nil, err
 		}
+		if forced {
+			str += " (forced update)"
+		}
 
 		if !dryRun {
-			err = event.Apply(rootConfig.APISIXCluster)
-			if err != nil {
-				color.Red("Failed to apply configuration: %v", err)
-				return nil, err
-			}
-			time.Sleep(100 * time.Millisecond)
+			// printed once applied
+			pending = append(pending, event)
+			outputs[event] = str
+			continue
 		}
 
-		for _, line := range strings.Split(str, "\n") {
-			if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "creating") {
-				color.Green(line)
-			} else if strings.HasPrefix(line, "-") || strings.HasPrefix(line, "deleting") {
-				color.Red(line)
-			} else {
-				fmt.Println(line)
-			}
+		fmt.Println(str)
+		opts.annotate(event)
+	}
+
+	if len(pending) > 0 {
+		summary.applied, err = applyEvents(cluster, pending, outputs, opts)
+		if err != nil {
+			summary.failure = err
+			return summary, err
+		}
+	}
+
+	if len(planned) > 0 {
+		str, err := data.FormatPlan(planned, &data.OutputOptions{DiffOnly: true, Color: opts.color})
+		if err != nil {
+			color.Red("Failed to get output of the plan: %v", err)
+			return nil, err
+		}
+		fmt.Println(str)
+		for _, event := range planned {
+			opts.annotate(event)
 		}
 	}
 
 	return summary, nil
 }
 
+// applyEvents applies the events to the cluster with
+// data.ApplyAllWithResults, it stops at the first failure. The outputs of
+// the applied events are printed in the order they are applied, see
+// data.SortEvents. It returns the applied events and the failures.
+func applyEvents(cluster apisix.Cluster, events []*data.Event, outputs map[*data.Event]string, opts syncOptions) ([]*data.Event, error) {
+	results, err := data.ApplyAllWithResults(context.Background(), cluster, events, data.ApplyOptions{
+		Concurrency: opts.concurrency,
+		Retry:       &data.DefaultRetryPolicy,
+		Force:       opts.force,
+	})
+	var applied []*data.Event
+	for _, result := range results {
+		switch result.Status {
+		case data.AppliedStatus, data.SkippedStatus:
+			applied = append(applied, result.Event)
+			fmt.Println(outputs[result.Event])
+			opts.annotate(result.Event)
+		case data.FailedStatus:
+			color.Red("Failed to apply configuration: %v", result.Err)
+		}
+	}
+	if err != nil {
+		opts.annotateError(err)
+	}
+	return applied, err
+}
+
+// getVersion returns the configured version of APISIX, or detects it, and
+// switches the cluster to the admin API of the version. It only warns if
+// the version is unknown.
+func getVersion() (data.Version, bool) {
+	var (
+		version data.Version
+		err     error
+	)
+	if rootConfig.Version != "" {
+		version, err = data.ParseVersion(rootConfig.Version)
+	} else {
+		version, err = data.DetectVersion(context.Background(), rootConfig.APISIXCluster)
+	}
+	if err != nil {
+		color.Yellow("Failed to detect the version of APISIX: %v", err)
+		return version, false
+	}
+	if setter, ok := rootConfig.APISIXCluster.(apisix.AdminAPISetter); ok {
+		setter.SetAdminAPIVersion(version.Major)
+	}
+	return version, true
+}
+
+// terminalWidth returns the width of the terminal of stdout, 0 if it isn't
+// a terminal.
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// getRemoteConfig returns the configuration of the cluster, from the cache
+// if it is fresh.
+func getRemoteConfig(opts syncOptions) (*types.Configuration, error) {
+	if opts.cache == nil {
+		return common.GetContentFromRemote(rootConfig.APISIXCluster)
+	}
+	return common.GetContentFromRemoteCached(context.Background(), rootConfig.APISIXCluster, opts.cache, rootConfig.Server, opts.refresh)
+}
+
 func sync(cmd *cobra.Command, dryRun bool) error {
 	files, err := cmd.Flags().GetStringArray("file")
 	if err != nil {
@@ -162,16 +408,112 @@ func sync(cmd *cobra.Command, dryRun bool) error {
 		partial = true
 	}
 
+	filters, err := getFilters(cmd)
+	if err != nil {
+		color.Red("Failed to get the filters: %v", err)
+		return err
+	}
+
+	pruneLabels, err := cmd.Flags().GetStringToString("prune-labels")
+	if err != nil {
+		color.Red("Failed to get prune-labels option: %v", err)
+		return err
+	}
+
+	opts := syncOptions{
+		dryRun:      dryRun,
+		partial:     partial,
+		filters:     filters,
+		pruneLabels: pruneLabels,
+		color:       !noColor && data.ColorEnabled(os.Stdout),
+	}
+	if dryRun {
+		opts.phases, err = cmd.Flags().GetBool("phases")
+		if err != nil {
+			color.Red("Failed to get phases option: %v", err)
+			return err
+		}
+		opts.unchanged, err = cmd.Flags().GetBool("show-unchanged")
+		if err != nil {
+			color.Red("Failed to get show-unchanged option: %v", err)
+			return err
+		}
+		opts.splitPlugins, err = cmd.Flags().GetBool("split-plugins")
+		if err != nil {
+			color.Red("Failed to get split-plugins option: %v", err)
+			return err
+		}
+		opts.sideBySide, err = cmd.Flags().GetBool("side-by-side")
+		if err != nil {
+			color.Red("Failed to get side-by-side option: %v", err)
+			return err
+		}
+	}
+	if !dryRun {
+		opts.force, err = cmd.Flags().GetBool("force")
+		if err != nil {
+			color.Red("Failed to get force option: %v", err)
+			return err
+		}
+		opts.deleteMode, err = getDeleteMode(cmd)
+		if err != nil {
+			color.Red("Failed to get delete-mode option: %v", err)
+			return err
+		}
+		opts.concurrency, err = cmd.Flags().GetInt("concurrency")
+		if err != nil {
+			color.Red("Failed to get concurrency option: %v", err)
+			return err
+		}
+		opts.rateLimiter, err = getRateLimiter(cmd)
+		if err != nil {
+			color.Red("Failed to get the rate limit options: %v", err)
+			return err
+		}
+		opts.notifier, err = getNotifier(cmd)
+		if err != nil {
+			color.Red("Failed to get the webhook options: %v", err)
+			return err
+		}
+	}
+	opts.annotations, err = getAnnotations(cmd)
+	if err != nil {
+		color.Red("Failed to get annotations option: %v", err)
+		return err
+	}
+	opts.cache, opts.refresh, err = getCache(cmd, dryRun)
+	if err != nil {
+		color.Yellow("Failed to get the cache of the remote configuration: %v", err)
+	}
+
+	// the version is detected once for all the files, before getting their
+	// remote configuration, since the admin API of APISIX 2.x is different
+	opts.version, opts.versionDetected = getVersion()
+
 	summary := &summary{
 		created: 0,
 		updated: 0,
 		deleted: 0,
 	}
 
+	// the notification summarizes the sync of all the files
+	var (
+		applied  []*data.Event
+		failures error
+	)
 	for _, file := range files {
-		sum, err := syncFile(dryRun, partial, file)
+		sum, err := syncFile(file, opts)
+		if sum != nil {
+			applied = append(applied, sum.applied...)
+		}
 		if err != nil {
 			color.Red("failed to sync file %v, error: %v", file, err)
+			if sum != nil && sum.failure != nil {
+				err = sum.failure
+			} else {
+				err = fmt.Errorf("failed to sync file %v: %w", file, err)
+			}
+			failures = multierr.Append(failures, err)
 			continue
 		}
 
@@ -179,6 +521,9 @@ func sync(cmd *cobra.Command, dryRun bool) error {
 		summary.updated += sum.updated
 		summary.deleted += sum.deleted
 	}
+	if !dryRun {
+		opts.notify(applied, failures)
+	}
 
 	if dryRun {
 		color.Green("Summary: create %d, update %d, delete %d", summary.created, summary.updated, summary.deleted)
@@ -188,3 +533,145 @@ func sync(cmd *cobra.Command, dryRun bool) error {
 
 	return nil
 }
+
+// getCache returns the cache of the remote configuration. Only diff reads
+// it, sync always fetches the cluster and invalidates the cache instead.
+func getCache(cmd *cobra.Command, dryRun bool) (*common.RemoteCache, bool, error) {
+	if !dryRun {
+		cache, err := common.NewRemoteCache(0)
+		return cache, false, err
+	}
+
+	ttl, err := cmd.Flags().GetDuration("cache-ttl")
+	if err != nil {
+		return nil, false, err
+	}
+	refresh, err := cmd.Flags().GetBool("refresh")
+	if err != nil {
+		return nil, false, err
+	}
+	if ttl <= 0 {
+		return nil, false, nil
+	}
+	cache, err := common.NewRemoteCache(ttl)
+	return cache, refresh, err
+}
+
+// getDeleteMode returns the delete mode of the --delete-mode flag.
+func getDeleteMode(cmd *cobra.Command) (data.DeleteMode, error) {
+	mode, err := cmd.Flags().GetString("delete-mode")
+	if err != nil {
+		return "", err
+	}
+	return data.ParseDeleteMode(mode)
+}
+
+// getRateLimiter returns the limiter of the writes to the admin API, nil
+// if all the limits are disabled.
+func getRateLimiter(cmd *cobra.Command) (*data.RateLimiter, error) {
+	limit, err := cmd.Flags().GetFloat64("rate-limit")
+	if err != nil {
+		return nil, err
+	}
+	sslLimit, err := cmd.Flags().GetFloat64("ssl-rate-limit")
+	if err != nil {
+		return nil, err
+	}
+	return data.NewRateLimiter(data.RateLimits{
+		Default: limit,
+		PerType: map[data.ResourceType]float64{
+			data.SSLResourceType: sslLimit,
+		},
+	}), nil
+}
+
+// getAnnotations reports whether to print the GitHub Actions annotations,
+// "auto" prints them in GitHub Actions only.
+func getAnnotations(cmd *cobra.Command) (bool, error) {
+	mode, err := cmd.Flags().GetString("annotations")
+	if err != nil {
+		return false, err
+	}
+	switch mode {
+	case "auto":
+		return data.GitHubActions(), nil
+	case "github":
+		return true, nil
+	case "none":
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown annotations mode %q, must be auto, github or none", mode)
+}
+
+// getNotifier returns the webhook notifier of the sync, nil without webhook.
+func getNotifier(cmd *cobra.Command) (*data.WebhookNotifier, error) {
+	url, err := cmd.Flags().GetString("webhook-url")
+	if err != nil || url == "" {
+		return nil, err
+	}
+	notifier := &data.WebhookNotifier{URL: url}
+
+	path, err := cmd.Flags().GetString("webhook-template")
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		tmpl, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		notifier.Template = string(tmpl)
+	}
+	return notifier, nil
+}
+
+// getFilters returns the filters selecting the events to sync.
+func getFilters(cmd *cobra.Command) ([]data.Filter, error) {
+	var filters []data.Filter
+
+	only, err := cmd.Flags().GetStringSlice("only")
+	if err != nil {
+		return nil, err
+	}
+	if len(only) > 0 {
+		types, err := data.ParseResourceTypes(only)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, data.ByResourceType(types...))
+	}
+
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		filter, err := data.ByNameGlob(name)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	expr, err := cmd.Flags().GetString("name-regexp")
+	if err != nil {
+		return nil, err
+	}
+	if expr != "" {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, data.ByName(re))
+	}
+
+	labels, err := cmd.Flags().GetStringToString("labels")
+	if err != nil {
+		return nil, err
+	}
+	if len(labels) > 0 {
+		filters = append(filters, data.ByLabels(labels))
+	}
+
+	return filters, nil
+}
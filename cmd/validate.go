@@ -99,6 +99,14 @@ func displayConfigOverview(d *types.Configuration) {
 		msg += fmt.Sprintf(", upstreams: %v", len(d.StreamRoutes))
 		changed = true
 	}
+	if len(d.Secrets) > 0 {
+		msg += fmt.Sprintf(", secrets: %v", len(d.Secrets))
+		changed = true
+	}
+	if len(d.Protos) > 0 {
+		msg += fmt.Sprintf(", protos: %v", len(d.Protos))
+		changed = true
+	}
 	if !changed {
 		msg += "nothing changed"
 	}
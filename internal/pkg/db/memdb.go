@@ -111,6 +111,26 @@ var schema = &memdb.DBSchema{
 				},
 			},
 		},
+		"secrets": {
+			Name: "secrets",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": {
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+			},
+		},
+		"protos": {
+			Name: "protos",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": {
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+			},
+		},
 	},
 }
 
@@ -202,6 +222,20 @@ func NewMemDB(config *types.Configuration) (*DB, error) {
 		}
 	}
 
+	for _, secret := range config.Secrets {
+		err = txn.Insert("secrets", secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, proto := range config.Protos {
+		err = txn.Insert("protos", proto)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	txn.Commit()
 
 	return &DB{memDB: db}, nil
@@ -259,3 +293,11 @@ func (db *DB) GetStreamRouteByID(id string) (*types.StreamRoute, error) {
 func (db *DB) GetUpstreamByID(id string) (*types.Upstream, error) {
 	return getByID[types.Upstream](db, "upstreams", id)
 }
+
+func (db *DB) GetSecretByID(id string) (*types.Secret, error) {
+	return getByID[types.Secret](db, "secrets", id)
+}
+
+func (db *DB) GetProtoByID(id string) (*types.Proto, error) {
+	return getByID[types.Proto](db, "protos", id)
+}
@@ -1,71 +1,26 @@
 package differ
 
 import (
-	"fmt"
-	"reflect"
-	"sort"
-
 	"github.com/api7/adc/internal/pkg/db"
 	"github.com/api7/adc/pkg/api/apisix/types"
 	"github.com/api7/adc/pkg/data"
 )
 
-var _orderIndex = -1
-
-func _order() int {
-	_orderIndex += 1
-	return _orderIndex
-}
-
-func _key(typ data.ResourceType, option int) string {
-	return fmt.Sprintf("%s:%d", typ, option)
-}
-
-// order is the events order to ensure the data dependency. Higher takes priority
-// stream route requires: upstream, service.
-// service requires: upstream (shouldn't)
-// route requires: service, plugin config, consumer (soft require), upstream (shouldn't, use service instead)
-// consumer requires: consumer group
-// The dependent resources should be created/updated first but deleted later
-var order = map[string]int{
-	_key(data.UpstreamResourceType, data.DeleteOption):      _order(),
-	_key(data.ServiceResourceType, data.DeleteOption):       _order(),
-	_key(data.PluginConfigResourceType, data.DeleteOption):  _order(),
-	_key(data.ConsumerGroupResourceType, data.DeleteOption): _order(),
-	_key(data.ConsumerResourceType, data.DeleteOption):      _order(),
-	_key(data.StreamRouteResourceType, data.DeleteOption):   _order(),
-	_key(data.RouteResourceType, data.DeleteOption):         _order(),
-
-	_key(data.RouteResourceType, data.UpdateOption):         _order(),
-	_key(data.StreamRouteResourceType, data.UpdateOption):   _order(),
-	_key(data.ServiceResourceType, data.UpdateOption):       _order(),
-	_key(data.UpstreamResourceType, data.UpdateOption):      _order(),
-	_key(data.PluginConfigResourceType, data.UpdateOption):  _order(),
-	_key(data.ConsumerResourceType, data.UpdateOption):      _order(),
-	_key(data.ConsumerGroupResourceType, data.UpdateOption): _order(),
-
-	_key(data.RouteResourceType, data.CreateOption):         _order(),
-	_key(data.StreamRouteResourceType, data.CreateOption):   _order(),
-	_key(data.ServiceResourceType, data.CreateOption):       _order(),
-	_key(data.UpstreamResourceType, data.CreateOption):      _order(),
-	_key(data.PluginConfigResourceType, data.CreateOption):  _order(),
-	_key(data.ConsumerResourceType, data.CreateOption):      _order(),
-	_key(data.ConsumerGroupResourceType, data.CreateOption): _order(),
-
-	// no dependency
-	_key(data.SSLResourceType, data.DeleteOption):            _order(),
-	_key(data.SSLResourceType, data.CreateOption):            _order(),
-	_key(data.SSLResourceType, data.UpdateOption):            _order(),
-	_key(data.GlobalRuleResourceType, data.DeleteOption):     _order(),
-	_key(data.GlobalRuleResourceType, data.CreateOption):     _order(),
-	_key(data.GlobalRuleResourceType, data.UpdateOption):     _order(),
-	_key(data.PluginMetadataResourceType, data.DeleteOption): _order(),
-	_key(data.PluginMetadataResourceType, data.CreateOption): _order(),
-	_key(data.PluginMetadataResourceType, data.UpdateOption): _order(),
-}
-
 // Differ is the object of comparing two configurations.
 type Differ struct {
+	// ShowUnchanged reports the resources equal in both configurations with
+	// data.NoOpOption events, they are omitted by default.
+	ShowUnchanged bool
+	// Prune deletes the remote resources missing from the local
+	// configuration with data.DeleteOption events. They are kept by
+	// default, so that the resources not managed by adc are never deleted
+	// by accident.
+	Prune bool
+	// PruneFilter selects the remote resources deleted by Prune if not nil,
+	// e.g. data.ByLabels to only delete the resources labeled as managed by
+	// adc. The others are kept.
+	PruneFilter data.Filter
+
 	localDB      *db.DB
 	localConfig  *types.Configuration
 	remoteConfig *types.Configuration
@@ -85,11 +40,31 @@ func NewDiffer(local, remote *types.Configuration) (*Differ, error) {
 	}, nil
 }
 
-// sortEvents sorts events descending, higher priority events will be executed first
+// DiffEvents compares the local and remote resources given as create
+// events, e.g. loaded with data.LoadFile and dumped with data.DumpCluster,
+// and returns the events applying the local resources to the remote. The
+// remote resources missing from local are deleted, see Differ.Prune.
+func DiffEvents(local, remote []*data.Event) ([]*data.Event, error) {
+	localConfig, err := data.ToConfiguration(local)
+	if err != nil {
+		return nil, err
+	}
+	remoteConfig, err := data.ToConfiguration(remote)
+	if err != nil {
+		return nil, err
+	}
+	d, err := NewDiffer(localConfig, remoteConfig)
+	if err != nil {
+		return nil, err
+	}
+	d.Prune = true
+	return d.Diff()
+}
+
+// sortEvents sorts events in place, higher priority events will be executed first.
+// Events of the same priority are sorted by name so the output is reproducible.
 func sortEvents(events []*data.Event) {
-	sort.Slice(events, func(i, j int) bool {
-		return order[_key(events[i].ResourceType, events[i].Option)] > order[_key(events[j].ResourceType, events[j].Option)]
-	})
+	copy(events, data.SortEventsByKey(events))
 }
 
 // Diff compares the local configuration and remote configuration, and returns the events.
@@ -147,6 +122,16 @@ func (d *Differ) Diff() ([]*data.Event, error) {
 		return nil, err
 	}
 
+	secretEvents, err := d.diffSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	protoEvents, err := d.diffProtos()
+	if err != nil {
+		return nil, err
+	}
+
 	events = append(events, serviceEvents...)
 	events = append(events, routeEvents...)
 	events = append(events, consumerEvents...)
@@ -157,12 +142,50 @@ func (d *Differ) Diff() ([]*data.Event, error) {
 	events = append(events, consumerGroupEvents...)
 	events = append(events, streamRouteEvents...)
 	events = append(events, upstreamEvents...)
+	events = append(events, secretEvents...)
+	events = append(events, protoEvents...)
 
+	events = data.FilterEvents(events, d.pruned)
 	sortEvents(events)
 
 	return events, nil
 }
 
+// pruned reports whether the event is kept by Prune and PruneFilter, only
+// deletes are filtered.
+func (d *Differ) pruned(event *data.Event) bool {
+	if event.Option != data.DeleteOption {
+		return true
+	}
+	return d.Prune && (d.PruneFilter == nil || d.PruneFilter(event))
+}
+
+// equal reports whether the local resource is the same as the remote one,
+// with the canonical comparison of data.Event.IsNoOp, so that the differ
+// and data.Diff agree on the unchanged resources.
+func (d *Differ) equal(typ data.ResourceType, remote, local interface{}) (bool, error) {
+	return (&data.Event{
+		ResourceType: typ,
+		Option:       data.UpdateOption,
+		OldValue:     remote,
+		Value:        local,
+	}).IsNoOp()
+}
+
+// unchanged appends the noop event of the resource equal in both
+// configurations if ShowUnchanged is set.
+func (d *Differ) unchanged(events []*data.Event, typ data.ResourceType, remote, local interface{}) []*data.Event {
+	if !d.ShowUnchanged {
+		return events
+	}
+	return append(events, &data.Event{
+		ResourceType: typ,
+		Option:       data.NoOpOption,
+		OldValue:     remote,
+		Value:        local,
+	})
+}
+
 // diffService compares the services between local and remote.
 func (d *Differ) diffServices() ([]*data.Event, error) {
 	var events []*data.Event
@@ -189,7 +212,12 @@ func (d *Differ) diffServices() ([]*data.Event, error) {
 		mark[localSvc.ID] = true
 		// If the service is equal, we don't need to add an event.
 		// Else, we use the local service to update the remote service.
-		if equal := reflect.DeepEqual(localSvc, remoteSvc); equal {
+		equal, err := d.equal(data.ServiceResourceType, remoteSvc, localSvc)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			events = d.unchanged(events, data.ServiceResourceType, remoteSvc, localSvc)
 			continue
 		}
 
@@ -243,7 +271,12 @@ func (d *Differ) diffRoutes() ([]*data.Event, error) {
 		mark[localRoute.ID] = true
 		// If the route is equal, we don't need to add an event.
 		// Else, we use the local routes to update the remote routes.
-		if equal := reflect.DeepEqual(localRoute, remoteRoute); equal {
+		equal, err := d.equal(data.RouteResourceType, remoteRoute, localRoute)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			events = d.unchanged(events, data.RouteResourceType, remoteRoute, localRoute)
 			continue
 		}
 
@@ -295,7 +328,12 @@ func (d *Differ) diffConsumers() ([]*data.Event, error) {
 
 		mark[localConsumer.Username] = true
 		// skip when equals
-		if equal := reflect.DeepEqual(localConsumer, remoteConsumers); equal {
+		equal, err := d.equal(data.ConsumerResourceType, remoteConsumers, localConsumer)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			events = d.unchanged(events, data.ConsumerResourceType, remoteConsumers, localConsumer)
 			continue
 		}
 
@@ -348,7 +386,12 @@ func (d *Differ) diffSSLs() ([]*data.Event, error) {
 
 		mark[localSSL.ID] = true
 		// skip when equals
-		if equal := reflect.DeepEqual(localSSL, remoteSSL); equal {
+		equal, err := d.equal(data.SSLResourceType, remoteSSL, localSSL)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			events = d.unchanged(events, data.SSLResourceType, remoteSSL, localSSL)
 			continue
 		}
 
@@ -400,7 +443,12 @@ func (d *Differ) diffGlobalRules() ([]*data.Event, error) {
 
 		mark[localGlobalRule.ID] = true
 		// skip when equals
-		if equal := reflect.DeepEqual(localGlobalRule, remoteGlobalRule); equal {
+		equal, err := d.equal(data.GlobalRuleResourceType, remoteGlobalRule, localGlobalRule)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			events = d.unchanged(events, data.GlobalRuleResourceType, remoteGlobalRule, localGlobalRule)
 			continue
 		}
 
@@ -453,7 +501,12 @@ func (d *Differ) diffPluginConfigs() ([]*data.Event, error) {
 
 		mark[localPluginConfig.ID] = true
 		// skip when equals
-		if equal := reflect.DeepEqual(localPluginConfig, remotePluginConfig); equal {
+		equal, err := d.equal(data.PluginConfigResourceType, remotePluginConfig, localPluginConfig)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			events = d.unchanged(events, data.PluginConfigResourceType, remotePluginConfig, localPluginConfig)
 			continue
 		}
 
@@ -506,7 +559,12 @@ func (d *Differ) diffConsumerGroups() ([]*data.Event, error) {
 
 		mark[localConsumerGroup.ID] = true
 		// skip when equals
-		if equal := reflect.DeepEqual(localConsumerGroup, remoteConsumerGroup); equal {
+		equal, err := d.equal(data.ConsumerGroupResourceType, remoteConsumerGroup, localConsumerGroup)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			events = d.unchanged(events, data.ConsumerGroupResourceType, remoteConsumerGroup, localConsumerGroup)
 			continue
 		}
 
@@ -559,7 +617,12 @@ func (d *Differ) diffPluginMetadata() ([]*data.Event, error) {
 
 		mark[localPluginMetadata.ID] = true
 		// skip when equals
-		if equal := reflect.DeepEqual(localPluginMetadata, remotePluginMetadata); equal {
+		equal, err := d.equal(data.PluginMetadataResourceType, remotePluginMetadata, localPluginMetadata)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			events = d.unchanged(events, data.PluginMetadataResourceType, remotePluginMetadata, localPluginMetadata)
 			continue
 		}
 
@@ -612,7 +675,12 @@ func (d *Differ) diffStreamRoutes() ([]*data.Event, error) {
 
 		mark[localStreamRoute.ID] = true
 		// skip when equals
-		if equal := reflect.DeepEqual(localStreamRoute, remoteStreamRoute); equal {
+		equal, err := d.equal(data.StreamRouteResourceType, remoteStreamRoute, localStreamRoute)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			events = d.unchanged(events, data.StreamRouteResourceType, remoteStreamRoute, localStreamRoute)
 			continue
 		}
 
@@ -665,7 +733,12 @@ func (d *Differ) diffUpstreams() ([]*data.Event, error) {
 
 		mark[localUpstream.ID] = true
 		// skip when equals
-		if equal := reflect.DeepEqual(localUpstream, remoteUpstream); equal {
+		equal, err := d.equal(data.UpstreamResourceType, remoteUpstream, localUpstream)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			events = d.unchanged(events, data.UpstreamResourceType, remoteUpstream, localUpstream)
 			continue
 		}
 
@@ -693,3 +766,119 @@ func (d *Differ) diffUpstreams() ([]*data.Event, error) {
 
 	return events, nil
 }
+
+// diffSecrets compares the Secrets between local and remote.
+func (d *Differ) diffSecrets() ([]*data.Event, error) {
+	var events []*data.Event
+	var mark = make(map[string]bool)
+
+	for _, remoteSecret := range d.remoteConfig.Secrets {
+		localSecret, err := d.localDB.GetSecretByID(remoteSecret.ID)
+		if err != nil {
+			// we can't find in local config, should delete it
+			if err == db.NotFound {
+				e := data.Event{
+					ResourceType: data.SecretResourceType,
+					Option:       data.DeleteOption,
+					OldValue:     remoteSecret,
+				}
+				events = append(events, &e)
+				continue
+			}
+
+			return nil, err
+		}
+
+		mark[localSecret.ID] = true
+		// skip when equals
+		equal, err := d.equal(data.SecretResourceType, remoteSecret, localSecret)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			events = d.unchanged(events, data.SecretResourceType, remoteSecret, localSecret)
+			continue
+		}
+
+		// otherwise update
+		events = append(events, &data.Event{
+			ResourceType: data.SecretResourceType,
+			Option:       data.UpdateOption,
+			OldValue:     remoteSecret,
+			Value:        localSecret,
+		})
+	}
+
+	// only in local, create
+	for _, secret := range d.localConfig.Secrets {
+		if mark[secret.ID] {
+			continue
+		}
+
+		events = append(events, &data.Event{
+			ResourceType: data.SecretResourceType,
+			Option:       data.CreateOption,
+			Value:        secret,
+		})
+	}
+
+	return events, nil
+}
+
+// diffProtos compares the Protos between local and remote.
+func (d *Differ) diffProtos() ([]*data.Event, error) {
+	var events []*data.Event
+	var mark = make(map[string]bool)
+
+	for _, remoteProto := range d.remoteConfig.Protos {
+		localProto, err := d.localDB.GetProtoByID(remoteProto.ID)
+		if err != nil {
+			// we can't find in local config, should delete it
+			if err == db.NotFound {
+				e := data.Event{
+					ResourceType: data.ProtoResourceType,
+					Option:       data.DeleteOption,
+					OldValue:     remoteProto,
+				}
+				events = append(events, &e)
+				continue
+			}
+
+			return nil, err
+		}
+
+		mark[localProto.ID] = true
+		// skip when equals
+		equal, err := d.equal(data.ProtoResourceType, remoteProto, localProto)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			events = d.unchanged(events, data.ProtoResourceType, remoteProto, localProto)
+			continue
+		}
+
+		// otherwise update
+		events = append(events, &data.Event{
+			ResourceType: data.ProtoResourceType,
+			Option:       data.UpdateOption,
+			OldValue:     remoteProto,
+			Value:        localProto,
+		})
+	}
+
+	// only in local, create
+	for _, proto := range d.localConfig.Protos {
+		if mark[proto.ID] {
+			continue
+		}
+
+		events = append(events, &data.Event{
+			ResourceType: data.ProtoResourceType,
+			Option:       data.CreateOption,
+			Value:        proto,
+		})
+	}
+
+	return events, nil
+}
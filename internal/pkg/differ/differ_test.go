@@ -118,6 +118,7 @@ func TestDiff(t *testing.T) {
 	}
 
 	differ, _ := NewDiffer(localConfig, remoteConfig)
+	differ.Prune = true
 	events, _ := differ.Diff()
 	assert.Equal(t, 3, len(events), "check the number of delete events")
 	assert.Equal(t, []*data.Event{
@@ -137,6 +138,90 @@ func TestDiff(t *testing.T) {
 			OldValue:     svc,
 		},
 	}, events, "check the content of delete events")
+
+	// Test case 2: the remote resources are kept without prune
+	differ.Prune = false
+	events, _ = differ.Diff()
+	assert.Equal(t, []*data.Event{
+		{
+			ResourceType: data.RouteResourceType,
+			Option:       data.CreateOption,
+			Value:        route,
+		},
+	}, events, "should not delete the remote resources")
+
+	// Test case 3: only the resources selected by the prune filter are deleted
+	route1.Labels = types.Labels{"managed-by": "adc"}
+	differ.Prune = true
+	differ.PruneFilter = data.ByLabels(types.Labels{"managed-by": "adc"})
+	events, _ = differ.Diff()
+	assert.Equal(t, []*data.Event{
+		{
+			ResourceType: data.RouteResourceType,
+			Option:       data.CreateOption,
+			Value:        route,
+		},
+		{
+			ResourceType: data.RouteResourceType,
+			Option:       data.DeleteOption,
+			OldValue:     &route1,
+		},
+	}, events, "should only delete the labeled route")
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	route1 := *route
+	route1.Description = "route1"
+	localConfig := &types.Configuration{
+		Services: []*types.Service{svc},
+		Routes:   []*types.Route{&route1},
+	}
+	remoteConfig := &types.Configuration{
+		Services: []*types.Service{svc},
+		Routes:   []*types.Route{route},
+	}
+
+	// Test case 1: the unchanged resources are omitted by default
+	differ, _ := NewDiffer(localConfig, remoteConfig)
+	events, err := differ.Diff()
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, events, 1)
+
+	// Test case 2: they are reported with noop events
+	differ.ShowUnchanged = true
+	events, err = differ.Diff()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []*data.Event{
+		{
+			ResourceType: data.RouteResourceType,
+			Option:       data.UpdateOption,
+			OldValue:     route,
+			Value:        &route1,
+		},
+		{
+			ResourceType: data.ServiceResourceType,
+			Option:       data.NoOpOption,
+			OldValue:     svc,
+			Value:        svc,
+		},
+	}, events)
+
+	// Test case 3: the resources that only differ in their canonical form
+	// are unchanged, like in data.Diff
+	svc1 := *svc
+	svc1.Hosts = []string{"a.example.com", "b.example.com"}
+	svc2 := svc1
+	svc2.Hosts = []string{"b.example.com", "a.example.com"}
+	localConfig = &types.Configuration{Services: []*types.Service{&svc1}}
+	remoteConfig = &types.Configuration{Services: []*types.Service{&svc2}}
+	differ, _ = NewDiffer(localConfig, remoteConfig)
+	events, err = differ.Diff()
+	assert.Nil(t, err, "should not return error")
+	assert.Empty(t, events, "should not update the service")
+
+	events, err = data.Diff(data.FromConfiguration(localConfig), data.FromConfiguration(remoteConfig))
+	assert.Nil(t, err, "should not return error")
+	assert.Empty(t, events, "should agree with data.Diff")
 }
 
 func TestDiffServices(t *testing.T) {
@@ -344,3 +429,46 @@ func TestDifferRoutes(t *testing.T) {
 		},
 	}, events, "check the content of delete and create events")
 }
+
+func TestDiffEvents(t *testing.T) {
+	local, err := data.LoadConfiguration([]byte(`
+services:
+- name: svc
+  hosts:
+  - svc.example.com
+routes:
+- name: route
+  service_id: svc
+  uris:
+  - /get
+`))
+	assert.Nil(t, err, "should not return error")
+
+	remoteSvc := &types.Service{ID: "svc", Name: "svc", Hosts: []string{"svc1.example.com"}}
+	remoteRoute := &types.Route{ID: "route1", Name: "route1", Uris: []string{"/get"}}
+	remote := []*data.Event{
+		{ResourceType: data.ServiceResourceType, Option: data.CreateOption, Value: remoteSvc},
+		{ResourceType: data.RouteResourceType, Option: data.CreateOption, Value: remoteRoute},
+	}
+
+	events, err := DiffEvents(local, remote)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []*data.Event{
+		{
+			ResourceType: data.RouteResourceType,
+			Option:       data.CreateOption,
+			Value:        local[1].Value,
+		},
+		{
+			ResourceType: data.ServiceResourceType,
+			Option:       data.UpdateOption,
+			OldValue:     remoteSvc,
+			Value:        local[0].Value,
+		},
+		{
+			ResourceType: data.RouteResourceType,
+			Option:       data.DeleteOption,
+			OldValue:     remoteRoute,
+		},
+	}, events)
+}
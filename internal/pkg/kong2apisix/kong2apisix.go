@@ -0,0 +1,275 @@
+package kong2apisix
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	apitypes "github.com/api7/adc/pkg/api/apisix/types"
+	"github.com/api7/adc/pkg/common"
+	"github.com/api7/adc/pkg/data"
+)
+
+var (
+	// LoadKongError is the error type for loading Kong configuration
+	LoadKongError = errors.New("load Kong configuration error")
+
+	_invalidUsernameRegex = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
+
+// Convert converts the Kong declarative configuration to APISIX services,
+// routes, consumers and global rules. The returned warnings describe what
+// can't be converted, e.g. the unsupported plugins.
+func Convert(content []byte) (*apitypes.Configuration, []string, error) {
+	var kong Config
+	if err := yaml.Unmarshal(content, &kong); err != nil {
+		return nil, nil, errors.Wrap(LoadKongError, err.Error())
+	}
+
+	c := &converter{
+		conf:      &apitypes.Configuration{},
+		services:  make(map[string]*apitypes.Service),
+		routes:    make(map[string]*apitypes.Route),
+		consumers: make(map[string]*apitypes.Consumer),
+	}
+	for _, svc := range kong.Services {
+		if err := c.convertService(svc); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, route := range kong.Routes {
+		c.convertRoute(route, c.services[string(route.Service)], "")
+	}
+	for _, consumer := range kong.Consumers {
+		c.convertConsumer(consumer)
+	}
+	for _, plugin := range kong.Plugins {
+		c.convertPlugin(plugin)
+	}
+	return c.conf, c.warnings, nil
+}
+
+// ConvertToEvents converts the Kong declarative configuration to the create
+// events of the APISIX resources, see Convert.
+func ConvertToEvents(content []byte) ([]*data.Event, []string, error) {
+	conf, warnings, err := Convert(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data.FromConfiguration(conf), warnings, nil
+}
+
+type converter struct {
+	conf     *apitypes.Configuration
+	warnings []string
+
+	// services and routes by their Kong names, to resolve references
+	services  map[string]*apitypes.Service
+	routes    map[string]*apitypes.Route
+	consumers map[string]*apitypes.Consumer
+}
+
+func (c *converter) warn(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+func (c *converter) convertService(kong *Service) error {
+	protocol, host, port, path := kong.Protocol, kong.Host, kong.Port, kong.Path
+	if kong.URL != "" {
+		u, err := url.Parse(kong.URL)
+		if err != nil {
+			return errors.Wrapf(LoadKongError, "invalid url of service \"%s\": %s", kong.Name, err)
+		}
+		protocol, host, path = u.Scheme, u.Hostname(), u.Path
+		port, _ = strconv.Atoi(u.Port())
+	}
+	if protocol == "" {
+		protocol = "http"
+	}
+	if port == 0 {
+		port = 80
+		if protocol == "https" {
+			port = 443
+		}
+	}
+
+	svc := &apitypes.Service{
+		ID:   common.GenID(kong.Name),
+		Name: kong.Name,
+		Upstream: &apitypes.Upstream{
+			ID:     common.GenID(kong.Name),
+			Name:   kong.Name,
+			Scheme: protocol,
+			Nodes: apitypes.UpstreamNodes{
+				{Host: host, Port: port, Weight: 1},
+			},
+		},
+	}
+	var warnings []string
+	svc.Plugins, warnings = convertPlugins(kong.Plugins, fmt.Sprintf("service \"%s\"", kong.Name))
+	c.warnings = append(c.warnings, warnings...)
+
+	c.conf.Services = append(c.conf.Services, svc)
+	c.services[kong.Name] = svc
+
+	for _, route := range kong.Routes {
+		c.convertRoute(route, svc, strings.TrimSuffix(path, "/"))
+	}
+	return nil
+}
+
+func (c *converter) convertRoute(kong *Route, svc *apitypes.Service, servicePath string) {
+	owner := fmt.Sprintf("route \"%s\"", kong.Name)
+	name := kong.Name
+	if name == "" {
+		name = strings.Join(append([]string{"route"}, kong.Paths...), "_")
+		owner = fmt.Sprintf("route of paths %v", kong.Paths)
+	}
+
+	route := &apitypes.Route{
+		ID:      common.GenID(name),
+		Name:    name,
+		Methods: kong.Methods,
+		Hosts:   kong.Hosts,
+	}
+	if svc != nil {
+		route.ServiceID = svc.ID
+	} else if kong.Service != "" {
+		c.warn("%s: service \"%s\" doesn't exist", owner, kong.Service)
+	}
+
+	// Kong matches the paths by prefix
+	var prefixes []string
+	for _, path := range kong.Paths {
+		if strings.HasPrefix(path, "~") {
+			c.warn("%s: regex path \"%s\" is not supported, skipped", owner, path)
+			continue
+		}
+		route.Uris = append(route.Uris, path+"*")
+		if prefix := strings.TrimSuffix(path, "/"); prefix != "" {
+			prefixes = append(prefixes, regexp.QuoteMeta(prefix))
+		}
+	}
+	if len(route.Uris) == 0 {
+		route.Uris = []string{"/*"}
+	}
+
+	var warnings []string
+	route.Plugins, warnings = convertPlugins(kong.Plugins, owner)
+	c.warnings = append(c.warnings, warnings...)
+
+	stripPath := kong.StripPath == nil || *kong.StripPath
+	var rewrite []string
+	switch {
+	case stripPath && len(prefixes) > 0:
+		rewrite = []string{"^(?:" + strings.Join(prefixes, "|") + ")/?(.*)", servicePath + "/$1"}
+	case servicePath != "":
+		rewrite = []string{"^/(.*)", servicePath + "/$1"}
+	}
+	if rewrite != nil {
+		if route.Plugins == nil {
+			route.Plugins = make(apitypes.Plugins)
+		}
+		route.Plugins["proxy-rewrite"] = apitypes.Plugin{"regex_uri": rewrite}
+	}
+
+	c.conf.Routes = append(c.conf.Routes, route)
+	if kong.Name != "" {
+		c.routes[kong.Name] = route
+	}
+}
+
+func (c *converter) convertConsumer(kong *Consumer) {
+	username := kong.Username
+	if username == "" {
+		username = kong.CustomID
+	}
+	owner := fmt.Sprintf("consumer \"%s\"", username)
+	consumer := &apitypes.Consumer{
+		// the username of APISIX only allows letters, digits and underscores
+		Username: _invalidUsernameRegex.ReplaceAllString(username, "_"),
+	}
+
+	var warnings []string
+	consumer.Plugins, warnings = convertPlugins(kong.Plugins, owner)
+	c.warnings = append(c.warnings, warnings...)
+	if consumer.Plugins == nil && (len(kong.KeyAuthCredentials) > 0 || len(kong.BasicAuthCredentials) > 0) {
+		consumer.Plugins = make(apitypes.Plugins)
+	}
+
+	if len(kong.KeyAuthCredentials) > 0 {
+		consumer.Plugins["key-auth"] = apitypes.Plugin{"key": kong.KeyAuthCredentials[0].Key}
+		if len(kong.KeyAuthCredentials) > 1 {
+			c.warn("%s: only the first key-auth credential is used", owner)
+		}
+	}
+	if len(kong.BasicAuthCredentials) > 0 {
+		cred := kong.BasicAuthCredentials[0]
+		consumer.Plugins["basic-auth"] = apitypes.Plugin{"username": cred.Username, "password": cred.Password}
+		if len(kong.BasicAuthCredentials) > 1 {
+			c.warn("%s: only the first basic-auth credential is used", owner)
+		}
+	}
+
+	c.conf.Consumers = append(c.conf.Consumers, consumer)
+	c.consumers[username] = consumer
+}
+
+// convertPlugin converts a top-level plugin, it's added to the referred
+// entity or as a global rule if it doesn't refer to any.
+func (c *converter) convertPlugin(kong *Plugin) {
+	var (
+		owner   string
+		plugins *apitypes.Plugins
+	)
+	switch {
+	case kong.Route != "":
+		owner = fmt.Sprintf("route \"%s\"", kong.Route)
+		if route, ok := c.routes[string(kong.Route)]; ok {
+			plugins = &route.Plugins
+		}
+	case kong.Service != "":
+		owner = fmt.Sprintf("service \"%s\"", kong.Service)
+		if svc, ok := c.services[string(kong.Service)]; ok {
+			plugins = &svc.Plugins
+		}
+	case kong.Consumer != "":
+		owner = fmt.Sprintf("consumer \"%s\"", kong.Consumer)
+		if consumer, ok := c.consumers[string(kong.Consumer)]; ok {
+			plugins = &consumer.Plugins
+		}
+	default:
+		owner = "global"
+	}
+	if owner != "global" && plugins == nil {
+		c.warn("%s: plugin \"%s\" refers to a missing entity, skipped", owner, kong.Name)
+		return
+	}
+
+	converted, warnings := convertPlugins([]*Plugin{kong}, owner)
+	c.warnings = append(c.warnings, warnings...)
+	if converted == nil {
+		return
+	}
+
+	if plugins == nil {
+		for name := range converted {
+			c.conf.GlobalRules = append(c.conf.GlobalRules, &apitypes.GlobalRule{
+				ID:      name,
+				Plugins: converted,
+			})
+		}
+		return
+	}
+	if *plugins == nil {
+		*plugins = make(apitypes.Plugins)
+	}
+	for name, conf := range converted {
+		(*plugins)[name] = conf
+	}
+}
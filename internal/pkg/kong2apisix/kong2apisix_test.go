@@ -0,0 +1,148 @@
+package kong2apisix
+
+import (
+	_ "embed"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apitypes "github.com/api7/adc/pkg/api/apisix/types"
+	"github.com/api7/adc/pkg/common"
+	"github.com/api7/adc/pkg/data"
+)
+
+var (
+	//go:embed testdata/kong.yaml
+	kongTest []byte
+)
+
+func TestConvert(t *testing.T) {
+	conf, warnings, err := Convert(kongTest)
+	assert.Nil(t, err, "should not return error")
+
+	httpbinID, echoID := common.GenID("httpbin"), common.GenID("echo")
+	assert.Equal(t, []*apitypes.Service{
+		{
+			ID:   httpbinID,
+			Name: "httpbin",
+			Upstream: &apitypes.Upstream{
+				ID:     httpbinID,
+				Name:   "httpbin",
+				Scheme: "https",
+				Nodes:  apitypes.UpstreamNodes{{Host: "httpbin.org", Port: 8443, Weight: 1}},
+			},
+			Plugins: apitypes.Plugins{
+				"cors": apitypes.Plugin{
+					"allow_origins":    "https://example.com",
+					"allow_methods":    "GET,POST",
+					"allow_credential": true,
+					"max_age":          3600,
+				},
+			},
+		},
+		{
+			ID:   echoID,
+			Name: "echo",
+			Upstream: &apitypes.Upstream{
+				ID:     echoID,
+				Name:   "echo",
+				Scheme: "http",
+				Nodes:  apitypes.UpstreamNodes{{Host: "echo.example.com", Port: 80, Weight: 1}},
+			},
+		},
+	}, conf.Services)
+
+	assert.Equal(t, []*apitypes.Route{
+		{
+			ID:        common.GenID("get"),
+			Name:      "get",
+			Uris:      []string{"/get*"},
+			Methods:   []string{"GET"},
+			ServiceID: httpbinID,
+			Plugins: apitypes.Plugins{
+				"key-auth": apitypes.Plugin{
+					"header": "apikey",
+					"query":  "apikey",
+				},
+				"proxy-rewrite": apitypes.Plugin{
+					"regex_uri": []string{"^(?:/get)/?(.*)", "/api/$1"},
+				},
+				"limit-count": apitypes.Plugin{
+					"count":         5,
+					"time_window":   60,
+					"rejected_code": 429,
+					"key_type":      "var",
+					"key":           "remote_addr",
+				},
+			},
+		},
+		{
+			ID:        common.GenID("anything"),
+			Name:      "anything",
+			Uris:      []string{"/anything*"},
+			ServiceID: httpbinID,
+			Plugins: apitypes.Plugins{
+				"proxy-rewrite": apitypes.Plugin{
+					"regex_uri": []string{"^/(.*)", "/api/$1"},
+				},
+			},
+		},
+		{
+			ID:        common.GenID("echo"),
+			Name:      "echo",
+			Uris:      []string{"/*"},
+			ServiceID: echoID,
+		},
+	}, conf.Routes)
+
+	assert.Equal(t, []*apitypes.Consumer{
+		{
+			Username: "jack_ma",
+			Plugins: apitypes.Plugins{
+				"key-auth": apitypes.Plugin{"key": "auth-one"},
+			},
+		},
+		{
+			Username: "rose",
+			Plugins: apitypes.Plugins{
+				"basic-auth": apitypes.Plugin{"username": "rose", "password": "secret"},
+			},
+		},
+	}, conf.Consumers)
+
+	assert.Equal(t, []*apitypes.GlobalRule{
+		{
+			ID: "cors",
+			Plugins: apitypes.Plugins{
+				"cors": apitypes.Plugin{
+					"allow_origins": "*",
+					"_meta":         map[string]interface{}{"disable": true},
+				},
+			},
+		},
+	}, conf.GlobalRules)
+
+	assert.Equal(t, []string{
+		`route "anything": regex path "~/regex$" is not supported, skipped`,
+		`consumer "jack-ma": only the first key-auth credential is used`,
+		`route "get": plugin "rate-limiting": only the smallest window is used, the limit per hour is ignored`,
+		`route "get": plugin "rate-limiting": policy "redis" is not supported, use the local policy instead`,
+		`global: plugin "prometheus" is not supported, skipped`,
+	}, warnings)
+}
+
+func TestConvertToEvents(t *testing.T) {
+	events, warnings, err := ConvertToEvents(kongTest)
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, warnings, 5)
+	assert.Len(t, events, 8)
+	for _, event := range events {
+		assert.Equal(t, data.CreateOption, event.Option)
+	}
+	assert.Equal(t, data.ServiceResourceType, events[0].ResourceType)
+	assert.Equal(t, data.GlobalRuleResourceType, events[7].ResourceType)
+
+	// Test case 2: invalid configuration
+	_, _, err = ConvertToEvents([]byte("services: {"))
+	assert.ErrorIs(t, err, LoadKongError)
+}
@@ -0,0 +1,159 @@
+package kong2apisix
+
+import (
+	"fmt"
+	"strings"
+
+	apitypes "github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// pluginConverter converts the config of a Kong plugin to an APISIX plugin,
+// the returned warnings describe the parts that can't be converted.
+type pluginConverter func(config map[string]interface{}) (string, apitypes.Plugin, []string)
+
+var pluginConverters = map[string]pluginConverter{
+	"key-auth":      convertKeyAuth,
+	"basic-auth":    convertBasicAuth,
+	"rate-limiting": convertRateLimiting,
+	"cors":          convertCORS,
+}
+
+// convertPlugins converts the Kong plugins of an entity, owner describes the
+// entity in the warnings, e.g. `route "foo"`.
+func convertPlugins(plugins []*Plugin, owner string) (apitypes.Plugins, []string) {
+	var warnings []string
+	result := make(apitypes.Plugins)
+	for _, plugin := range plugins {
+		convert, ok := pluginConverters[plugin.Name]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("%s: plugin \"%s\" is not supported, skipped", owner, plugin.Name))
+			continue
+		}
+
+		name, conf, warns := convert(plugin.Config)
+		for _, warn := range warns {
+			warnings = append(warnings, fmt.Sprintf("%s: plugin \"%s\": %s", owner, plugin.Name, warn))
+		}
+		if plugin.Enabled != nil && !*plugin.Enabled {
+			conf["_meta"] = map[string]interface{}{"disable": true}
+		}
+		result[name] = conf
+	}
+	if len(result) == 0 {
+		return nil, warnings
+	}
+	return result, warnings
+}
+
+func convertKeyAuth(config map[string]interface{}) (string, apitypes.Plugin, []string) {
+	var warnings []string
+	conf := apitypes.Plugin{}
+	names := stringList(config["key_names"])
+	if len(names) > 0 {
+		conf["header"] = names[0]
+		conf["query"] = names[0]
+	}
+	if len(names) > 1 {
+		warnings = append(warnings, fmt.Sprintf("only the first key name \"%s\" is used", names[0]))
+	}
+	if hide, ok := config["hide_credentials"].(bool); ok {
+		conf["hide_credentials"] = hide
+	}
+	return "key-auth", conf, warnings
+}
+
+func convertBasicAuth(config map[string]interface{}) (string, apitypes.Plugin, []string) {
+	conf := apitypes.Plugin{}
+	if hide, ok := config["hide_credentials"].(bool); ok {
+		conf["hide_credentials"] = hide
+	}
+	return "basic-auth", conf, nil
+}
+
+// rateLimitingWindows are the time windows of the Kong rate-limiting plugin in
+// seconds, from the smallest.
+var rateLimitingWindows = []struct {
+	name    string
+	seconds int
+}{
+	{"second", 1},
+	{"minute", 60},
+	{"hour", 3600},
+	{"day", 86400},
+	{"month", 2592000},
+	{"year", 31536000},
+}
+
+func convertRateLimiting(config map[string]interface{}) (string, apitypes.Plugin, []string) {
+	var warnings []string
+	conf := apitypes.Plugin{
+		"rejected_code": 429,
+		"key_type":      "var",
+		"key":           "remote_addr",
+	}
+
+	for _, window := range rateLimitingWindows {
+		count, ok := config[window.name].(float64)
+		if !ok {
+			continue
+		}
+		if _, set := conf["count"]; set {
+			warnings = append(warnings, fmt.Sprintf("only the smallest window is used, the limit per %s is ignored", window.name))
+			continue
+		}
+		conf["count"] = int(count)
+		conf["time_window"] = window.seconds
+	}
+	if _, set := conf["count"]; !set {
+		warnings = append(warnings, "no limit is set")
+	}
+
+	switch config["limit_by"] {
+	case nil, "ip":
+	case "consumer", "credential":
+		conf["key"] = "consumer_name"
+	default:
+		warnings = append(warnings, fmt.Sprintf("limit by \"%v\" is not supported, limit by ip instead", config["limit_by"]))
+	}
+	if policy, ok := config["policy"].(string); ok && policy != "local" {
+		warnings = append(warnings, fmt.Sprintf("policy \"%s\" is not supported, use the local policy instead", policy))
+	}
+	return "limit-count", conf, warnings
+}
+
+func convertCORS(config map[string]interface{}) (string, apitypes.Plugin, []string) {
+	conf := apitypes.Plugin{}
+	if origins := stringList(config["origins"]); len(origins) > 0 {
+		conf["allow_origins"] = strings.Join(origins, ",")
+	}
+	if methods := stringList(config["methods"]); len(methods) > 0 {
+		conf["allow_methods"] = strings.Join(methods, ",")
+	}
+	if headers := stringList(config["headers"]); len(headers) > 0 {
+		conf["allow_headers"] = strings.Join(headers, ",")
+	}
+	if headers := stringList(config["exposed_headers"]); len(headers) > 0 {
+		conf["expose_headers"] = strings.Join(headers, ",")
+	}
+	if credentials, ok := config["credentials"].(bool); ok {
+		conf["allow_credential"] = credentials
+	}
+	if maxAge, ok := config["max_age"].(float64); ok {
+		conf["max_age"] = int(maxAge)
+	}
+	return "cors", conf, nil
+}
+
+func stringList(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	var result []string
+	for _, elem := range list {
+		if s, ok := elem.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
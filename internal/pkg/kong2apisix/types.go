@@ -0,0 +1,92 @@
+package kong2apisix
+
+import (
+	"encoding/json"
+)
+
+// Config is the declarative configuration of Kong, i.e. kong.yml.
+type Config struct {
+	FormatVersion string      `json:"_format_version"`
+	Services      []*Service  `json:"services"`
+	Routes        []*Route    `json:"routes"`
+	Consumers     []*Consumer `json:"consumers"`
+	Plugins       []*Plugin   `json:"plugins"`
+}
+
+type Service struct {
+	Name     string    `json:"name"`
+	URL      string    `json:"url"`
+	Protocol string    `json:"protocol"`
+	Host     string    `json:"host"`
+	Port     int       `json:"port"`
+	Path     string    `json:"path"`
+	Routes   []*Route  `json:"routes"`
+	Plugins  []*Plugin `json:"plugins"`
+}
+
+type Route struct {
+	Name      string    `json:"name"`
+	Service   Ref       `json:"service"`
+	Paths     []string  `json:"paths"`
+	Methods   []string  `json:"methods"`
+	Hosts     []string  `json:"hosts"`
+	StripPath *bool     `json:"strip_path"`
+	Plugins   []*Plugin `json:"plugins"`
+}
+
+type Consumer struct {
+	Username             string                 `json:"username"`
+	CustomID             string                 `json:"custom_id"`
+	KeyAuthCredentials   []*KeyAuthCredential   `json:"keyauth_credentials"`
+	BasicAuthCredentials []*BasicAuthCredential `json:"basicauth_credentials"`
+	Plugins              []*Plugin              `json:"plugins"`
+}
+
+type KeyAuthCredential struct {
+	Key string `json:"key"`
+}
+
+type BasicAuthCredential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type Plugin struct {
+	Name     string                 `json:"name"`
+	Config   map[string]interface{} `json:"config"`
+	Enabled  *bool                  `json:"enabled"`
+	Service  Ref                    `json:"service"`
+	Route    Ref                    `json:"route"`
+	Consumer Ref                    `json:"consumer"`
+}
+
+// Ref is the reference to another entity, Kong accepts both the name and
+// an object with the name.
+type Ref string
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (r *Ref) UnmarshalJSON(p []byte) error {
+	var name string
+	if err := json.Unmarshal(p, &name); err == nil {
+		*r = Ref(name)
+		return nil
+	}
+
+	var obj struct {
+		Name     string `json:"name"`
+		Username string `json:"username"`
+		ID       string `json:"id"`
+	}
+	if err := json.Unmarshal(p, &obj); err != nil {
+		return err
+	}
+	switch {
+	case obj.Name != "":
+		*r = Ref(obj.Name)
+	case obj.Username != "":
+		*r = Ref(obj.Username)
+	default:
+		*r = Ref(obj.ID)
+	}
+	return nil
+}
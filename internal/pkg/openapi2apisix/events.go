@@ -0,0 +1,18 @@
+package openapi2apisix
+
+import (
+	"context"
+
+	"github.com/api7/adc/pkg/data"
+)
+
+// ConvertToEvents converts OAS to the create events of the API Service and
+// Routes, which can be applied to APISIX directly. The events of the service
+// come first, as the routes refer to it.
+func ConvertToEvents(ctx context.Context, oas []byte) ([]*data.Event, error) {
+	conf, err := Convert(ctx, oas)
+	if err != nil {
+		return nil, err
+	}
+	return data.FromConfiguration(conf), nil
+}
@@ -0,0 +1,56 @@
+package openapi2apisix
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apitypes "github.com/api7/adc/pkg/api/apisix/types"
+	"github.com/api7/adc/pkg/common"
+	"github.com/api7/adc/pkg/data"
+)
+
+func TestConvertToEvents(t *testing.T) {
+	events, err := ConvertToEvents(context.Background(), operationIdTest)
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, events, 3)
+
+	// Test case 1: the service with the servers as upstream nodes
+	assert.Equal(t, data.ServiceResourceType, events[0].ResourceType)
+	assert.Equal(t, data.CreateOption, events[0].Option)
+	svc := events[0].Value.(*apitypes.Service)
+	assert.Equal(t, "API 101", svc.Name)
+	assert.Equal(t, "api-101.glitch.me", svc.Upstream.Nodes[0].Host)
+	assert.Equal(t, 443, svc.Upstream.Nodes[0].Port)
+
+	// Test case 2: the routes are named by operationId with path parameters
+	var routes []*apitypes.Route
+	for _, event := range events[1:] {
+		assert.Equal(t, data.RouteResourceType, event.ResourceType)
+		assert.Equal(t, data.CreateOption, event.Option)
+		routes = append(routes, event.Value.(*apitypes.Route))
+	}
+	assert.Equal(t, []*apitypes.Route{
+		{
+			ID:          common.GenID("update Customer"),
+			Name:        "update Customer",
+			Description: "Update customer",
+			Methods:     []string{"PUT"},
+			Uris:        []string{"/customer/:customer_id"},
+			ServiceID:   svc.ID,
+		},
+		{
+			ID:          common.GenID("getCustomers"),
+			Name:        "getCustomers",
+			Description: "Get all customers",
+			Methods:     []string{"GET"},
+			Uris:        []string{"/customers"},
+			ServiceID:   svc.ID,
+		},
+	}, routes)
+
+	// Test case 3: invalid document
+	_, err = ConvertToEvents(context.Background(), []byte("openapi: 3.0.0\npaths: ["))
+	assert.NotNil(t, err, "should return error")
+}
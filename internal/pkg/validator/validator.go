@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 
+	"go.uber.org/multierr"
+
 	"github.com/api7/adc/pkg/api/apisix"
 	"github.com/api7/adc/pkg/api/apisix/types"
 	"github.com/api7/adc/pkg/common"
+	"github.com/api7/adc/pkg/data"
 )
 
 type Validator struct {
@@ -122,5 +125,9 @@ func (v *Validator) Validate() []error {
 		}
 	}
 
+	events := data.FromConfiguration(v.localConfig)
+	allErr = append(allErr, multierr.Errors(data.ValidateUnique(events))...)
+	allErr = append(allErr, multierr.Errors(data.ValidateReferences(context.Background(), v.cluster, events))...)
+
 	return allErr
 }
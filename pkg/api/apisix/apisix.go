@@ -17,11 +17,48 @@ type Cluster interface {
 	PluginMetadata() PluginMetadata
 	StreamRoute() StreamRoute
 	Upstream() Upstream
+	Secret() Secret
+	Proto() Proto
 	Ping() error
 	SupportValidate() (bool, error)
 	SupportStreamRoute() (bool, error)
 }
 
+// The schema types of PluginSchemaGetter.PluginSchema.
+const (
+	// PluginSchemaTypeRoute is the schema of the plugins of routes, services
+	// and the other resources.
+	PluginSchemaTypeRoute = ""
+	// PluginSchemaTypeConsumer is the schema of the plugins of consumers,
+	// e.g. the key of key-auth.
+	PluginSchemaTypeConsumer = "consumer"
+)
+
+// PluginSchemaGetter is implemented by the clusters exposing the JSON
+// schemas of their plugins.
+type PluginSchemaGetter interface {
+	// PluginSchema returns the JSON schema of the plugin, ErrNotFound is
+	// returned for unknown plugins.
+	PluginSchema(ctx context.Context, name string, schemaType string) (string, error)
+}
+
+// VersionGetter is implemented by the clusters reporting the version of
+// APISIX.
+type VersionGetter interface {
+	// Version returns the version of APISIX, e.g. "3.8.0".
+	Version(ctx context.Context) (string, error)
+}
+
+// AdminAPISetter is implemented by the clusters supporting the admin APIs
+// of both APISIX 2.x and 3.x, which differ in the paths of some resources
+// and the shapes of the responses.
+type AdminAPISetter interface {
+	// SetAdminAPIVersion switches to the admin API of the major version of
+	// APISIX, the one of 3.x is used by default. It must not be called
+	// concurrently with the requests to the cluster.
+	SetAdminAPIVersion(major int)
+}
+
 type ResourceClient[T any] interface {
 	Get(ctx context.Context, name string) (*T, error)
 	List(ctx context.Context) ([]*T, error)
@@ -70,3 +107,11 @@ type StreamRoute interface {
 type Upstream interface {
 	ResourceClient[types.Upstream]
 }
+
+type Secret interface {
+	ResourceClient[types.Secret]
+}
+
+type Proto interface {
+	ResourceClient[types.Proto]
+}
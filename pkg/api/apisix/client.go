@@ -10,11 +10,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/fatih/color"
 	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/tracing"
 )
 
 var (
@@ -23,37 +26,71 @@ var (
 	ErrFunctionDisabled = errors.New("function disabled")
 )
 
+// StatusError is returned when the admin API responds with an
+// unexpected status code.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d; %s", e.StatusCode, e.Message)
+}
+
 type Client struct {
 	baseURL  string
 	adminKey string
+	// adminAPIVersion is the major version of APISIX of the admin API, 3
+	// if it's 0.
+	adminAPIVersion int
 
 	cli *http.Client
 }
 
+// v2 reports whether the client uses the admin API of APISIX 2.x.
+func (c *Client) v2() bool {
+	return c.adminAPIVersion != 0 && c.adminAPIVersion < 3
+}
+
+// maxIdleConnsPerHost is the number of idle connections kept open to the
+// admin API, it is larger than the default 2 so that the connections of
+// the concurrent appliers are reused instead of being re-dialed.
+const maxIdleConnsPerHost = 32
+
+// newTransport returns the transport of the client, with keep-alive like
+// http.DefaultTransport. A client holds a single transport, so all its
+// requests share the same pool of connections.
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	return transport
+}
+
 func newClient(baseURL, adminKey string) *Client {
 	return &Client{
 		baseURL:  baseURL,
 		adminKey: adminKey,
 		cli: &http.Client{
-			Timeout: 5 * time.Second,
+			Timeout:   5 * time.Second,
+			Transport: newTransport(),
 		},
 	}
 }
 
 func newClientWithCertificates(baseURL, adminKey string, host string, insecure bool, ca *x509.CertPool, certs []tls.Certificate) *Client {
+	transport := newTransport()
+	transport.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: insecure,
+		ServerName:         host,
+		RootCAs:            ca,
+		Certificates:       certs,
+	}
 	return &Client{
 		baseURL:  baseURL,
 		adminKey: adminKey,
 		cli: &http.Client{
-			Timeout: 5 * time.Second,
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: insecure,
-					ServerName:         host,
-					RootCAs:            ca,
-					Certificates:       certs,
-				},
-			},
+			Timeout:   5 * time.Second,
+			Transport: transport,
 		},
 	}
 }
@@ -64,12 +101,34 @@ func (c *Client) setAdminKey(req *http.Request) {
 	}
 }
 
-func (c *Client) do(req *http.Request) (*http.Response, error) {
+// do sends the request, traced in a child span of the ctx of the request
+// if it has a tracer.
+func (c *Client) do(req *http.Request) (resp *http.Response, err error) {
+	ctx, span := tracing.Start(req.Context(), "apisix "+req.Method,
+		tracing.String("http.method", req.Method),
+		tracing.String("http.path", req.URL.Path),
+	)
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(tracing.String("http.status_code", strconv.Itoa(resp.StatusCode)))
+		}
+		tracing.End(span, err)
+	}()
+
+	req = req.WithContext(ctx)
 	c.setAdminKey(req)
 	return c.cli.Do(req)
 }
 
 func (c *Client) getResource(ctx context.Context, url string) (*item, error) {
+	if c.v2() {
+		var res v2Response
+		if err := makeGetRequest(c, ctx, url, &res); err != nil {
+			return nil, err
+		}
+		return &res.Node.item, nil
+	}
+
 	var res getResponse
 	err := makeGetRequest(c, ctx, url, &res)
 	if err != nil {
@@ -79,16 +138,46 @@ func (c *Client) getResource(ctx context.Context, url string) (*item, error) {
 }
 
 func (c *Client) listResource(ctx context.Context, url string) (items, error) {
+	if c.v2() {
+		// APISIX 2.x doesn't page the resources
+		var res v2Response
+		if err := makeGetRequest(c, ctx, url, &res); err != nil {
+			return nil, err
+		}
+		return res.Node.Nodes, nil
+	}
+
 	var res listResponse
 
 	err := makeGetRequest(c, ctx, url, &res)
 	if err != nil {
 		return nil, err
 	}
-	return res.List, nil
+
+	// APISIX returns all the resources unless a page is requested, but keep
+	// fetching the next pages in case the response is paged anyway, e.g. by
+	// a gateway in front of the admin API.
+	list := res.List
+	pageSize := len(list)
+	for page := 2; pageSize > 0 && len(list) < res.Total.IntValue; page++ {
+		var next listResponse
+		err := makeGetRequest(c, ctx, fmt.Sprintf("%s?page=%d&page_size=%d", url, page, pageSize), &next)
+		if err != nil {
+			return nil, err
+		}
+		if len(next.List) == 0 {
+			break
+		}
+		list = append(list, next.List...)
+	}
+	return list, nil
 }
 
 func (c *Client) createResource(ctx context.Context, url string, body []byte) (*item, error) {
+	if c.v2() {
+		return c.putV2Resource(ctx, url, body)
+	}
+
 	var cr createResponse
 	err := makePutRequest(c, ctx, url, body, &cr)
 	if err != nil {
@@ -98,6 +187,10 @@ func (c *Client) createResource(ctx context.Context, url string, body []byte) (*
 }
 
 func (c *Client) updateResource(ctx context.Context, url string, body []byte) (*item, error) {
+	if c.v2() {
+		return c.putV2Resource(ctx, url, body)
+	}
+
 	var ur updateResponse
 
 	err := makePutRequest(c, ctx, url, body, &ur)
@@ -107,6 +200,16 @@ func (c *Client) updateResource(ctx context.Context, url string, body []byte) (*
 	return &ur, nil
 }
 
+// putV2Resource creates or updates the resource with the admin API of
+// APISIX 2.x.
+func (c *Client) putV2Resource(ctx context.Context, url string, body []byte) (*item, error) {
+	var res v2Response
+	if err := makePutRequest(c, ctx, url, body, &res); err != nil {
+		return nil, err
+	}
+	return &res.Node.item, nil
+}
+
 func (c *Client) deleteResource(ctx context.Context, url string) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
 	if err != nil {
@@ -117,7 +220,7 @@ func (c *Client) deleteResource(ctx context.Context, url string) error {
 		return err
 	}
 
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
 		return handleErrorResponse(resp)
@@ -125,6 +228,17 @@ func (c *Client) deleteResource(ctx context.Context, url string) error {
 	return nil
 }
 
+// maxDrainSize is the maximum size of the unread response body drained
+// before closing it, larger bodies close the connection instead.
+const maxDrainSize = 64 << 10
+
+// closeBody drains and closes the response body, the connection is only
+// returned to the pool and reused if the body is read to the end.
+func closeBody(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, maxDrainSize))
+	_ = body.Close()
+}
+
 func readBody(r io.ReadCloser) (string, error) {
 	defer r.Close()
 
@@ -135,9 +249,25 @@ func readBody(r io.ReadCloser) (string, error) {
 	return string(data), nil
 }
 
+// getServer returns the Server header of the response to the GET request.
+func (c *Client) getServer(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer closeBody(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", handleErrorResponse(resp)
+	}
+	return resp.Header.Get("Server"), nil
+}
+
 // getSchema returns the schema of APISIX object.
-//
-//nolint:unused
 func (c *Client) getSchema(ctx context.Context, url string) (string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -148,7 +278,7 @@ func (c *Client) getSchema(ctx context.Context, url string) (string, error) {
 		return "", err
 	}
 
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
 	body, err := readBody(resp.Body)
 	if err != nil {
 		err = multierr.Append(err, fmt.Errorf("read body failed"))
@@ -199,7 +329,7 @@ func (c *Client) validate(ctx context.Context, url string, resource interface{})
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
 	if resp.StatusCode != http.StatusOK {
 		return handleErrorResponse(resp)
 	}
@@ -210,6 +340,13 @@ func isFunctionDisabled(msg string) bool {
 	return strings.Contains(msg, "is disabled")
 }
 
+// isStillInUse reports whether APISIX refused to delete a resource
+// because other resources still reference it, e.g.
+// "can not delete this upstream, route [1] is still using it now".
+func isStillInUse(msg string) bool {
+	return strings.Contains(msg, "is still using it now")
+}
+
 func makeGetRequest[T any](c *Client, ctx context.Context, url string, result *T) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -220,7 +357,7 @@ func makeGetRequest[T any](c *Client, ctx context.Context, url string, result *T
 		return err
 	}
 
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
 	if resp.StatusCode != http.StatusOK {
 		if resp.StatusCode == http.StatusNotFound {
 			return ErrNotFound
@@ -246,7 +383,7 @@ func makePutRequest[T any](c *Client, ctx context.Context, url string, body []by
 		return err
 	}
 
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		return handleErrorResponse(resp)
@@ -273,12 +410,22 @@ func handleErrorResponse(resp *http.Response) error {
 	if err != nil {
 		color.Red("unmarshal response failed:")
 		color.Red(body)
-		return err
+		// e.g. the HTML page of 502 Bad Gateway from a proxy in front of APISIX
+		return &StatusError{
+			StatusCode: resp.StatusCode,
+			Message:    body,
+		}
 	}
 
 	errMsg := errors.New(respData.ErrMsg)
 	if isFunctionDisabled(errMsg.Error()) {
 		return errMsg
 	}
-	return multierr.Append(fmt.Errorf("unexpected status code %d", resp.StatusCode), errMsg)
+	if isStillInUse(errMsg.Error()) {
+		return fmt.Errorf("%w: %s", ErrStillInUse, errMsg)
+	}
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Message:    respData.ErrMsg,
+	}
 }
@@ -0,0 +1,276 @@
+package apisix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/config"
+	"github.com/api7/adc/pkg/tracing"
+)
+
+func TestListResourcePages(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		switch page {
+		case "1", "2":
+			fmt.Fprintf(w, `{"total":5,"list":[{"key":"/apisix/routes/%[1]s1","value":{"id":"%[1]s1"}},{"key":"/apisix/routes/%[1]s2","value":{"id":"%[1]s2"}}]}`, page)
+		case "3":
+			fmt.Fprint(w, `{"total":5,"list":[{"key":"/apisix/routes/31","value":{"id":"31"}}]}`)
+		}
+	}))
+	defer server.Close()
+
+	cli := newClient(server.URL, "")
+
+	// Test case 1: the next pages are fetched until the total
+	list, err := cli.listResource(context.Background(), server.URL+"/routes")
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, list, 5)
+	assert.Equal(t, "/apisix/routes/31", list[4].Key)
+	assert.Equal(t, []string{"", "page=2&page_size=2", "page=3&page_size=2"}, queries)
+
+	// Test case 2: a single request when everything is returned
+	queries = nil
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		fmt.Fprint(w, `{"total":1,"list":[{"key":"/apisix/routes/1","value":{"id":"1"}}]}`)
+	})
+	list, err = cli.listResource(context.Background(), server.URL+"/routes")
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, list, 1)
+	assert.Equal(t, []string{""}, queries)
+}
+
+// newCountingServer returns a server counting the connections dialed.
+func newCountingServer(handler http.HandlerFunc) (*httptest.Server, *int64) {
+	var conns int64
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	server.Start()
+	return server, &conns
+}
+
+func routeHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		fmt.Fprint(w, `{"key":"/apisix/routes/1","value":{"id":"1"}}`+"\n")
+	case http.MethodPut:
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"key":"/apisix/routes/1","value":{"id":"1"}}`+"\n")
+	case http.MethodDelete:
+		fmt.Fprint(w, `{"deleted":"1","key":"/apisix/routes/1"}`)
+	}
+}
+
+func TestClientReusesConnections(t *testing.T) {
+	server, conns := newCountingServer(routeHandler)
+	defer server.Close()
+	cli := newClient(server.URL, "")
+
+	// Test case 1: sequential requests share one connection
+	for i := 0; i < 10; i++ {
+		_, err := cli.getResource(context.Background(), server.URL+"/routes/1")
+		assert.Nil(t, err, "should not return error")
+		_, err = cli.createResource(context.Background(), server.URL+"/routes/1", []byte(`{"id":"1"}`))
+		assert.Nil(t, err, "should not return error")
+		assert.Nil(t, cli.deleteResource(context.Background(), server.URL+"/routes/1"), "should not return error")
+	}
+	assert.Equal(t, int64(1), atomic.LoadInt64(conns), "should reuse the connection")
+
+	// Test case 2: concurrent requests keep more than 2 idle connections
+	atomic.StoreInt64(conns, 0)
+	for round := 0; round < 3; round++ {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := cli.getResource(context.Background(), server.URL+"/routes/1")
+				assert.Nil(t, err, "should not return error")
+			}()
+		}
+		wg.Wait()
+	}
+	assert.LessOrEqual(t, atomic.LoadInt64(conns), int64(8), "should not re-dial the connections of the previous rounds")
+}
+
+func benchmarkClient(b *testing.B, keepAlive bool) {
+	server, _ := newCountingServer(routeHandler)
+	defer server.Close()
+	cli := newClient(server.URL, "")
+	cli.cli.Transport.(*http.Transport).DisableKeepAlives = !keepAlive
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cli.getResource(context.Background(), server.URL+"/routes/1"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClientKeepAlive(b *testing.B)   { benchmarkClient(b, true) }
+func BenchmarkClientNoKeepAlive(b *testing.B) { benchmarkClient(b, false) }
+
+func TestPluginSchema(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		if r.URL.Path == "/apisix/admin/plugins/unknown" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"type":"object"}`)
+	}))
+	defer server.Close()
+
+	cluster, err := NewCluster(context.Background(), config.ClientConfig{Server: server.URL})
+	assert.Nil(t, err, "should not return error")
+	getter, ok := cluster.(PluginSchemaGetter)
+	assert.True(t, ok, "should implement PluginSchemaGetter")
+
+	// Test case 1: the schemas of routes and consumers
+	schema, err := getter.PluginSchema(context.Background(), "key-auth", PluginSchemaTypeRoute)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, `{"type":"object"}`, schema)
+	_, err = getter.PluginSchema(context.Background(), "key-auth", PluginSchemaTypeConsumer)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []string{"/apisix/admin/plugins/key-auth", "/apisix/admin/plugins/key-auth?schema_type=consumer"}, requests)
+
+	// Test case 2: unknown plugins
+	_, err = getter.PluginSchema(context.Background(), "unknown", PluginSchemaTypeRoute)
+	assert.True(t, errors.Is(err, ErrNotFound), "should return ErrNotFound")
+}
+
+func TestClusterVersion(t *testing.T) {
+	serverHeader := "APISIX/3.8.0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", serverHeader)
+		fmt.Fprint(w, `{"total":0,"list":[]}`)
+	}))
+	defer server.Close()
+
+	cluster, err := NewCluster(context.Background(), config.ClientConfig{Server: server.URL})
+	assert.Nil(t, err, "should not return error")
+	getter, ok := cluster.(VersionGetter)
+	assert.True(t, ok, "should implement VersionGetter")
+
+	// Test case 1: the version is in the Server header
+	version, err := getter.Version(context.Background())
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "3.8.0", version)
+
+	// Test case 2: other servers
+	serverHeader = "nginx"
+	_, err = getter.Version(context.Background())
+	assert.EqualError(t, err, `unknown APISIX version, the Server header is "nginx"`)
+}
+
+func TestAdminAPIV2(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/apisix/admin/ssl":
+			fmt.Fprint(w, `{"action":"get","count":1,"node":{"dir":true,"key":"/apisix/ssl","nodes":[{"key":"/apisix/ssl/ssl1","value":{"id":"ssl1","snis":["apisix.dev"]}}]}}`)
+		case "/apisix/admin/routes":
+			fmt.Fprint(w, `{"action":"get","count":0,"node":{"dir":true,"key":"/apisix/routes","nodes":{}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Test case 1: configured version
+	cluster, err := NewCluster(context.Background(), config.ClientConfig{Server: server.URL, Version: "2.15.3"})
+	assert.Nil(t, err, "should not return error")
+	ssls, err := cluster.SSL().List(context.Background())
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, ssls, 1)
+	assert.Equal(t, "ssl1", ssls[0].ID)
+	routes, err := cluster.Route().List(context.Background())
+	assert.Nil(t, err, "should not return error")
+	assert.Empty(t, routes)
+	assert.Equal(t, []string{"/apisix/admin/ssl", "/apisix/admin/routes"}, requests)
+
+	// Test case 2: switch the admin API
+	cluster, err = NewCluster(context.Background(), config.ClientConfig{Server: server.URL})
+	assert.Nil(t, err, "should not return error")
+	setter, ok := cluster.(AdminAPISetter)
+	assert.True(t, ok, "should implement AdminAPISetter")
+	setter.SetAdminAPIVersion(2)
+	ssls, err = cluster.SSL().List(context.Background())
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, ssls, 1)
+
+	// Test case 3: invalid versions
+	_, err = NewCluster(context.Background(), config.ClientConfig{Server: server.URL, Version: "latest"})
+	assert.EqualError(t, err, `invalid APISIX version "latest"`)
+}
+
+type recordingSpan struct {
+	parent string
+	attrs  map[string]string
+	err    error
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...tracing.Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+func (s *recordingSpan) RecordError(err error) { s.err = err }
+func (s *recordingSpan) End()                  {}
+
+type parentKey struct{}
+
+// recordingTracer records the spans by name.
+type recordingTracer map[string]*recordingSpan
+
+func (t recordingTracer) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+	parent, _ := ctx.Value(parentKey{}).(string)
+	span := &recordingSpan{parent: parent, attrs: map[string]string{}}
+	t[name] = span
+	return context.WithValue(ctx, parentKey{}, name), span
+}
+
+func TestClientTracing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	cluster, err := NewCluster(context.Background(), config.ClientConfig{Server: server.URL})
+	assert.Nil(t, err, "should not return error")
+
+	tracer := recordingTracer{}
+	ctx, parent := tracing.Start(tracing.ContextWithTracer(context.Background(), tracer), "adc.apply")
+	_, err = cluster.(PluginSchemaGetter).PluginSchema(ctx, "key-auth", PluginSchemaTypeRoute)
+	parent.End()
+	assert.True(t, errors.Is(err, ErrNotFound), "should return ErrNotFound")
+
+	span := tracer["apisix GET"]
+	assert.NotNil(t, span, "should trace the request")
+	assert.Equal(t, "adc.apply", span.parent, "should be a child span")
+	assert.Equal(t, map[string]string{
+		"http.method":      "GET",
+		"http.path":        "/apisix/admin/plugins/key-auth",
+		"http.status_code": "404",
+	}, span.attrs)
+}
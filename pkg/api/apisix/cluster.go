@@ -5,8 +5,10 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
+	"fmt"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
@@ -31,6 +33,8 @@ type cluster struct {
 	pluginMetadata PluginMetadata
 	streamRoute    StreamRoute
 	upstream       Upstream
+	secret         Secret
+	proto          Proto
 }
 
 func NewCluster(ctx context.Context, conf config.ClientConfig) (Cluster, error) {
@@ -80,6 +84,15 @@ func NewCluster(ctx context.Context, conf config.ClientConfig) (Cluster, error)
 		cli = newClient(c.baseURL, c.adminKey)
 	}
 
+	if conf.Version != "" {
+		major, _, _ := strings.Cut(conf.Version, ".")
+		n, err := strconv.Atoi(major)
+		if err != nil {
+			return nil, fmt.Errorf("invalid APISIX version %q", conf.Version)
+		}
+		cli.adminAPIVersion = n
+	}
+
 	c.cli = cli
 	c.route = newRoute(cli)
 	c.service = newService(cli)
@@ -91,6 +104,8 @@ func NewCluster(ctx context.Context, conf config.ClientConfig) (Cluster, error)
 	c.pluginMetadata = newPluginMetadata(cli)
 	c.streamRoute = newStreamRoute(cli)
 	c.upstream = newUpstream(cli)
+	c.secret = newSecret(cli)
+	c.proto = newProto(cli)
 
 	return c, nil
 }
@@ -145,6 +160,58 @@ func (c *cluster) Upstream() Upstream {
 	return c.upstream
 }
 
+// Secret implements Cluster.Secret method.
+func (c *cluster) Secret() Secret {
+	return c.secret
+}
+
+// Proto implements Cluster.Proto method.
+func (c *cluster) Proto() Proto {
+	return c.proto
+}
+
+var _ PluginSchemaGetter = (*cluster)(nil)
+
+// PluginSchema implements PluginSchemaGetter.PluginSchema method.
+func (c *cluster) PluginSchema(ctx context.Context, name string, schemaType string) (string, error) {
+	baseURL := strings.TrimSuffix(c.baseURL, "/")
+	if !strings.HasSuffix(baseURL, "/apisix/admin") {
+		baseURL += "/apisix/admin"
+	}
+	u := baseURL + "/plugins/" + url.PathEscape(name)
+	if schemaType != PluginSchemaTypeRoute {
+		u += "?schema_type=" + url.QueryEscape(schemaType)
+	}
+	return c.cli.getSchema(ctx, u)
+}
+
+var _ VersionGetter = (*cluster)(nil)
+
+// Version implements VersionGetter.Version method. APISIX reports its
+// version in the Server header of every response, e.g. "APISIX/3.8.0".
+func (c *cluster) Version(ctx context.Context) (string, error) {
+	baseURL := strings.TrimSuffix(c.baseURL, "/")
+	if !strings.HasSuffix(baseURL, "/apisix/admin") {
+		baseURL += "/apisix/admin"
+	}
+	server, err := c.cli.getServer(ctx, baseURL+"/routes")
+	if err != nil {
+		return "", err
+	}
+	version, ok := strings.CutPrefix(server, "APISIX/")
+	if !ok || version == "" {
+		return "", fmt.Errorf("unknown APISIX version, the Server header is %q", server)
+	}
+	return version, nil
+}
+
+var _ AdminAPISetter = (*cluster)(nil)
+
+// SetAdminAPIVersion implements AdminAPISetter.SetAdminAPIVersion method.
+func (c *cluster) SetAdminAPIVersion(major int) {
+	c.cli.adminAPIVersion = major
+}
+
 func (c *cluster) Ping() error {
 	_, err := c.Route().List(context.Background())
 	return err
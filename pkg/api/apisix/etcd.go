@@ -0,0 +1,357 @@
+package apisix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+	"github.com/api7/adc/pkg/config"
+)
+
+// defaultEtcdPrefix is the default prefix of the keys of APISIX in etcd.
+const defaultEtcdPrefix = "/apisix"
+
+// etcdClient is the client of the JSON gRPC gateway of etcd v3, keys and
+// values are base64 encoded in the requests and the responses.
+type etcdClient struct {
+	endpoint string
+	token    string
+
+	cli *http.Client
+}
+
+type etcdKeyValue struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value,omitempty"`
+}
+
+type etcdRangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+type etcdRangeResponse struct {
+	Kvs []etcdKeyValue `json:"kvs"`
+}
+
+// post sends the request to the API of the gateway, e.g. "kv/range".
+func (c *etcdClient) post(ctx context.Context, api string, request, result interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/v3/"+api, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", c.token)
+	}
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer closeBody(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		respData := &struct {
+			Message string `json:"message"`
+		}{}
+		msg, err := readBody(resp.Body)
+		if err != nil {
+			return err
+		}
+		if json.Unmarshal([]byte(msg), respData) == nil && respData.Message != "" {
+			msg = respData.Message
+		}
+		return &StatusError{StatusCode: resp.StatusCode, Message: msg}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// authenticate gets the token of the user, for the etcd with auth enabled.
+func (c *etcdClient) authenticate(ctx context.Context, username, password string) error {
+	var res struct {
+		Token string `json:"token"`
+	}
+	err := c.post(ctx, "auth/authenticate", map[string]string{"name": username, "password": password}, &res)
+	if err != nil {
+		return fmt.Errorf("failed to authenticate to etcd: %w", err)
+	}
+	c.token = res.Token
+	return nil
+}
+
+func (c *etcdClient) get(ctx context.Context, key string) (*item, error) {
+	var res etcdRangeResponse
+	if err := c.post(ctx, "kv/range", &etcdRangeRequest{Key: []byte(key)}, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, ErrNotFound
+	}
+	return &item{Key: string(res.Kvs[0].Key), Value: res.Kvs[0].Value}, nil
+}
+
+// list returns the items of the keys with the prefix.
+func (c *etcdClient) list(ctx context.Context, prefix string, limit int) (items, error) {
+	var res etcdRangeResponse
+	err := c.post(ctx, "kv/range", &etcdRangeRequest{Key: []byte(prefix), RangeEnd: prefixEnd(prefix), Limit: limit}, &res)
+	if err != nil {
+		return nil, err
+	}
+	list := make(items, 0, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		list = append(list, item{Key: string(kv.Key), Value: kv.Value})
+	}
+	return list, nil
+}
+
+func (c *etcdClient) put(ctx context.Context, key string, value []byte) error {
+	return c.post(ctx, "kv/put", &etcdKeyValue{Key: []byte(key), Value: value}, nil)
+}
+
+func (c *etcdClient) delete(ctx context.Context, key string) error {
+	return c.post(ctx, "kv/deleterange", &etcdRangeRequest{Key: []byte(key)}, nil)
+}
+
+// prefixEnd returns the range end of the keys with the prefix, the prefix
+// with its last byte incremented.
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// all the keys
+	return []byte{0}
+}
+
+// etcdResourceClient is the ResourceClient of the resources stored in etcd
+// under {prefix}/{resourceName}/, the key of a resource is its keyField,
+// e.g. "/apisix/routes/1" or "/apisix/consumers/jack".
+type etcdResourceClient[T any] struct {
+	prefix   string
+	keyField string
+	client   *etcdClient
+}
+
+func newEtcdResourceClient[T any](c *etcdClient, prefix, resourceName, keyField string) *etcdResourceClient[T] {
+	return &etcdResourceClient[T]{
+		prefix:   prefix + "/" + resourceName + "/",
+		keyField: keyField,
+		client:   c,
+	}
+}
+
+func (u *etcdResourceClient[T]) Get(ctx context.Context, name string) (*T, error) {
+	resp, err := u.client.get(ctx, u.prefix+name)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalItem[T](resp)
+}
+
+func (u *etcdResourceClient[T]) List(ctx context.Context) ([]*T, error) {
+	list, err := u.client.list(ctx, u.prefix, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var objs []*T
+	for _, item := range list {
+		obj, err := unmarshalItem[T](&item)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// put stores the resource like the admin API, which creates and updates
+// the resources with PUT requests.
+func (u *etcdResourceClient[T]) put(ctx context.Context, obj *T) (*T, error) {
+	key, err := ResourceField(obj, u.keyField)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, fmt.Errorf("resource %T has an empty %s", obj, u.keyField)
+	}
+	value, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.client.put(ctx, u.prefix+key, value); err != nil {
+		return nil, err
+	}
+	return unmarshalItem[T](&item{Key: u.prefix + key, Value: value})
+}
+
+func (u *etcdResourceClient[T]) Create(ctx context.Context, obj *T) (*T, error) {
+	return u.put(ctx, obj)
+}
+
+func (u *etcdResourceClient[T]) Update(ctx context.Context, obj *T) (*T, error) {
+	return u.put(ctx, obj)
+}
+
+// Delete deletes the resource, deleting a missing one isn't an error like
+// with the admin API. Unlike the admin API, the resources still referenced
+// by others are deleted too.
+func (u *etcdResourceClient[T]) Delete(ctx context.Context, name string) error {
+	return u.client.delete(ctx, u.prefix+name)
+}
+
+// Validate does nothing, the resources are validated by the admin API.
+func (u *etcdResourceClient[T]) Validate(context.Context, *T) error {
+	return nil
+}
+
+type etcdCluster struct {
+	prefix string
+	cli    *etcdClient
+
+	route          Route
+	service        Service
+	consumer       Consumer
+	ssl            SSL
+	globalRule     GlobalRule
+	pluginConfig   PluginConfig
+	consumerGroup  ConsumerGroup
+	pluginMetadata PluginMetadata
+	streamRoute    StreamRoute
+	upstream       Upstream
+	secret         Secret
+	proto          Proto
+}
+
+// NewEtcdCluster returns the cluster writing the resources to the etcd of
+// APISIX directly, for the environments not exposing the admin API. The
+// resources are stored with the key layout and the JSON of the admin API of
+// APISIX 3.x, but they are neither validated nor checked for references,
+// and the create_time and update_time fields aren't set.
+func NewEtcdCluster(ctx context.Context, conf config.EtcdConfig) (Cluster, error) {
+	prefix := strings.TrimSuffix(conf.Prefix, "/")
+	if prefix == "" {
+		prefix = defaultEtcdPrefix
+	}
+	cli := &etcdClient{
+		endpoint: strings.TrimSuffix(conf.Endpoint, "/"),
+		cli:      &http.Client{Timeout: 5 * time.Second, Transport: newTransport()},
+	}
+	if conf.Username != "" {
+		if err := cli.authenticate(ctx, conf.Username, conf.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	return &etcdCluster{
+		prefix:         prefix,
+		cli:            cli,
+		route:          newEtcdResourceClient[types.Route](cli, prefix, "routes", "ID"),
+		service:        newEtcdResourceClient[types.Service](cli, prefix, "services", "ID"),
+		consumer:       newEtcdResourceClient[types.Consumer](cli, prefix, "consumers", "Username"),
+		ssl:            newEtcdResourceClient[types.SSL](cli, prefix, "ssls", "ID"),
+		globalRule:     newEtcdResourceClient[types.GlobalRule](cli, prefix, "global_rules", "ID"),
+		pluginConfig:   newEtcdResourceClient[types.PluginConfig](cli, prefix, "plugin_configs", "ID"),
+		consumerGroup:  newEtcdResourceClient[types.ConsumerGroup](cli, prefix, "consumer_groups", "ID"),
+		pluginMetadata: newEtcdResourceClient[types.PluginMetadata](cli, prefix, "plugin_metadata", "ID"),
+		streamRoute:    newEtcdResourceClient[types.StreamRoute](cli, prefix, "stream_routes", "ID"),
+		upstream:       newEtcdResourceClient[types.Upstream](cli, prefix, "upstreams", "ID"),
+		secret:         newEtcdResourceClient[types.Secret](cli, prefix, "secrets", "ID"),
+		proto:          newEtcdResourceClient[types.Proto](cli, prefix, "protos", "ID"),
+	}, nil
+}
+
+// Route implements Cluster.Route method.
+func (c *etcdCluster) Route() Route {
+	return c.route
+}
+
+// Service implements Cluster.Service method.
+func (c *etcdCluster) Service() Service {
+	return c.service
+}
+
+// Consumer implements Cluster.Consumer method.
+func (c *etcdCluster) Consumer() Consumer {
+	return c.consumer
+}
+
+// SSL implements Cluster.SSL method.
+func (c *etcdCluster) SSL() SSL {
+	return c.ssl
+}
+
+// GlobalRule implements Cluster.GlobalRule method.
+func (c *etcdCluster) GlobalRule() GlobalRule {
+	return c.globalRule
+}
+
+// PluginConfig implements Cluster.PluginConfig method.
+func (c *etcdCluster) PluginConfig() PluginConfig {
+	return c.pluginConfig
+}
+
+// ConsumerGroup implements Cluster.ConsumerGroup method.
+func (c *etcdCluster) ConsumerGroup() ConsumerGroup {
+	return c.consumerGroup
+}
+
+// PluginMetadata implements Cluster.PluginMetadata method.
+func (c *etcdCluster) PluginMetadata() PluginMetadata {
+	return c.pluginMetadata
+}
+
+// StreamRoute implements Cluster.StreamRoute method.
+func (c *etcdCluster) StreamRoute() StreamRoute {
+	return c.streamRoute
+}
+
+// Upstream implements Cluster.Upstream method.
+func (c *etcdCluster) Upstream() Upstream {
+	return c.upstream
+}
+
+// Secret implements Cluster.Secret method.
+func (c *etcdCluster) Secret() Secret {
+	return c.secret
+}
+
+// Proto implements Cluster.Proto method.
+func (c *etcdCluster) Proto() Proto {
+	return c.proto
+}
+
+// Ping implements Cluster.Ping method, it reads a key of APISIX.
+func (c *etcdCluster) Ping() error {
+	_, err := c.cli.list(context.Background(), c.prefix+"/", 1)
+	return err
+}
+
+// SupportValidate implements Cluster.SupportValidate method, the resources
+// can't be validated without the admin API.
+func (c *etcdCluster) SupportValidate() (bool, error) {
+	return false, nil
+}
+
+// SupportStreamRoute implements Cluster.SupportStreamRoute method, whether
+// the stream mode of APISIX is enabled isn't stored in etcd, so the stream
+// routes are always written.
+func (c *etcdCluster) SupportStreamRoute() (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,139 @@
+package apisix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+	"github.com/api7/adc/pkg/config"
+)
+
+// newFakeEtcd returns a fake of the gRPC gateway of etcd storing the keys
+// in kvs, the requests must have the token.
+func newFakeEtcd(kvs map[string]string, token string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Key      []byte `json:"key"`
+			RangeEnd []byte `json:"range_end"`
+			Value    []byte `json:"value"`
+			Name     string `json:"name"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path == "/v3/auth/authenticate" {
+			if req.Name != "root" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error":"authentication failed","code":3,"message":"authentication failed"}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": token})
+			return
+		}
+		if r.Header.Get("Authorization") != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		key := string(req.Key)
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			var keys []string
+			for k := range kvs {
+				if k == key || (req.RangeEnd != nil && k >= key && k < string(req.RangeEnd)) {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			var res etcdRangeResponse
+			for _, k := range keys {
+				res.Kvs = append(res.Kvs, etcdKeyValue{Key: []byte(k), Value: []byte(kvs[k])})
+			}
+			_ = json.NewEncoder(w).Encode(&res)
+		case "/v3/kv/put":
+			kvs[key] = string(req.Value)
+			_, _ = w.Write([]byte(`{}`))
+		case "/v3/kv/deleterange":
+			delete(kvs, key)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestEtcdCluster(t *testing.T) {
+	kvs := map[string]string{
+		"/apisix/routes":          `"init_dir"`,
+		"/apisix/routes/existing": `{"id":"existing","uri":"/existing"}`,
+	}
+	server := newFakeEtcd(kvs, "token")
+	defer server.Close()
+
+	cluster, err := NewEtcdCluster(context.Background(), config.EtcdConfig{Endpoint: server.URL, Username: "root", Password: "secret"})
+	assert.Nil(t, err, "should not return error")
+	assert.Nil(t, cluster.Ping(), "should ping etcd")
+
+	// Test case 1: the resources are stored with the key layout of APISIX
+	_, err = cluster.Route().Create(context.Background(), &types.Route{ID: "route", Name: "route", Uri: "/get"})
+	assert.Nil(t, err, "should not return error")
+	assert.JSONEq(t, `{"id":"route","name":"route","uri":"/get"}`, kvs["/apisix/routes/route"])
+	_, err = cluster.Consumer().Create(context.Background(), &types.Consumer{Username: "jack"})
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, kvs, "/apisix/consumers/jack")
+	_, err = cluster.Secret().Create(context.Background(), &types.Secret{ID: "vault/1", URI: "http://127.0.0.1:8200"})
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, kvs, "/apisix/secrets/vault/1")
+
+	// Test case 2: get and list, excluding the directory key of APISIX 2.x
+	got, err := cluster.Route().Get(context.Background(), "route")
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "/get", got.Uri)
+	routes, err := cluster.Route().List(context.Background())
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, routes, 2)
+	secrets, err := cluster.Secret().List(context.Background())
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "vault/1", secrets[0].ID)
+	_, err = cluster.Upstream().Get(context.Background(), "missing")
+	assert.True(t, errors.Is(err, ErrNotFound), "should return ErrNotFound")
+
+	// Test case 3: update and delete
+	_, err = cluster.Route().Update(context.Background(), &types.Route{ID: "route", Name: "route", Uri: "/post"})
+	assert.Nil(t, err, "should not return error")
+	assert.JSONEq(t, `{"id":"route","name":"route","uri":"/post"}`, kvs["/apisix/routes/route"])
+	assert.Nil(t, cluster.Route().Delete(context.Background(), "route"), "should not return error")
+	assert.NotContains(t, kvs, "/apisix/routes/route")
+
+	// Test case 4: resources without key
+	_, err = cluster.Route().Create(context.Background(), &types.Route{Uri: "/get"})
+	assert.EqualError(t, err, "resource *types.Route has an empty ID")
+}
+
+func TestEtcdClusterPrefix(t *testing.T) {
+	kvs := map[string]string{}
+	server := newFakeEtcd(kvs, "")
+	defer server.Close()
+
+	// Test case 1: custom prefix
+	cluster, err := NewEtcdCluster(context.Background(), config.EtcdConfig{Endpoint: server.URL + "/", Prefix: "/prod/"})
+	assert.Nil(t, err, "should not return error")
+	_, err = cluster.PluginMetadata().Create(context.Background(), &types.PluginMetadata{ID: "http-logger", Config: map[string]interface{}{"log_format": "$host"}})
+	assert.Nil(t, err, "should not return error")
+	for key := range kvs {
+		assert.True(t, strings.HasPrefix(key, "/prod/plugin_metadata/"), "unexpected key %s", key)
+	}
+	metadata, err := cluster.PluginMetadata().List(context.Background())
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "http-logger", metadata[0].ID)
+
+	// Test case 2: failed authentication
+	_, err = NewEtcdCluster(context.Background(), config.EtcdConfig{Endpoint: server.URL, Username: "jack"})
+	assert.EqualError(t, err, "failed to authenticate to etcd: unexpected status code 401; authentication failed")
+}
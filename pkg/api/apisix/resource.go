@@ -22,6 +22,17 @@ type listResponse struct {
 	List  items       `json:"list"`
 }
 
+// v2Response is the response mapping of APISIX 2.x, which wraps the item,
+// or the items of LIST responses, in a node.
+type v2Response struct {
+	Node v2Node `json:"node"`
+}
+
+type v2Node struct {
+	item
+	Nodes items `json:"nodes"`
+}
+
 // IntOrString processing number and string types, after json deserialization will output int
 type IntOrString struct {
 	IntValue int `json:"int_value"`
@@ -80,6 +91,11 @@ func unmarshalItem[T any](i *item) (*T, error) {
 		any(obj).(*types.PluginMetadata).ID = list[len(list)-1]
 	case types.PluginMetadata:
 		any(&obj).(*types.PluginMetadata).ID = list[len(list)-1]
+	case types.Secret:
+		// patch Secret since the ID is composed of the manager and the id
+		if len(list) >= 2 {
+			any(&obj).(*types.Secret).ID = list[len(list)-2] + "/" + list[len(list)-1]
+		}
 	}
 
 	return &obj, nil
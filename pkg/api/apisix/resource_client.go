@@ -11,8 +11,6 @@ import (
 type resourceClient[T any] struct {
 	baseURL      string
 	resourceName string
-	resourceURL  string
-	validateURL  string
 	client       *Client
 }
 
@@ -28,14 +26,40 @@ func newResourceClient[T any](c *Client, resourceName string) *resourceClient[T]
 	return &resourceClient[T]{
 		baseURL:      baseURL,
 		resourceName: resourceName,
-		resourceURL:  baseURL + resourceName,
-		validateURL:  baseURL + "schema/validate/" + resourceName,
 		client:       c,
 	}
 }
 
+// v2ResourceNames are the names of the resources in the admin API of
+// APISIX 2.x which were renamed in 3.x.
+var v2ResourceNames = map[string]string{
+	"ssls":   "ssl",
+	"protos": "proto",
+}
+
+// name returns the name of the resources in the admin API used by the
+// client.
+func (u *resourceClient[T]) name() string {
+	if name, ok := v2ResourceNames[u.resourceName]; ok && u.client.v2() {
+		return name
+	}
+	return u.resourceName
+}
+
+func (u *resourceClient[T]) resourceURL() string {
+	return u.baseURL + u.name()
+}
+
+func (u *resourceClient[T]) itemURL(id string) string {
+	return u.resourceURL() + "/" + id
+}
+
+func (u *resourceClient[T]) validateURL() string {
+	return u.baseURL + "schema/validate/" + u.name()
+}
+
 func (u *resourceClient[T]) Get(ctx context.Context, name string) (*T, error) {
-	url := u.resourceURL + "/" + name
+	url := u.itemURL(name)
 	resp, err := u.client.getResource(ctx, url)
 	if err != nil {
 		return nil, err
@@ -49,7 +73,7 @@ func (u *resourceClient[T]) Get(ctx context.Context, name string) (*T, error) {
 }
 
 func (u *resourceClient[T]) List(ctx context.Context) ([]*T, error) {
-	svcItems, err := u.client.listResource(ctx, u.resourceURL)
+	svcItems, err := u.client.listResource(ctx, u.resourceURL())
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +94,7 @@ func (u *resourceClient[T]) Create(ctx context.Context, id string, obj *T) (*T,
 	if err != nil {
 		return nil, err
 	}
-	url := u.resourceURL + "/" + id
+	url := u.itemURL(id)
 
 	resp, err := u.client.createResource(ctx, url, body)
 	if err != nil {
@@ -84,7 +108,7 @@ func (u *resourceClient[T]) Create(ctx context.Context, id string, obj *T) (*T,
 }
 
 func (u *resourceClient[T]) Delete(ctx context.Context, name string) error {
-	url := u.resourceURL + "/" + name
+	url := u.itemURL(name)
 	if err := u.client.deleteResource(ctx, url); err != nil {
 		return err
 	}
@@ -97,7 +121,7 @@ func (u *resourceClient[T]) Update(ctx context.Context, id string, obj *T) (*T,
 		return nil, err
 	}
 
-	url := u.resourceURL + "/" + id
+	url := u.itemURL(id)
 	resp, err := u.client.updateResource(ctx, url, body)
 	if err != nil {
 		return nil, err
@@ -109,21 +133,79 @@ func (u *resourceClient[T]) Update(ctx context.Context, id string, obj *T) (*T,
 	return svc, err
 }
 
-func GetResourceUniqueKey(resource interface{}) string {
+// ResourceUniqueKey returns the ID, Name or Username of the resource,
+// whichever is found first. An error is returned if the resource is nil,
+// not a struct, or has none of these string fields.
+func ResourceUniqueKey(resource interface{}) (string, error) {
+	value, err := resourceStruct(resource)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range []string{"ID", "Name", "Username"} {
+		if key, ok := stringField(value, name); ok {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("resource %T has no ID, Name or Username field", resource)
+}
+
+// ResourceField returns the string field of the resource, e.g. "Username".
+// An error is returned if the resource is nil, not a struct, or doesn't
+// have the string field.
+func ResourceField(resource interface{}, field string) (string, error) {
+	value, err := resourceStruct(resource)
+	if err != nil {
+		return "", err
+	}
+	if key, ok := stringField(value, field); ok {
+		return key, nil
+	}
+	return "", fmt.Errorf("resource %T has no %s field", resource, field)
+}
+
+func resourceStruct(resource interface{}) (reflect.Value, error) {
 	value := reflect.ValueOf(resource)
-	value = reflect.Indirect(value)
-	nameOrID := value.FieldByName("ID")
-	if !nameOrID.IsValid() {
-		nameOrID = value.FieldByName("Name")
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return value, fmt.Errorf("resource is nil")
+		}
+		value = value.Elem()
 	}
-	if !nameOrID.IsValid() {
-		nameOrID = value.FieldByName("Username")
+	if value.Kind() != reflect.Struct {
+		return value, fmt.Errorf("resource must be a struct, got %T", resource)
 	}
-	return nameOrID.String()
+	return value, nil
+}
+
+func stringField(value reflect.Value, name string) (string, bool) {
+	field, ok := value.Type().FieldByName(name)
+	if !ok || field.Type.Kind() != reflect.String {
+		return "", false
+	}
+	// FieldByIndexErr doesn't panic through nil embedded pointers
+	v, err := value.FieldByIndexErr(field.Index)
+	if err != nil {
+		return "", false
+	}
+	return v.String(), true
+}
+
+// GetResourceUniqueKey is ResourceUniqueKey without the error, it returns
+// an empty string for invalid resources.
+func GetResourceUniqueKey(resource interface{}) string {
+	key, _ := ResourceUniqueKey(resource)
+	return key
+}
+
+// GetResourceID returns the ID field of the resource, or an empty string
+// if the resource is nil, not a struct, or has no ID field.
+func GetResourceID(resource interface{}) string {
+	id, _ := ResourceField(resource, "ID")
+	return id
 }
 
 func (u *resourceClient[T]) Validate(ctx context.Context, resource *T) error {
-	err := u.client.validate(ctx, u.validateURL, resource)
+	err := u.client.validate(ctx, u.validateURL(), resource)
 	if err != nil {
 		return fmt.Errorf("failed to validate resource '%s (%s)': %s", u.resourceName, GetResourceUniqueKey(resource), err.Error())
 	}
@@ -0,0 +1,67 @@
+package apisix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestResourceUniqueKey(t *testing.T) {
+	// Test case 1: ID, Name and Username are looked up in order
+	key, err := ResourceUniqueKey(&types.Route{ID: "route", Name: "name"})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "route", key)
+
+	key, err = ResourceUniqueKey(&types.Consumer{Username: "jack"})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "jack", key)
+
+	key, err = ResourceUniqueKey(types.Service{ID: "svc"})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "svc", key)
+
+	// Test case 2: nil
+	_, err = ResourceUniqueKey(nil)
+	assert.EqualError(t, err, "resource must be a struct, got <nil>")
+	_, err = ResourceUniqueKey((*types.Route)(nil))
+	assert.EqualError(t, err, "resource is nil")
+
+	// Test case 3: map
+	_, err = ResourceUniqueKey(map[string]interface{}{"id": "route"})
+	assert.EqualError(t, err, "resource must be a struct, got map[string]interface {}")
+
+	// Test case 4: struct without the fields
+	_, err = ResourceUniqueKey(&struct{ Desc string }{Desc: "route"})
+	assert.EqualError(t, err, "resource *struct { Desc string } has no ID, Name or Username field")
+	_, err = ResourceUniqueKey(&struct{ ID int }{ID: 1})
+	assert.EqualError(t, err, "resource *struct { ID int } has no ID, Name or Username field")
+
+	// Test case 5: a nil embedded struct doesn't panic
+	type embedded struct {
+		*types.Route
+	}
+	_, err = ResourceUniqueKey(&embedded{})
+	assert.EqualError(t, err, "resource *apisix.embedded has no ID, Name or Username field")
+	assert.Equal(t, "", GetResourceUniqueKey(&embedded{}))
+}
+
+func TestGetResourceID(t *testing.T) {
+	// Test case 1: the ID is returned even if there is a name
+	assert.Equal(t, "route", GetResourceID(&types.Route{ID: "route", Name: "name"}))
+	assert.Equal(t, "ssl", GetResourceID(types.SSL{ID: "ssl"}))
+
+	// Test case 2: resources without ID
+	assert.Equal(t, "", GetResourceID(&types.Consumer{Username: "jack"}))
+	assert.Equal(t, "", GetResourceID(&struct{ ID int }{ID: 1}))
+
+	// Test case 3: invalid resources don't panic
+	type embedded struct {
+		*types.Route
+	}
+	assert.Equal(t, "", GetResourceID(nil))
+	assert.Equal(t, "", GetResourceID((*types.Route)(nil)))
+	assert.Equal(t, "", GetResourceID(map[string]interface{}{"id": "route"}))
+	assert.Equal(t, "", GetResourceID(&embedded{}))
+}
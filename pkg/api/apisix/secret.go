@@ -0,0 +1,26 @@
+package apisix
+
+import (
+	"context"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+type secretClient struct {
+	*resourceClient[types.Secret]
+}
+
+func newSecret(c *Client) Secret {
+	cli := newResourceClient[types.Secret](c, "secrets")
+	return &secretClient{
+		resourceClient: cli,
+	}
+}
+
+func (u *secretClient) Create(ctx context.Context, obj *types.Secret) (*types.Secret, error) {
+	return u.resourceClient.Create(ctx, obj.ID, obj)
+}
+
+func (u *secretClient) Update(ctx context.Context, obj *types.Secret) (*types.Secret, error) {
+	return u.resourceClient.Update(ctx, obj.ID, obj)
+}
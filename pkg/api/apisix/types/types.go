@@ -31,23 +31,27 @@ func FilterResources[T HasLabels](filters Labels, resources []T) []T {
 	}
 	var filtered []T
 	for _, res := range resources {
-		labels := res.GetLabels()
-		if len(labels) > 0 {
-			matchAll := true
-			for k, v := range filters {
-				if label, ok := labels[k]; !ok || label != v {
-					matchAll = false
-					break
-				}
-			}
-			if matchAll {
-				filtered = append(filtered, res)
-			}
+		if MatchLabels(filters, res.GetLabels()) {
+			filtered = append(filtered, res)
 		}
 	}
 	return filtered
 }
 
+// MatchLabels reports whether the labels contain all the filters,
+// resources without labels never match.
+func MatchLabels(filters, labels Labels) bool {
+	if len(labels) == 0 {
+		return false
+	}
+	for k, v := range filters {
+		if label, ok := labels[k]; !ok || label != v {
+			return false
+		}
+	}
+	return true
+}
+
 // Configuration is the configuration of services
 type Configuration struct {
 	Name            string             `yaml:"name" json:"name"`
@@ -63,6 +67,8 @@ type Configuration struct {
 	PluginMetadatas []*PluginMetadata  `yaml:"plugin_metadatas,omitempty" json:"plugin_metadatas,omitempty"`
 	StreamRoutes    []*StreamRoute     `yaml:"stream_routes,omitempty" json:"stream_routes,omitempty"`
 	Upstreams       []*Upstream        `yaml:"upstreams,omitempty" json:"upstreams,omitempty"`
+	Secrets         []*Secret          `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+	Protos          []*Proto           `yaml:"protos,omitempty" json:"protos,omitempty"`
 }
 
 type ConfigurationMode string
@@ -677,3 +683,44 @@ func (s *StreamRoute) UnmarshalJSON(cont []byte) error {
 	SetStreamRouteDefaultValues(s)
 	return nil
 }
+
+// Secret represents the secret object in APISIX.
+// The ID is composed of the secret manager and the secret id, e.g. "vault/1",
+// which matches the admin API path "/apisix/admin/secrets/vault/1".
+type Secret struct {
+	ID string `json:"id" yaml:"id"`
+
+	// vault
+	URI       string `json:"uri,omitempty" yaml:"uri,omitempty"`
+	Prefix    string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Token     string `json:"token,omitempty" yaml:"token,omitempty"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+
+	// aws
+	AccessKeyID     string `json:"access_key_id,omitempty" yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty" yaml:"secret_access_key,omitempty"`
+	SessionToken    string `json:"session_token,omitempty" yaml:"session_token,omitempty"`
+	Region          string `json:"region,omitempty" yaml:"region,omitempty"`
+	EndpointURL     string `json:"endpoint_url,omitempty" yaml:"endpoint_url,omitempty"`
+}
+
+// Proto represents the protobuf definition used by the grpc-transcode plugin.
+type Proto struct {
+	ID     string `json:"id" yaml:"id"`
+	Desc   string `json:"desc,omitempty" yaml:"desc,omitempty"`
+	Labels Labels `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Content is the multi-line source of the .proto file
+	Content string `json:"content" yaml:"content"`
+}
+
+func (p *Proto) GetLabels() Labels {
+	return p.Labels
+}
+
+func (p *Proto) SetLabel(k, v string) {
+	if p.Labels == nil {
+		p.Labels = map[string]string{}
+	}
+	p.Labels[k] = v
+}
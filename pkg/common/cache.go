@@ -0,0 +1,143 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// RemoteCache caches the remote configuration on disk, one file per
+// cluster endpoint. The files contain the plugin configurations, secrets
+// included, so they are only readable by the current user.
+type RemoteCache struct {
+	// Dir is the directory of the cache files.
+	Dir string
+	// TTL is how long a cached configuration is used, the cache is
+	// disabled if it is not positive.
+	TTL time.Duration
+
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	Endpoint      string               `json:"endpoint"`
+	FetchedAt     time.Time            `json:"fetched_at"`
+	Configuration *types.Configuration `json:"configuration"`
+}
+
+// NewRemoteCache returns the cache in the "adc" directory of the user
+// cache directory, e.g. ~/.cache/adc on Linux.
+func NewRemoteCache(ttl time.Duration) (*RemoteCache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteCache{
+		Dir: filepath.Join(dir, "adc"),
+		TTL: ttl,
+	}, nil
+}
+
+func (c *RemoteCache) path(endpoint string) string {
+	sum := sha256.Sum256([]byte(endpoint))
+	return filepath.Join(c.Dir, "remote-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+func (c *RemoteCache) since(t time.Time) time.Duration {
+	if c.now == nil {
+		return time.Since(t)
+	}
+	return c.now().Sub(t)
+}
+
+// Load returns the cached configuration of the endpoint, ok is false if
+// there is none, it has expired, or it was cached for another endpoint.
+func (c *RemoteCache) Load(endpoint string) (conf *types.Configuration, ok bool, err error) {
+	if c.TTL <= 0 {
+		return nil, false, nil
+	}
+	data, err := os.ReadFile(c.path(endpoint))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// a corrupted cache is refreshed
+		return nil, false, nil
+	}
+	if entry.Endpoint != endpoint || entry.Configuration == nil || c.since(entry.FetchedAt) >= c.TTL {
+		return nil, false, nil
+	}
+	return entry.Configuration, true, nil
+}
+
+// Save caches the configuration of the endpoint.
+func (c *RemoteCache) Save(endpoint string, conf *types.Configuration) error {
+	if c.TTL <= 0 {
+		return nil
+	}
+	fetchedAt := time.Now()
+	if c.now != nil {
+		fetchedAt = c.now()
+	}
+	data, err := json.Marshal(&cacheEntry{
+		Endpoint:      endpoint,
+		FetchedAt:     fetchedAt,
+		Configuration: conf,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path(endpoint), data, 0o600)
+}
+
+// Invalidate removes the cached configuration of the endpoint, e.g. after
+// the cluster is changed.
+func (c *RemoteCache) Invalidate(endpoint string) error {
+	err := os.Remove(c.path(endpoint))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// GetContentFromRemoteCached is GetContentFromRemoteWithContext using the
+// cache of the endpoint when it is fresh, unless refresh is set. A fetched
+// configuration is cached for the next calls, failing to cache it is only
+// a warning.
+func GetContentFromRemoteCached(ctx context.Context, cluster apisix.Cluster, cache *RemoteCache, endpoint string, refresh bool) (*types.Configuration, error) {
+	if !refresh {
+		conf, ok, err := cache.Load(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return conf, nil
+		}
+	}
+
+	conf, err := GetContentFromRemoteWithContext(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.Save(endpoint, conf); err != nil {
+		color.Yellow("Failed to cache the remote configuration: %v", err)
+	}
+	return conf, nil
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestRemoteCache(t *testing.T) {
+	now := time.Now()
+	cache := &RemoteCache{
+		Dir: t.TempDir(),
+		TTL: time.Minute,
+		now: func() time.Time { return now },
+	}
+	endpoint := "http://127.0.0.1:9180"
+	conf := &types.Configuration{
+		Routes: []*types.Route{{ID: "route", Name: "route", Uris: []string{"/get"}}},
+	}
+
+	// Test case 1: nothing is cached
+	_, ok, err := cache.Load(endpoint)
+	assert.Nil(t, err, "should not return error")
+	assert.False(t, ok, "should miss")
+
+	// Test case 2: the cached configuration is fresh
+	assert.Nil(t, cache.Save(endpoint, conf), "should not return error")
+	cached, ok, err := cache.Load(endpoint)
+	assert.Nil(t, err, "should not return error")
+	assert.True(t, ok, "should hit")
+	assert.Equal(t, "route", cached.Routes[0].ID)
+	info, err := os.Stat(cache.path(endpoint))
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "should only be readable by the user")
+
+	// Test case 3: another endpoint
+	_, ok, err = cache.Load("http://127.0.0.1:9280")
+	assert.Nil(t, err, "should not return error")
+	assert.False(t, ok, "should miss for another endpoint")
+
+	// Test case 4: the cached configuration expires
+	now = now.Add(time.Minute)
+	_, ok, err = cache.Load(endpoint)
+	assert.Nil(t, err, "should not return error")
+	assert.False(t, ok, "should expire after the TTL")
+
+	// Test case 5: invalidate
+	now = now.Add(-time.Minute)
+	assert.Nil(t, cache.Invalidate(endpoint), "should not return error")
+	_, ok, err = cache.Load(endpoint)
+	assert.Nil(t, err, "should not return error")
+	assert.False(t, ok, "should miss after invalidate")
+	assert.Nil(t, cache.Invalidate(endpoint), "should ignore a missing cache")
+
+	// Test case 6: a corrupted cache is refreshed
+	assert.Nil(t, os.WriteFile(cache.path(endpoint), []byte("{"), 0o600))
+	_, ok, err = cache.Load(endpoint)
+	assert.Nil(t, err, "should not return error")
+	assert.False(t, ok, "should miss")
+
+	// Test case 7: disabled
+	cache = &RemoteCache{Dir: t.TempDir()}
+	assert.Nil(t, cache.Save(endpoint, conf), "should not return error")
+	_, err = os.Stat(cache.path(endpoint))
+	assert.True(t, os.IsNotExist(err), "should not write the cache")
+}
@@ -3,10 +3,14 @@ package common
 import (
 	"bufio"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"sync"
 
 	"github.com/fatih/color"
+	"go.uber.org/multierr"
 	"sigs.k8s.io/yaml"
 
 	"github.com/api7/adc/pkg/api/apisix"
@@ -75,6 +79,11 @@ func NormalizeConfiguration(content *types.Configuration) {
 				route.SetLabel(k, v)
 			}
 		}
+		for _, route := range content.Protos {
+			for k, v := range labels {
+				route.SetLabel(k, v)
+			}
+		}
 	}
 }
 
@@ -107,69 +116,75 @@ func GetContentFromFile(filename string) (*types.Configuration, error) {
 	return &content, nil
 }
 
-func GetContentFromRemote(cluster apisix.Cluster) (*types.Configuration, error) {
-	svcs, err := cluster.Service().List(context.Background())
-	if err != nil {
-		return nil, err
-	}
-
-	routes, err := cluster.Route().List(context.Background())
-	if err != nil {
-		return nil, err
-	}
-
-	consumers, err := cluster.Consumer().List(context.Background())
-	if err != nil {
-		return nil, err
-	}
-
-	ssls, err := cluster.SSL().List(context.Background())
-	if err != nil {
-		return nil, err
-	}
-
-	globalRules, err := cluster.GlobalRule().List(context.Background())
-	if err != nil {
-		return nil, err
-	}
-
-	pluginConfigs, err := cluster.PluginConfig().List(context.Background())
-	if err != nil {
-		return nil, err
-	}
+// RemoteListConcurrency is the maximum number of list requests sent in
+// parallel by GetContentFromRemoteWithContext.
+const RemoteListConcurrency = 4
 
-	consumerGroups, err := cluster.ConsumerGroup().List(context.Background())
-	if err != nil {
-		return nil, err
-	}
-
-	pluginMetadatas, err := cluster.PluginMetadata().List(context.Background())
-	if err != nil {
-		return nil, err
-	}
+// GetContentFromRemote is GetContentFromRemoteWithContext with the
+// background context.
+func GetContentFromRemote(cluster apisix.Cluster) (*types.Configuration, error) {
+	return GetContentFromRemoteWithContext(context.Background(), cluster)
+}
 
-	streamRoutes, err := cluster.StreamRoute().List(context.Background())
-	if err != nil {
+// GetContentFromRemoteWithContext lists the resources of every type from
+// the cluster. The types are independent, so they are listed concurrently
+// with at most RemoteListConcurrency requests in flight. The returned error
+// combines the errors of all the failed lists.
+func GetContentFromRemoteWithContext(ctx context.Context, cluster apisix.Cluster) (*types.Configuration, error) {
+	conf := &types.Configuration{}
+	lists := []func(ctx context.Context) error{
+		listInto[types.Service]("service", cluster.Service(), &conf.Services),
+		listInto[types.Route]("route", cluster.Route(), &conf.Routes),
+		listInto[types.Consumer]("consumer", cluster.Consumer(), &conf.Consumers),
+		listInto[types.SSL]("ssl", cluster.SSL(), &conf.SSLs),
+		listInto[types.GlobalRule]("global_rule", cluster.GlobalRule(), &conf.GlobalRules),
+		listInto[types.PluginConfig]("plugin_config", cluster.PluginConfig(), &conf.PluginConfigs),
+		listInto[types.ConsumerGroup]("consumer_group", cluster.ConsumerGroup(), &conf.ConsumerGroups),
+		listInto[types.PluginMetadata]("plugin_metadata", cluster.PluginMetadata(), &conf.PluginMetadatas),
+		listInto[types.StreamRoute]("stream_route", cluster.StreamRoute(), &conf.StreamRoutes),
+		listInto[types.Upstream]("upstream", cluster.Upstream(), &conf.Upstreams),
+		func(ctx context.Context) error {
+			err := listInto[types.Secret]("secret", cluster.Secret(), &conf.Secrets)(ctx)
+			// the secret API is only available since APISIX 3.x
+			if errors.Is(err, apisix.ErrNotFound) {
+				return nil
+			}
+			return err
+		},
+		listInto[types.Proto]("proto", cluster.Proto(), &conf.Protos),
+	}
+
+	errs := make([]error, len(lists))
+	sem := make(chan struct{}, RemoteListConcurrency)
+	var wg sync.WaitGroup
+	for i, list := range lists {
+		i, list := i, list
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = list(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if err := multierr.Combine(errs...); err != nil {
 		return nil, err
 	}
+	return conf, nil
+}
 
-	upstream, err := cluster.Upstream().List(context.Background())
-	if err != nil {
-		return nil, err
+// listInto returns the function listing the resources into the list.
+func listInto[T any](typ string, client apisix.ResourceClient[T], list *[]*T) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		items, err := client.List(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", typ, err)
+		}
+		*list = items
+		return nil
 	}
-
-	return &types.Configuration{
-		Routes:          routes,
-		Services:        svcs,
-		Consumers:       consumers,
-		SSLs:            ssls,
-		GlobalRules:     globalRules,
-		PluginConfigs:   pluginConfigs,
-		ConsumerGroups:  consumerGroups,
-		PluginMetadatas: pluginMetadatas,
-		StreamRoutes:    streamRoutes,
-		Upstreams:       upstream,
-	}, nil
 }
 
 func SaveAPISIXConfiguration(path string, conf *types.Configuration) error {
@@ -11,4 +11,21 @@ type ClientConfig struct {
 	Certificate    string
 	CertificateKey string
 	Insecure       bool
+
+	// Version is the version of APISIX, e.g. "2.15.3", to use its admin API
+	// without detecting it.
+	Version string
+}
+
+// EtcdConfig is the configuration of the etcd of APISIX, for the clusters
+// applying the resources to etcd directly instead of the admin API.
+type EtcdConfig struct {
+	// Endpoint is the URL of the gRPC gateway of etcd, e.g.
+	// "http://127.0.0.1:2379".
+	Endpoint string
+	// Prefix is the prefix of the keys of APISIX, "/apisix" if empty.
+	Prefix string
+
+	Username string
+	Password string
 }
@@ -0,0 +1,72 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+// GitHubActions reports whether adc runs in GitHub Actions, where the
+// annotations of the workflow commands are shown in the UI.
+func GitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// Annotation returns the "::notice" workflow command of GitHub Actions
+// annotating the change of the event, e.g.
+//
+//	::notice title=update route "route1"::changed `desc`, `uris`
+//
+// Updates that change nothing have no annotation.
+func (e *Event) Annotation() (string, error) {
+	noop, err := e.IsNoOp()
+	if err != nil || noop {
+		return "", err
+	}
+	changes, err := e.changeSummary()
+	if err != nil {
+		return "", err
+	}
+	title := fmt.Sprintf("%s %s \"%s\"", e.Option, e.ResourceType, e.key())
+	return workflowCommand("notice", title, changes), nil
+}
+
+// ErrorAnnotation returns the "::error" workflow command of GitHub Actions
+// annotating the error, with a command for each error of a multierr. The
+// errors of events are titled with the failed event.
+func ErrorAnnotation(err error) string {
+	var commands []string
+	for _, err := range multierr.Errors(err) {
+		title := "adc failed"
+		msg := err.Error()
+		var eventErr *EventError
+		if errors.As(err, &eventErr) {
+			title = fmt.Sprintf("failed to %s %s \"%s\"", eventErr.Event.Option, eventErr.Event.ResourceType, eventErr.Event.key())
+			msg = eventErr.Err.Error()
+		}
+		commands = append(commands, workflowCommand("error", title, msg))
+	}
+	return strings.Join(commands, "\n")
+}
+
+// workflowCommand returns the workflow command with the escaped title and
+// message.
+func workflowCommand(command, title, msg string) string {
+	return fmt.Sprintf("::%s title=%s::%s", command, escapeProperty(title), escapeData(msg))
+}
+
+var (
+	dataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	propertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func escapeData(s string) string {
+	return dataEscaper.Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return propertyEscaper.Replace(s)
+}
@@ -0,0 +1,49 @@
+package data
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+)
+
+func TestAnnotation(t *testing.T) {
+	// Test case 1: changes
+	route1 := *route
+	route1.Description = "route1"
+	annotation, err := (&Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: &route1}).Annotation()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "::notice title=update route \"route\"::changed `desc`", annotation)
+
+	annotation, err = (&Event{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc}).Annotation()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "::notice title=create service \"svc\"::new resource", annotation)
+
+	// Test case 2: no annotation without change
+	annotation, err = (&Event{ResourceType: ServiceResourceType, Option: UpdateOption, OldValue: svc, Value: svc}).Annotation()
+	assert.Nil(t, err, "should not return error")
+	assert.Empty(t, annotation)
+}
+
+func TestErrorAnnotation(t *testing.T) {
+	event := &Event{ResourceType: ServiceResourceType, Option: DeleteOption, OldValue: svc}
+	err := multierr.Combine(
+		&EventError{Event: event, Err: errors.New("still in use,\nby route: route")},
+		errors.New("100% failed"),
+	)
+	assert.Equal(t, "::error title=failed to delete service \"svc\"::still in use,%0Aby route: route\n"+
+		"::error title=adc failed::100%25 failed", ErrorAnnotation(err))
+
+	// Test case 2: a single error and the escaping of the properties
+	err = &EventError{Event: &Event{ResourceType: RouteResourceType, Option: CreateOption, Value: route}, Err: errors.New("invalid")}
+	assert.Equal(t, "::error title=failed to create route \"route\"::invalid", ErrorAnnotation(err))
+	assert.Equal(t, "::notice title=a%3A b%2C c::d: e, f", workflowCommand("notice", "a: b, c", "d: e, f"))
+}
+
+func TestGitHubActions(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	assert.True(t, GitHubActions())
+	t.Setenv("GITHUB_ACTIONS", "")
+	assert.False(t, GitHubActions())
+}
@@ -0,0 +1,334 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/tracing"
+)
+
+// phases splits the events into groups of consecutive events with the same
+// resource type and option. Events in the same phase don't depend on each
+// other, so they can be applied concurrently.
+func phases(events []*Event) [][]*Event {
+	var result [][]*Event
+	for i, event := range events {
+		if i == 0 || event.ResourceType != events[i-1].ResourceType || event.Option != events[i-1].Option {
+			result = append(result, nil)
+		}
+		result[len(result)-1] = append(result[len(result)-1], event)
+	}
+	return result
+}
+
+// EventError is the error of applying an event.
+type EventError struct {
+	Event *Event
+	Err   error
+}
+
+func (e *EventError) Error() string {
+	return fmt.Sprintf("%s \"%s\": %s", e.Event.ResourceType, e.Event.key(), e.Err)
+}
+
+func (e *EventError) Unwrap() error {
+	return e.Err
+}
+
+// key returns the unique key of the resource changed by the event, or an
+// empty string if the resource is invalid.
+func (e *Event) key() string {
+	key, _ := e.resourceKey()
+	return key
+}
+
+// identifiers are the fields identifying the resources of each type, which
+// are used to print and delete them. Consumers are identified by username,
+// the others by id. The id of plugin metadata is the name of the plugin.
+var identifiers = map[ResourceType]string{
+	ServiceResourceType:        "ID",
+	RouteResourceType:          "ID",
+	ConsumerResourceType:       "Username",
+	SSLResourceType:            "ID",
+	GlobalRuleResourceType:     "ID",
+	PluginConfigResourceType:   "ID",
+	ConsumerGroupResourceType:  "ID",
+	PluginMetadataResourceType: "ID",
+	StreamRouteResourceType:    "ID",
+	UpstreamResourceType:       "ID",
+	SecretResourceType:         "ID",
+	ProtoResourceType:          "ID",
+}
+
+// resourceKey returns the unique key of the resource changed by the event,
+// that is the identifier of the old value for deletes and of the new value
+// otherwise. See identifiers.
+func (e *Event) resourceKey() (string, error) {
+	value := e.Value
+	if e.Option == DeleteOption {
+		value = e.OldValue
+	}
+	var (
+		key string
+		err error
+	)
+	if field, ok := identifiers[e.ResourceType]; ok {
+		key, err = apisix.ResourceField(value, field)
+	} else {
+		key, err = apisix.ResourceUniqueKey(value)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid %s event", e.ResourceType)
+	}
+	return key, nil
+}
+
+// DefaultConcurrency is the number of events applied in parallel if
+// ApplyOptions.Concurrency is not set.
+const DefaultConcurrency = 4
+
+// ProgressFunc is called after each event is applied, err is the error
+// of applying the event, or nil on success.
+type ProgressFunc func(event Event, err error)
+
+// ApplyOptions are the options of ApplyAllWithOptions.
+type ApplyOptions struct {
+	// Concurrency is the maximum number of events applied in parallel, zero
+	// or less means DefaultConcurrency. Only the events of the same phase
+	// run in parallel, so 1 applies the events one by one in the order of
+	// SortEvents. With a cluster of NewRateLimitedCluster, the workers wait
+	// for the limiter of the resource type, so the writes of a type never
+	// exceed its rate limit whatever the concurrency.
+	Concurrency int
+	// ContinueOnError applies the remaining events after a failure.
+	ContinueOnError bool
+	// Retry retries the events failing with a retryable error with the
+	// policy if not nil, see ApplyWithRetry. The bulk requests are not
+	// retried.
+	Retry *RetryPolicy
+	// Progress is called after each event if not nil. The calls are
+	// serialized, so it doesn't need to be safe for concurrent use.
+	Progress ProgressFunc
+	// Validate validates all the events with ValidateEvents, ValidateUnique
+	// and ValidateReferences before applying any of them, nothing is applied
+	// if some are invalid.
+	Validate bool
+	// ValidateSchema validates all the events with ValidateSchemas before
+	// applying any of them, like Validate.
+	ValidateSchema bool
+	// PluginValidator validates the plugins of all the events against the
+	// plugin schemas of the cluster before applying any of them if not nil,
+	// like Validate.
+	PluginValidator *PluginValidator
+	// Filters select the events to apply, see FilterEvents. The others are
+	// dropped before validating, so they are neither validated nor applied.
+	// Any predicate can be a filter, combined with the built-in ones like
+	// ByResourceType, and Not turns it into an exclusion.
+	Filters []Filter
+	// Bulk applies the creates and updates of the same resource type in a
+	// single request when the cluster implements BulkWriter, other clusters
+	// apply the events one by one.
+	Bulk bool
+	// UpsertCreates applies the creates like upserts: the resource is
+	// looked up with a GET first and updated if it already exists, instead
+	// of failing. It costs a request per create, so it should be left unset
+	// when the created resources are known to be new, e.g. in the events of
+	// Differ. In bulk phases, the creates are still recorded as upserts, but
+	// BulkWriter already creates or updates them.
+	UpsertCreates bool
+	// DeleteMode is how the deletes are applied, HardDelete if empty, see
+	// DisableDelete.
+	DeleteMode DeleteMode
+	// Force writes the updates that change nothing and the noop events too,
+	// instead of skipping them, see ContextWithForce.
+	Force bool
+	// Metrics records every applied event if not nil, see Metrics.
+	Metrics Metrics
+	// Logger logs the start and the outcome of every event if not nil, the
+	// logger of ContextWithLogger is used if it is.
+	Logger Logger
+	// BeforeApply is called before every event if not nil, the event is
+	// skipped and failed with the error if it returns one. AfterApply is
+	// called after every event, skipped ones included, if not nil. The
+	// hooks run in the workers, so they must be safe for concurrent use
+	// with a Concurrency above 1.
+	BeforeApply BeforeApplyFunc
+	AfterApply  AfterApplyFunc
+}
+
+// ApplyAll applies the events to the cluster with at most concurrency
+// workers, see ApplyOptions.Concurrency. The events are sorted by their
+// dependencies with SortEvents, phases are applied one by one and only the
+// events of the same phase run in parallel.
+// By default, it stops at the first failed event: no other event is
+// started, but the ones already being applied by the other workers are
+// completed, so with a concurrency above 1 more events may be applied or
+// fail after the first failure. With continueOnError, it applies all the
+// remaining events instead. In both cases the returned error combines an
+// EventError for every failed event, see ApplyAllWithResults for the
+// outcome of each event.
+func ApplyAll(ctx context.Context, cluster apisix.Cluster, events []*Event, concurrency int, continueOnError bool) error {
+	return ApplyAllWithOptions(ctx, cluster, events, ApplyOptions{
+		Concurrency:     concurrency,
+		ContinueOnError: continueOnError,
+	})
+}
+
+// ApplyAllWithOptions is ApplyAll with options. With a tracer in ctx, it is
+// traced in an "adc.apply_all" span, parent of the spans of the events.
+func ApplyAllWithOptions(ctx context.Context, cluster apisix.Cluster, events []*Event, opts ApplyOptions) error {
+	return applyAll(ctx, cluster, events, opts, nil)
+}
+
+// applyAll implements ApplyAllWithOptions, recording the outcome of every
+// applied event into results if not nil.
+func applyAll(ctx context.Context, cluster apisix.Cluster, events []*Event, opts ApplyOptions, results *resultRecorder) (err error) {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	events = FilterEvents(events, opts.Filters...)
+	sorted := SortEvents(events)
+	results.plan(sorted, opts.Force)
+
+	if opts.Force {
+		ctx = ContextWithForce(ctx)
+	}
+	if logger := loggerFromContext(ctx); logger != nil {
+		// the events are logged with the options, not by Event.Apply
+		if opts.Logger == nil {
+			opts.Logger = logger
+		}
+		ctx = ContextWithLogger(ctx, nil)
+	}
+	ctx, span := tracing.Start(ctx, "adc.apply_all", tracing.String("adc.events", strconv.Itoa(len(events))))
+	defer func() { tracing.End(span, err) }()
+
+	if opts.Validate {
+		err := multierr.Combine(ValidateEvents(events), ValidateUnique(events), ValidateReferences(ctx, cluster, events))
+		if err != nil {
+			return err
+		}
+	}
+	if opts.ValidateSchema {
+		if err := ValidateSchemas(events); err != nil {
+			return err
+		}
+	}
+	if opts.PluginValidator != nil {
+		if err := opts.PluginValidator.Validate(ctx, events); err != nil {
+			return err
+		}
+	}
+
+	var errs error
+	for _, phase := range phases(sorted) {
+		errs = multierr.Append(errs, applyPhase(ctx, cluster, phase, &opts, results))
+		if errs != nil && (!opts.ContinueOnError || ctx.Err() != nil) {
+			break
+		}
+	}
+	return errs
+}
+
+func applyPhase(ctx context.Context, cluster apisix.Cluster, events []*Event, opts *ApplyOptions, results *resultRecorder) error {
+	if writer, ok := bulkable(cluster, events); ok && opts.Bulk {
+		return applyBulk(ctx, cluster, writer, events, opts, results)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs error
+
+		progressMu sync.Mutex
+	)
+
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return errs != nil
+	}
+
+	queue := make(chan *Event)
+	for i := 0; i < opts.Concurrency && i < len(events); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for event := range queue {
+				target := opts.target(event)
+				err := opts.beforeApply(ctx, event)
+				if err == nil {
+					start := opts.startTimer()
+					opts.logStart(event)
+					err = opts.apply(ctx, cluster, target)
+					opts.observe(event, start, err)
+					opts.logFinish(event, start, err)
+				}
+				if err != nil {
+					mu.Lock()
+					errs = multierr.Append(errs, &EventError{Event: event, Err: err})
+					mu.Unlock()
+				}
+				opts.finish(ctx, event, target, err, results, &progressMu)
+			}
+		}()
+	}
+
+	for _, event := range events {
+		if err := ctx.Err(); err != nil {
+			mu.Lock()
+			errs = multierr.Append(errs, err)
+			mu.Unlock()
+			break
+		}
+		if !opts.ContinueOnError && failed() {
+			break
+		}
+		queue <- event
+	}
+	close(queue)
+	wg.Wait()
+
+	return errs
+}
+
+// finish reports the outcome of the event applied as target, err is nil on
+// success: the after hook is called, the outcome is recorded into results
+// and Progress is called. The calls of Progress are serialized with mu if
+// not nil.
+func (o *ApplyOptions) finish(ctx context.Context, event, target *Event, err error, results *resultRecorder, mu *sync.Mutex) {
+	o.afterApply(ctx, event, err)
+	results.record(event, target, err)
+	if o.Progress == nil {
+		return
+	}
+	if mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
+	o.Progress(*event, err)
+}
+
+// target returns the event to apply for the event: the upsert of a create
+// with UpsertCreates, and the update disabling the deleted resource with
+// DisableDelete if it can be disabled. The creates without identifier
+// can't be looked up, they are left as is.
+func (o *ApplyOptions) target(event *Event) *Event {
+	switch {
+	case o.UpsertCreates && event.Option == CreateOption && event.key() != "":
+		upsert := *event
+		upsert.Option = UpsertOption
+		return &upsert
+	case o.DeleteMode == DisableDelete:
+		if disabled, ok := event.Disable(); ok {
+			return &disabled
+		}
+	}
+	return event
+}
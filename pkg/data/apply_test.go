@@ -0,0 +1,218 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func routeEvents(n int) []*Event {
+	events := make([]*Event, 0, n)
+	for i := 0; i < n; i++ {
+		events = append(events, &Event{
+			ResourceType: RouteResourceType,
+			Option:       CreateOption,
+			Value: &types.Route{
+				ID:        fmt.Sprintf("route%d", i),
+				Name:      fmt.Sprintf("route%d", i),
+				Uris:      []string{"/get"},
+				ServiceID: "svc",
+			},
+		})
+	}
+	return events
+}
+
+// orderedRouteClient records whether a route is created before its service.
+type orderedRouteClient struct {
+	*fakeResourceClient[types.Route]
+	services *fakeResourceClient[types.Service]
+
+	mu     sync.Mutex
+	orphan bool
+}
+
+func (c *orderedRouteClient) Create(ctx context.Context, obj *types.Route) (*types.Route, error) {
+	if _, err := c.services.Get(ctx, obj.ServiceID); err != nil {
+		c.mu.Lock()
+		c.orphan = true
+		c.mu.Unlock()
+	}
+	return c.fakeResourceClient.Create(ctx, obj)
+}
+
+type orderedCluster struct {
+	*fakeCluster
+	routes *orderedRouteClient
+}
+
+func (c *orderedCluster) Route() apisix.Route { return c.routes }
+
+func TestApplyAll(t *testing.T) {
+	// Test case 1: services must be applied before routes
+	fake := newFakeCluster()
+	fake.service.delay = 10 * time.Millisecond
+	cluster := &orderedCluster{
+		fakeCluster: fake,
+		routes: &orderedRouteClient{
+			fakeResourceClient: fake.route,
+			services:           fake.service,
+		},
+	}
+	events := append([]*Event{
+		{
+			ResourceType: ServiceResourceType,
+			Option:       CreateOption,
+			Value:        svc,
+		},
+	}, routeEvents(20)...)
+	assert.Nil(t, ApplyAll(context.Background(), cluster, events, 8, false), "should apply all events")
+	assert.False(t, cluster.routes.orphan, "should create the service before routes")
+	assert.Len(t, fake.route.items, 20, "should create all routes")
+
+	// Test case 2: stops at the first failure
+	fake = newFakeCluster()
+	fake.route.err = errors.New("unexpected status code 400; invalid route")
+	events = append(routeEvents(3), &Event{
+		ResourceType: ServiceResourceType,
+		Option:       DeleteOption,
+		OldValue:     svc,
+	})
+	err := ApplyAll(context.Background(), fake, events, 1, false)
+	assert.Equal(t, "route \"route0\": failed to apply route: unexpected status code 400; invalid route", err.Error())
+	assert.Len(t, fake.route.calls, 1, "should not apply the remaining routes")
+	assert.Empty(t, fake.service.calls, "should not apply the next phase")
+
+	// Test case 3: continue on error
+	fake = newFakeCluster()
+	fake.route.err = errors.New("unexpected status code 400; invalid route")
+	err = ApplyAll(context.Background(), fake, events, 2, true)
+	errs := multierr.Errors(err)
+	assert.Len(t, errs, 3, "should aggregate the errors of all events")
+	for _, err := range errs {
+		var eventErr *EventError
+		assert.True(t, errors.As(err, &eventErr), "should be an event error")
+		assert.Equal(t, RouteResourceType, eventErr.Event.ResourceType)
+		assert.Contains(t, err.Error(), "failed to apply route: unexpected status code 400; invalid route")
+	}
+	assert.Equal(t, []string{"delete:svc"}, fake.service.calls, "should apply the next phase")
+
+	// Test case 4: canceled context
+	fake = newFakeCluster()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = ApplyAll(ctx, fake, routeEvents(3), 2, false)
+	assert.True(t, errors.Is(err, context.Canceled), "should return the context error")
+	assert.Empty(t, fake.route.calls, "should not apply any event")
+}
+
+func TestApplyAllSequential(t *testing.T) {
+	fake := newFakeCluster()
+	// the delay lets the events overtake each other if applied in parallel
+	fake.route.delay = time.Millisecond
+	events := append(routeEvents(10), &Event{
+		ResourceType: ServiceResourceType,
+		Option:       CreateOption,
+		Value:        svc,
+	}, &Event{
+		ResourceType: ConsumerResourceType,
+		Option:       DeleteOption,
+		OldValue:     consumer,
+	})
+	var order []string
+	err := ApplyAllWithOptions(context.Background(), fake, events, ApplyOptions{
+		Concurrency: 1,
+		Progress: func(event Event, _ error) {
+			order = append(order, string(event.ResourceType)+":"+event.key())
+		},
+	})
+	assert.Nil(t, err, "should apply all events")
+
+	var expected []string
+	for _, event := range SortEvents(events) {
+		expected = append(expected, string(event.ResourceType)+":"+event.key())
+	}
+	assert.Equal(t, expected, order, "should apply the events in the sorted order")
+}
+
+func TestApplyAllUpsertCreates(t *testing.T) {
+	route1 := *route
+	route1.Description = "route1"
+	events := append(routeEvents(2), &Event{
+		ResourceType: RouteResourceType,
+		Option:       CreateOption,
+		Value:        &route1,
+	})
+
+	// Test case 1: the existing resources are updated
+	fake := newFakeCluster()
+	fake.route.items["route"] = route
+	err := ApplyAllWithOptions(context.Background(), fake, events, ApplyOptions{Concurrency: 1, UpsertCreates: true})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []string{"create:route0", "create:route1", "update:route"}, fake.route.calls)
+	assert.Equal(t, &route1, fake.route.items["route"])
+	assert.Equal(t, CreateOption, events[2].Option, "should not change the events")
+
+	// Test case 2: the creates are sent as is by default
+	fake = newFakeCluster()
+	fake.route.items["route"] = route
+	fake.route.err = errors.New("unexpected status code 400; route already exists")
+	err = ApplyAllWithOptions(context.Background(), fake, events[2:], ApplyOptions{})
+	assert.EqualError(t, err, "route \"route\": failed to apply route: unexpected status code 400; route already exists")
+	assert.Equal(t, []string{"create:route"}, fake.route.calls, "should send the create")
+}
+
+func benchmarkApplyAll(b *testing.B, concurrency int) {
+	events := routeEvents(1000)
+	for i := 0; i < b.N; i++ {
+		cluster := newFakeCluster()
+		cluster.route.delay = 100 * time.Microsecond
+		if err := ApplyAll(context.Background(), cluster, events, concurrency, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkApplyAllSequential(b *testing.B) { benchmarkApplyAll(b, 1) }
+func BenchmarkApplyAllParallel(b *testing.B)   { benchmarkApplyAll(b, 16) }
+
+func TestApplyAllProgress(t *testing.T) {
+	fake := newFakeCluster()
+	fake.service.err = errors.New("unexpected status code 400; invalid service")
+
+	var (
+		applied []string
+		failed  []string
+	)
+	events := append(routeEvents(5), &Event{
+		ResourceType: ServiceResourceType,
+		Option:       CreateOption,
+		Value:        svc,
+	})
+	err := ApplyAllWithOptions(context.Background(), fake, events, ApplyOptions{
+		Concurrency:     4,
+		ContinueOnError: true,
+		Progress: func(event Event, err error) {
+			if err != nil {
+				failed = append(failed, event.key())
+			} else {
+				applied = append(applied, event.key())
+			}
+		},
+	})
+	assert.NotNil(t, err, "should return error")
+	assert.Equal(t, []string{"svc"}, failed, "should report the failed event")
+	assert.ElementsMatch(t, []string{"route0", "route1", "route2", "route3", "route4"}, applied, "should report every applied event")
+
+	// a nil progress is ignored
+	assert.Nil(t, ApplyAllWithOptions(context.Background(), newFakeCluster(), routeEvents(2), ApplyOptions{}))
+}
@@ -0,0 +1,126 @@
+package data
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/tracing"
+)
+
+// BulkWriter is implemented by the clusters able to write many resources
+// of the same type in a single request, APISIX itself doesn't support it.
+type BulkWriter interface {
+	// WriteBulk creates or updates all the values, which are of the Go type
+	// of the resource type, e.g. *types.Route for routes.
+	WriteBulk(ctx context.Context, typ ResourceType, values []interface{}) error
+}
+
+// bulkable reports whether the phase can be applied with the bulk writer of
+// the cluster, or of a cluster it wraps, only creates, updates and upserts
+// are batched. The bulk requests respect the limiter of a wrapping
+// NewRateLimitedCluster.
+func bulkable(cluster apisix.Cluster, events []*Event) (BulkWriter, bool) {
+	writer, ok := findCluster[BulkWriter](cluster)
+	if !ok || len(events) < 2 || events[0].Option == DeleteOption || events[0].Option == NoOpOption {
+		return nil, false
+	}
+	if limited, ok := findCluster[*rateLimitedCluster](cluster); ok {
+		writer = &rateLimitedBulkWriter{BulkWriter: writer, limiter: limited.limiter}
+	}
+	return writer, true
+}
+
+// applyBulk applies the events of a phase in a single request. Each event
+// is checked like Event.Apply first: it is applied as the event returned by
+// ApplyOptions.target, it is validated and checked against the version of
+// the cluster, and the updates that change nothing are skipped unless
+// forced. Then the before hooks are called, nothing is written if some
+// events are invalid or aborted, and those are reported like the events
+// failing in applyPhase. The written events are applied or failed
+// together, so the error and the duration of the request are reported for
+// each of them.
+func applyBulk(ctx context.Context, cluster apisix.Cluster, writer BulkWriter, events []*Event, opts *ApplyOptions, results *resultRecorder) (errs error) {
+	typ := events[0].ResourceType
+	ctx, span := tracing.Start(ctx, "adc.apply_bulk",
+		tracing.String("adc.resource_type", string(typ)),
+		tracing.String("adc.events", strconv.Itoa(len(events))),
+	)
+	defer func() { tracing.End(span, errs) }()
+
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "cancelled before applying "+string(typ))
+	}
+
+	targets := make([]*Event, len(events))
+	values := make([]interface{}, 0, len(events))
+	for i, event := range events {
+		targets[i] = opts.target(event)
+		start := opts.startTimer()
+		write, err := targets[i].bulkWrite(ctx, cluster)
+		if err != nil {
+			// the rejected events are reported like the ones failing in
+			// Event.Apply
+			opts.logStart(event)
+			opts.observe(event, start, err)
+			opts.logFinish(event, start, err)
+			errs = multierr.Append(errs, &EventError{Event: event, Err: err})
+			opts.finish(ctx, event, targets[i], err, results, nil)
+			continue
+		}
+		if write {
+			values = append(values, targets[i].Value)
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	for i, event := range events {
+		if err := opts.beforeApply(ctx, event); err != nil {
+			errs = multierr.Append(errs, &EventError{Event: event, Err: err})
+			opts.finish(ctx, event, targets[i], err, results, nil)
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+
+	start := opts.startTimer()
+	for _, event := range events {
+		opts.logStart(event)
+	}
+	var err error
+	if len(values) > 0 {
+		if err = writer.WriteBulk(ctx, typ, values); err != nil {
+			err = errors.Wrap(err, "failed to apply "+string(typ))
+		}
+	}
+	for i, event := range events {
+		opts.observe(event, start, err)
+		opts.logFinish(event, start, err)
+		if err != nil {
+			errs = multierr.Append(errs, &EventError{Event: event, Err: err})
+		}
+		opts.finish(ctx, event, targets[i], err, results, nil)
+	}
+	return errs
+}
+
+// bulkWrite checks the event like Event.Apply and reports whether its value
+// is written, the updates that change nothing are not unless forced.
+func (e *Event) bulkWrite(ctx context.Context, cluster apisix.Cluster) (bool, error) {
+	if err := e.Validate(); err != nil {
+		return false, err
+	}
+	if err := e.checkVersion(cluster); err != nil {
+		return false, errors.Wrapf(err, "failed to apply %s", e.ResourceType)
+	}
+	noop, err := e.IsNoOp()
+	if err != nil {
+		return false, err
+	}
+	return !noop || forced(ctx), nil
+}
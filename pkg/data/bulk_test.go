@@ -0,0 +1,200 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// bulkCluster is a fake cluster writing the routes in bulk.
+type bulkCluster struct {
+	*fakeCluster
+	requests int
+	err      error
+}
+
+func (c *bulkCluster) WriteBulk(ctx context.Context, typ ResourceType, values []interface{}) error {
+	c.requests++
+	if err := c.route.wait(ctx); err != nil {
+		return err
+	}
+	if c.err != nil {
+		return c.err
+	}
+	for _, value := range values {
+		route := value.(*types.Route)
+		c.route.items[route.ID] = route
+	}
+	return nil
+}
+
+func TestApplyAllBulk(t *testing.T) {
+	// Test case 1: the routes are created in one request
+	cluster := &bulkCluster{fakeCluster: newFakeCluster()}
+	var applied int
+	err := ApplyAllWithOptions(context.Background(), cluster, routeEvents(5), ApplyOptions{
+		Bulk: true,
+		Progress: func(event Event, err error) {
+			assert.Nil(t, err, "should not report error")
+			applied++
+		},
+	})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, 1, cluster.requests, "should send a single request")
+	assert.Len(t, cluster.route.items, 5, "should create all the routes")
+	assert.Empty(t, cluster.route.calls, "should not apply the events one by one")
+	assert.Equal(t, 5, applied, "should report every event")
+
+	// Test case 2: the error is reported for every event
+	cluster = &bulkCluster{fakeCluster: newFakeCluster(), err: errors.New("unexpected status code 400")}
+	err = ApplyAllWithOptions(context.Background(), cluster, routeEvents(3), ApplyOptions{Bulk: true})
+	errs := multierr.Errors(err)
+	assert.Len(t, errs, 3, "should fail every event")
+	assert.Equal(t, "route \"route0\": failed to apply route: unexpected status code 400", errs[0].Error())
+
+	// Test case 3: nothing is written if some events are invalid
+	cluster = &bulkCluster{fakeCluster: newFakeCluster()}
+	events := routeEvents(3)
+	events[1].Value = &types.Route{ID: "route1"}
+	err = ApplyAllWithOptions(context.Background(), cluster, events, ApplyOptions{Bulk: true})
+	assert.Contains(t, err.Error(), "route \"route1\": invalid route event")
+	assert.Equal(t, 0, cluster.requests, "should not send the request")
+
+	// Test case 4: deletes and single events are applied one by one
+	cluster = &bulkCluster{fakeCluster: newFakeCluster()}
+	events = []*Event{
+		{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: route},
+		{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: &types.Route{ID: "route1"}},
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+	}
+	err = ApplyAllWithOptions(context.Background(), cluster, events, ApplyOptions{Bulk: true})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, 0, cluster.requests, "should not send bulk requests")
+	assert.ElementsMatch(t, []string{"delete:route", "delete:route1"}, cluster.route.calls)
+	assert.Equal(t, []string{"create:svc"}, cluster.service.calls)
+
+	// Test case 5: the bulk writer is opt-in
+	cluster = &bulkCluster{fakeCluster: newFakeCluster()}
+	assert.Nil(t, ApplyAll(context.Background(), cluster, routeEvents(2), 1, false))
+	assert.Equal(t, 0, cluster.requests, "should not send bulk requests")
+	assert.Len(t, cluster.route.calls, 2, "should apply the events one by one")
+
+	// Test case 6: the updates that change nothing are not written unless
+	// forced
+	events = routeEvents(3)
+	for i, event := range events {
+		event.Option, event.OldValue = UpdateOption, event.Value
+		if i == 0 {
+			event.OldValue = &types.Route{ID: "route0", Name: "route0", Uris: []string{"/post"}, ServiceID: "svc"}
+		}
+	}
+	cluster = &bulkCluster{fakeCluster: newFakeCluster()}
+	results, err := ApplyAllWithResults(context.Background(), cluster, events, ApplyOptions{Bulk: true})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, 1, cluster.requests, "should send a single request")
+	assert.Equal(t, []string{"route0"}, keys(cluster.route.items), "should only write the changed route")
+	assert.Equal(t, []*Event{events[1], events[2]}, results.Events(SkippedStatus))
+
+	cluster = &bulkCluster{fakeCluster: newFakeCluster()}
+	assert.Nil(t, ApplyAllWithOptions(context.Background(), cluster, events[1:], ApplyOptions{Bulk: true}))
+	assert.Equal(t, 0, cluster.requests, "should not send a request without changes")
+
+	cluster = &bulkCluster{fakeCluster: newFakeCluster()}
+	assert.Nil(t, ApplyAllWithOptions(context.Background(), cluster, events, ApplyOptions{Bulk: true, Force: true}))
+	assert.Len(t, cluster.route.items, 3, "should write all the routes when forced")
+
+	// Test case 7: the creates are recorded as upserts with UpsertCreates
+	cluster = &bulkCluster{fakeCluster: newFakeCluster()}
+	results, err = ApplyAllWithResults(context.Background(), cluster, routeEvents(2), ApplyOptions{Bulk: true, UpsertCreates: true})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, 1, cluster.requests, "should send a single request")
+	for _, result := range results {
+		assert.Equal(t, UpsertOption, result.Applied.Option, "should apply the creates as upserts")
+	}
+
+	// Test case 8: the rejected and aborted events are reported like the
+	// failed ones
+	var (
+		progress = map[string]error{}
+		after    = map[string]error{}
+	)
+	opts := ApplyOptions{
+		Bulk: true,
+		Progress: func(event Event, err error) {
+			progress[event.key()] = err
+		},
+		AfterApply: func(_ context.Context, event *Event, err error) {
+			after[event.key()] = err
+		},
+	}
+	cluster = &bulkCluster{fakeCluster: newFakeCluster()}
+	events = routeEvents(3)
+	events[1].Value = &types.Route{ID: "route1"}
+	assert.NotNil(t, ApplyAllWithOptions(context.Background(), cluster, events, opts), "should return error")
+	assert.Equal(t, []string{"route1"}, keys(progress), "should report the rejected event")
+	assert.Contains(t, progress["route1"].Error(), "invalid route event")
+	assert.Equal(t, progress, after, "should call the after hook for the rejected event")
+
+	progress, after = map[string]error{}, map[string]error{}
+	opts.BeforeApply = func(_ context.Context, event *Event) error {
+		if event.key() == "route0" {
+			return errors.New("not now")
+		}
+		return nil
+	}
+	assert.NotNil(t, ApplyAllWithOptions(context.Background(), cluster, routeEvents(2), opts), "should return error")
+	assert.Equal(t, []string{"route0"}, keys(progress), "should report the aborted event")
+	assert.EqualError(t, progress["route0"], "aborted by the before apply hook: not now")
+	assert.Equal(t, progress, after, "should call the after hook for the aborted event")
+	assert.Equal(t, 0, cluster.requests, "should not send the request")
+}
+
+func TestApplyAllBulkWrapped(t *testing.T) {
+	// Test case 1: the bulk writer is found through the wrappers
+	cluster := &bulkCluster{fakeCluster: newFakeCluster()}
+	wrapped := NewVersionedCluster(NewRateLimitedCluster(cluster, NewRateLimiter(RateLimits{Default: 1000})), Version{Major: 3})
+	assert.Nil(t, ApplyAllWithOptions(context.Background(), wrapped, routeEvents(3), ApplyOptions{Bulk: true}))
+	assert.Equal(t, 1, cluster.requests, "should send a single request")
+	assert.Empty(t, cluster.route.calls, "should not apply the events one by one")
+
+	// Test case 2: the events are checked against the version of the cluster
+	cluster = &bulkCluster{fakeCluster: newFakeCluster()}
+	events := []*Event{
+		{ResourceType: ConsumerGroupResourceType, Option: CreateOption, Value: &types.ConsumerGroup{ID: "group1", Plugins: types.Plugins{"limit-count": {}}}},
+		{ResourceType: ConsumerGroupResourceType, Option: CreateOption, Value: &types.ConsumerGroup{ID: "group2", Plugins: types.Plugins{"limit-count": {}}}},
+	}
+	err := ApplyAllWithOptions(context.Background(), NewVersionedCluster(cluster, Version{Major: 2, Minor: 15}), events, ApplyOptions{Bulk: true})
+	assert.Len(t, multierr.Errors(err), 2, "should refuse every event")
+	assert.Contains(t, err.Error(), "consumer groups are not supported by APISIX 2.15.0")
+	assert.Equal(t, 0, cluster.requests, "should not send the request")
+}
+
+func keys[T any](items map[string]T) []string {
+	var result []string
+	for key := range items {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func benchmarkApplyAllBulk(b *testing.B, bulk bool) {
+	events := routeEvents(500)
+	for i := 0; i < b.N; i++ {
+		cluster := &bulkCluster{fakeCluster: newFakeCluster()}
+		cluster.route.delay = 100 * time.Microsecond
+		if err := ApplyAllWithOptions(context.Background(), cluster, events, ApplyOptions{Bulk: bulk}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkApplyAllBulk(b *testing.B)           { benchmarkApplyAllBulk(b, true) }
+func BenchmarkApplyAllBulkSequential(b *testing.B) { benchmarkApplyAllBulk(b, false) }
@@ -0,0 +1,198 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// fakeResourceClient is an in-memory ResourceClient used to test the
+// appliers without a real APISIX instance.
+type fakeResourceClient[T any] struct {
+	mu    sync.Mutex
+	items map[string]*T
+	calls []string
+	err   error
+	// failures is the number of calls failing with err, 0 means all of them
+	failures int
+	// delay simulates the latency of the admin API
+	delay time.Duration
+	// listErr is the error of List
+	listErr error
+}
+
+func newFakeResourceClient[T any]() *fakeResourceClient[T] {
+	return &fakeResourceClient[T]{
+		items: make(map[string]*T),
+	}
+}
+
+func (f *fakeResourceClient[T]) record(call string) {
+	f.calls = append(f.calls, call)
+}
+
+// wait simulates the latency of the admin API, it returns early when
+// the ctx is done like the http client.
+func (f *fakeResourceClient[T]) wait(ctx context.Context) error {
+	if f.delay == 0 {
+		return nil
+	}
+	select {
+	case <-time.After(f.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fail returns the error for the current call.
+func (f *fakeResourceClient[T]) fail() error {
+	if f.err == nil {
+		return nil
+	}
+	if f.failures > 0 {
+		f.failures--
+		if f.failures == 0 {
+			err := f.err
+			f.err = nil
+			return err
+		}
+	}
+	return f.err
+}
+
+func (f *fakeResourceClient[T]) Get(_ context.Context, name string) (*T, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	obj, ok := f.items[name]
+	if !ok {
+		return nil, apisix.ErrNotFound
+	}
+	return obj, nil
+}
+
+func (f *fakeResourceClient[T]) List(ctx context.Context) ([]*T, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	var list []*T
+	for _, obj := range f.items {
+		list = append(list, obj)
+	}
+	return list, nil
+}
+
+func (f *fakeResourceClient[T]) Create(ctx context.Context, obj *T) (*T, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := apisix.GetResourceUniqueKey(obj)
+	f.record("create:" + key)
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	f.items[key] = obj
+	return obj, nil
+}
+
+func (f *fakeResourceClient[T]) Delete(ctx context.Context, name string) error {
+	if err := f.wait(ctx); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("delete:" + name)
+	if err := f.fail(); err != nil {
+		return err
+	}
+	delete(f.items, name)
+	return nil
+}
+
+func (f *fakeResourceClient[T]) Update(ctx context.Context, obj *T) (*T, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := apisix.GetResourceUniqueKey(obj)
+	f.record("update:" + key)
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	f.items[key] = obj
+	return obj, nil
+}
+
+func (f *fakeResourceClient[T]) Validate(_ context.Context, _ *T) error {
+	return nil
+}
+
+// fakeCluster implements apisix.Cluster with in-memory resource clients.
+type fakeCluster struct {
+	route          *fakeResourceClient[types.Route]
+	service        *fakeResourceClient[types.Service]
+	consumer       *fakeResourceClient[types.Consumer]
+	ssl            *fakeResourceClient[types.SSL]
+	globalRule     *fakeResourceClient[types.GlobalRule]
+	pluginConfig   *fakeResourceClient[types.PluginConfig]
+	consumerGroup  *fakeResourceClient[types.ConsumerGroup]
+	pluginMetadata *fakeResourceClient[types.PluginMetadata]
+	streamRoute    *fakeResourceClient[types.StreamRoute]
+	upstream       *fakeResourceClient[types.Upstream]
+	secret         *fakeResourceClient[types.Secret]
+	proto          *fakeResourceClient[types.Proto]
+}
+
+var _ apisix.Cluster = (*fakeCluster)(nil)
+
+func newFakeCluster() *fakeCluster {
+	return &fakeCluster{
+		route:          newFakeResourceClient[types.Route](),
+		service:        newFakeResourceClient[types.Service](),
+		consumer:       newFakeResourceClient[types.Consumer](),
+		ssl:            newFakeResourceClient[types.SSL](),
+		globalRule:     newFakeResourceClient[types.GlobalRule](),
+		pluginConfig:   newFakeResourceClient[types.PluginConfig](),
+		consumerGroup:  newFakeResourceClient[types.ConsumerGroup](),
+		pluginMetadata: newFakeResourceClient[types.PluginMetadata](),
+		streamRoute:    newFakeResourceClient[types.StreamRoute](),
+		upstream:       newFakeResourceClient[types.Upstream](),
+		secret:         newFakeResourceClient[types.Secret](),
+		proto:          newFakeResourceClient[types.Proto](),
+	}
+}
+
+func (c *fakeCluster) Route() apisix.Route                   { return c.route }
+func (c *fakeCluster) Service() apisix.Service               { return c.service }
+func (c *fakeCluster) Consumer() apisix.Consumer             { return c.consumer }
+func (c *fakeCluster) SSL() apisix.SSL                       { return c.ssl }
+func (c *fakeCluster) GlobalRule() apisix.GlobalRule         { return c.globalRule }
+func (c *fakeCluster) PluginConfig() apisix.PluginConfig     { return c.pluginConfig }
+func (c *fakeCluster) ConsumerGroup() apisix.ConsumerGroup   { return c.consumerGroup }
+func (c *fakeCluster) PluginMetadata() apisix.PluginMetadata { return c.pluginMetadata }
+func (c *fakeCluster) StreamRoute() apisix.StreamRoute       { return c.streamRoute }
+func (c *fakeCluster) Upstream() apisix.Upstream             { return c.upstream }
+func (c *fakeCluster) Secret() apisix.Secret                 { return c.secret }
+func (c *fakeCluster) Proto() apisix.Proto                   { return c.proto }
+func (c *fakeCluster) Ping() error                           { return nil }
+func (c *fakeCluster) SupportValidate() (bool, error)        { return true, nil }
+func (c *fakeCluster) SupportStreamRoute() (bool, error)     { return true, nil }
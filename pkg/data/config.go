@@ -0,0 +1,134 @@
+package data
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// The formats supported by WriteConfig.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// ToConfiguration collects the target values of the events into a
+// declarative configuration, grouped by resource type. Create and update
+// events contribute their Value, delete events are omitted.
+func ToConfiguration(events []*Event) (*types.Configuration, error) {
+	conf := &types.Configuration{}
+	for _, event := range events {
+		if event.Option == DeleteOption {
+			continue
+		}
+		if err := addToConfiguration(conf, event); err != nil {
+			return nil, err
+		}
+	}
+	return conf, nil
+}
+
+// FromConfiguration returns the create events of the resources of the
+// configuration, it's the inverse of ToConfiguration.
+func FromConfiguration(conf *types.Configuration) []*Event {
+	var events []*Event
+	events = appendCreates(events, ServiceResourceType, conf.Services)
+	events = appendCreates(events, RouteResourceType, conf.Routes)
+	events = appendCreates(events, ConsumerResourceType, conf.Consumers)
+	events = appendCreates(events, SSLResourceType, conf.SSLs)
+	events = appendCreates(events, GlobalRuleResourceType, conf.GlobalRules)
+	events = appendCreates(events, PluginConfigResourceType, conf.PluginConfigs)
+	events = appendCreates(events, ConsumerGroupResourceType, conf.ConsumerGroups)
+	events = appendCreates(events, PluginMetadataResourceType, conf.PluginMetadatas)
+	events = appendCreates(events, StreamRouteResourceType, conf.StreamRoutes)
+	events = appendCreates(events, UpstreamResourceType, conf.Upstreams)
+	events = appendCreates(events, SecretResourceType, conf.Secrets)
+	events = appendCreates(events, ProtoResourceType, conf.Protos)
+	return events
+}
+
+func appendCreates[T any](events []*Event, typ ResourceType, list []*T) []*Event {
+	for _, obj := range list {
+		events = append(events, &Event{
+			ResourceType: typ,
+			Option:       CreateOption,
+			Value:        obj,
+		})
+	}
+	return events
+}
+
+func addToConfiguration(conf *types.Configuration, event *Event) error {
+	var ok bool
+	switch event.ResourceType {
+	case ServiceResourceType:
+		conf.Services, ok = appendValue(conf.Services, event.Value)
+	case RouteResourceType:
+		conf.Routes, ok = appendValue(conf.Routes, event.Value)
+	case ConsumerResourceType:
+		conf.Consumers, ok = appendValue(conf.Consumers, event.Value)
+	case SSLResourceType:
+		conf.SSLs, ok = appendValue(conf.SSLs, event.Value)
+	case GlobalRuleResourceType:
+		conf.GlobalRules, ok = appendValue(conf.GlobalRules, event.Value)
+	case PluginConfigResourceType:
+		conf.PluginConfigs, ok = appendValue(conf.PluginConfigs, event.Value)
+	case ConsumerGroupResourceType:
+		conf.ConsumerGroups, ok = appendValue(conf.ConsumerGroups, event.Value)
+	case PluginMetadataResourceType:
+		conf.PluginMetadatas, ok = appendValue(conf.PluginMetadatas, event.Value)
+	case StreamRouteResourceType:
+		conf.StreamRoutes, ok = appendValue(conf.StreamRoutes, event.Value)
+	case UpstreamResourceType:
+		conf.Upstreams, ok = appendValue(conf.Upstreams, event.Value)
+	case SecretResourceType:
+		conf.Secrets, ok = appendValue(conf.Secrets, event.Value)
+	case ProtoResourceType:
+		conf.Protos, ok = appendValue(conf.Protos, event.Value)
+	default:
+		return errors.Errorf("unsupported resource type %q", event.ResourceType)
+	}
+	if !ok {
+		return errors.Errorf("invalid %s event: unexpected value %T", event.ResourceType, event.Value)
+	}
+	return nil
+}
+
+func appendValue[T any](list []*T, value interface{}) ([]*T, bool) {
+	v, ok := value.(*T)
+	if !ok || v == nil {
+		return list, false
+	}
+	return append(list, v), true
+}
+
+// WriteConfig writes the declarative configuration of the events to w in
+// the format, FormatYAML or FormatJSON. It's the inverse of loading a
+// configuration file, see ToConfiguration for what is written.
+func WriteConfig(w io.Writer, events []*Event, format string) error {
+	conf, err := ToConfiguration(events)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	switch format {
+	case FormatYAML:
+		out, err = yaml.Marshal(conf)
+	case FormatJSON:
+		out, err = json.MarshalIndent(conf, "", "  ")
+		out = append(out, '\n')
+	default:
+		return errors.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the configuration")
+	}
+
+	_, err = w.Write(out)
+	return err
+}
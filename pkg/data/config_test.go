@@ -0,0 +1,71 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestWriteConfig(t *testing.T) {
+	events := []*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: &types.Route{ID: "route"}, Value: route},
+		{ResourceType: ConsumerResourceType, Option: DeleteOption, OldValue: consumer},
+		{ResourceType: SecretResourceType, Option: CreateOption, Value: &types.Secret{ID: "vault/1", URI: "http://127.0.0.1:8200"}},
+	}
+	expected := &types.Configuration{
+		Services: []*types.Service{svc},
+		Routes:   []*types.Route{route},
+		Secrets:  []*types.Secret{{ID: "vault/1", URI: "http://127.0.0.1:8200"}},
+	}
+
+	// Test case 1: YAML can be loaded back, deletes are omitted
+	var buf bytes.Buffer
+	err := WriteConfig(&buf, events, FormatYAML)
+	assert.Nil(t, err, "should not return error")
+	out, _ := yaml.Marshal(expected)
+	assert.Equal(t, string(out), buf.String())
+	var conf types.Configuration
+	assert.Nil(t, yaml.Unmarshal(buf.Bytes(), &conf), "should be valid YAML")
+	assert.Len(t, conf.Services, 1)
+	assert.Len(t, conf.Routes, 1)
+	assert.Len(t, conf.Secrets, 1)
+	assert.NotContains(t, buf.String(), "jack", "should not contain deleted resources")
+
+	// Test case 2: JSON
+	buf.Reset()
+	err = WriteConfig(&buf, events, FormatJSON)
+	assert.Nil(t, err, "should not return error")
+	out, _ = json.MarshalIndent(expected, "", "  ")
+	assert.Equal(t, string(out)+"\n", buf.String())
+
+	// Test case 3: errors
+	err = WriteConfig(&buf, events, "toml")
+	assert.EqualError(t, err, "unsupported format \"toml\"")
+	err = WriteConfig(&buf, []*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: svc}}, FormatYAML)
+	assert.EqualError(t, err, "invalid route event: unexpected value *types.Service")
+}
+
+func TestFromConfiguration(t *testing.T) {
+	conf := &types.Configuration{
+		Services:  []*types.Service{svc},
+		Routes:    []*types.Route{route},
+		Consumers: []*types.Consumer{consumer},
+	}
+	events := FromConfiguration(conf)
+	assert.Equal(t, []*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: route},
+		{ResourceType: ConsumerResourceType, Option: CreateOption, Value: consumer},
+	}, events)
+
+	// Test case 2: the inverse of ToConfiguration
+	back, err := ToConfiguration(events)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, conf, back)
+}
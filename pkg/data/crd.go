@@ -0,0 +1,373 @@
+package data
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// CRDAPIVersion is the API version of the CRDs of apisix-ingress-controller
+// written by WriteCRDs.
+const CRDAPIVersion = "apisix.apache.org/v2"
+
+// DefaultCRDNamespace is the namespace of the CRDs if none is given.
+const DefaultCRDNamespace = "default"
+
+type crdMetadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// crdManifest is a Kubernetes manifest, the Secrets of the ApisixTls have
+// a type and data instead of a spec.
+type crdManifest struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   crdMetadata       `json:"metadata"`
+	Type       string            `json:"type,omitempty"`
+	StringData map[string]string `json:"stringData,omitempty"`
+	Spec       interface{}       `json:"spec,omitempty"`
+}
+
+type apisixRouteSpec struct {
+	HTTP []apisixRouteHTTP `json:"http"`
+}
+
+type apisixRouteHTTP struct {
+	Name      string                 `json:"name"`
+	Priority  int                    `json:"priority,omitempty"`
+	Match     apisixRouteMatch       `json:"match"`
+	Websocket bool                   `json:"websocket,omitempty"`
+	Upstreams []apisixRouteUpstream  `json:"upstreams,omitempty"`
+	Plugins   []apisixRoutePlugin    `json:"plugins,omitempty"`
+	Timeout   *apisixUpstreamTimeout `json:"timeout,omitempty"`
+}
+
+type apisixRouteMatch struct {
+	Hosts       []string `json:"hosts,omitempty"`
+	Paths       []string `json:"paths"`
+	Methods     []string `json:"methods,omitempty"`
+	RemoteAddrs []string `json:"remoteAddrs,omitempty"`
+}
+
+type apisixRouteUpstream struct {
+	Name string `json:"name"`
+}
+
+type apisixRoutePlugin struct {
+	Name   string       `json:"name"`
+	Enable bool         `json:"enable"`
+	Config types.Plugin `json:"config,omitempty"`
+}
+
+type apisixUpstreamSpec struct {
+	ExternalNodes []apisixUpstreamNode     `json:"externalNodes,omitempty"`
+	LoadBalancer  *apisixLoadBalancer      `json:"loadbalancer,omitempty"`
+	Scheme        string                   `json:"scheme,omitempty"`
+	Retries       int                      `json:"retries,omitempty"`
+	Timeout       *apisixUpstreamTimeout   `json:"timeout,omitempty"`
+	PassHost      string                   `json:"passHost,omitempty"`
+	UpstreamHost  string                   `json:"upstreamHost,omitempty"`
+	Discovery     *apisixUpstreamDiscovery `json:"discovery,omitempty"`
+}
+
+type apisixUpstreamNode struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Port   int    `json:"port,omitempty"`
+	Weight int    `json:"weight,omitempty"`
+}
+
+type apisixLoadBalancer struct {
+	Type   string `json:"type"`
+	HashOn string `json:"hashOn,omitempty"`
+	Key    string `json:"key,omitempty"`
+}
+
+type apisixUpstreamTimeout struct {
+	Connect string `json:"connect,omitempty"`
+	Send    string `json:"send,omitempty"`
+	Read    string `json:"read,omitempty"`
+}
+
+type apisixUpstreamDiscovery struct {
+	Type        string            `json:"type"`
+	ServiceName string            `json:"serviceName"`
+	Args        map[string]string `json:"args,omitempty"`
+}
+
+type apisixTLSSpec struct {
+	Hosts  []string        `json:"hosts"`
+	Secret apisixTLSSecret `json:"secret"`
+}
+
+type apisixTLSSecret struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// crdConverter converts the resources to the manifests, collecting the
+// warnings about what can't be converted.
+type crdConverter struct {
+	namespace string
+	services  map[string]*types.Service
+	manifests []*crdManifest
+	warnings  []string
+}
+
+func (c *crdConverter) warn(typ ResourceType, key, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf("%s \"%s\": %s", typ, key, fmt.Sprintf(format, args...)))
+}
+
+func (c *crdConverter) add(kind, name string, spec interface{}) {
+	c.manifests = append(c.manifests, &crdManifest{
+		APIVersion: CRDAPIVersion,
+		Kind:       kind,
+		Metadata:   crdMetadata{Name: name, Namespace: c.namespace},
+		Spec:       spec,
+	})
+}
+
+var invalidCRDNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// crdName returns the Kubernetes name of the resource, a lowercase RFC 1123
+// subdomain.
+func crdName(key string) string {
+	name := invalidCRDNameChars.ReplaceAllString(strings.ToLower(key), "-")
+	name = strings.Trim(name, "-.")
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-.")
+	}
+	return name
+}
+
+func crdTimeout(timeout *types.UpstreamTimeout) *apisixUpstreamTimeout {
+	if timeout == nil {
+		return nil
+	}
+	seconds := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%ds", n)
+	}
+	return &apisixUpstreamTimeout{Connect: seconds(timeout.Connect), Send: seconds(timeout.Send), Read: seconds(timeout.Read)}
+}
+
+// crdPlugins returns the plugins sorted by name, so that the manifests
+// are stable.
+func crdPlugins(plugins types.Plugins) []apisixRoutePlugin {
+	var list []apisixRoutePlugin
+	for name, config := range plugins {
+		list = append(list, apisixRoutePlugin{Name: name, Enable: true, Config: config})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func (c *crdConverter) upstream(typ ResourceType, key, name string, ups *types.Upstream) {
+	spec := &apisixUpstreamSpec{
+		Scheme:       ups.Scheme,
+		Retries:      ups.Retries,
+		Timeout:      crdTimeout(ups.Timeout),
+		PassHost:     ups.PassHost,
+		UpstreamHost: ups.UpstreamHost,
+	}
+	if ups.Type != "" {
+		spec.LoadBalancer = &apisixLoadBalancer{Type: ups.Type, HashOn: ups.HashOn, Key: ups.Key}
+	}
+	for _, node := range ups.Nodes {
+		spec.ExternalNodes = append(spec.ExternalNodes, apisixUpstreamNode{Type: "Domain", Name: node.Host, Port: node.Port, Weight: node.Weight})
+	}
+	if ups.ServiceName != "" {
+		spec.Discovery = &apisixUpstreamDiscovery{Type: ups.DiscoveryType, ServiceName: ups.ServiceName, Args: ups.DiscoveryArgs}
+	}
+	if ups.Checks != nil {
+		c.warn(typ, key, "health checks are not converted")
+	}
+	if ups.TLS != nil {
+		c.warn(typ, key, "the client certificate of the upstream is not converted")
+	}
+	if ups.KeepalivePool != nil {
+		c.warn(typ, key, "the keepalive pool is not converted")
+	}
+	c.add("ApisixUpstream", name, spec)
+}
+
+func (c *crdConverter) route(route *types.Route) {
+	var (
+		upstream   = route.Upstream
+		upstreamID = route.UpstreamID
+		plugins    = types.Plugins{}
+		hosts      = route.Hosts
+	)
+	if route.Host != "" {
+		hosts = append([]string{route.Host}, hosts...)
+	}
+	if route.ServiceID != "" {
+		svc, ok := c.services[route.ServiceID]
+		if !ok {
+			c.warn(RouteResourceType, route.ID, "service \"%s\" is not in the events, its upstream and plugins are not converted", route.ServiceID)
+		} else {
+			// the service is merged into the route, which has precedence
+			if upstream == nil && upstreamID == "" {
+				upstream, upstreamID = svc.Upstream, svc.UpstreamID
+			}
+			for name, config := range svc.Plugins {
+				plugins[name] = config
+			}
+			if len(hosts) == 0 {
+				hosts = svc.Hosts
+			}
+		}
+	}
+	for name, config := range route.Plugins {
+		plugins[name] = config
+	}
+
+	name := crdName(route.ID)
+	paths := route.Uris
+	if route.Uri != "" {
+		paths = append([]string{route.Uri}, paths...)
+	}
+	remoteAddrs := route.RemoteAddrs
+	if route.RemoteAddr != "" {
+		remoteAddrs = append([]string{route.RemoteAddr}, remoteAddrs...)
+	}
+	http := apisixRouteHTTP{
+		Name: crdName(route.Name),
+		Match: apisixRouteMatch{
+			Hosts:       hosts,
+			Paths:       paths,
+			Methods:     route.Methods,
+			RemoteAddrs: remoteAddrs,
+		},
+		Websocket: route.EnableWebsocket,
+		Plugins:   crdPlugins(plugins),
+		Timeout:   crdTimeout(route.Timeout),
+	}
+	if http.Name == "" {
+		http.Name = name
+	}
+	if route.Priority != nil {
+		http.Priority = *route.Priority
+	}
+	switch {
+	case upstream != nil:
+		// suffixed, not to collide with an upstream with the ID of the route
+		upstreamName := crdName(route.ID + "-upstream")
+		c.upstream(RouteResourceType, route.ID, upstreamName, upstream)
+		http.Upstreams = []apisixRouteUpstream{{Name: upstreamName}}
+	case upstreamID != "":
+		http.Upstreams = []apisixRouteUpstream{{Name: crdName(upstreamID)}}
+	default:
+		c.warn(RouteResourceType, route.ID, "the route has no upstream")
+	}
+
+	if len(route.Vars) > 0 {
+		c.warn(RouteResourceType, route.ID, "vars are not converted, use the exprs of the match")
+	}
+	for _, field := range []struct{ name, value string }{
+		{"filter_func", route.FilterFunc},
+		{"script", route.Script},
+		{"plugin_config_id", route.PluginConfigID},
+	} {
+		if field.value != "" {
+			c.warn(RouteResourceType, route.ID, "%s is not converted", field.name)
+		}
+	}
+	c.add("ApisixRoute", name, &apisixRouteSpec{HTTP: []apisixRouteHTTP{http}})
+}
+
+func (c *crdConverter) ssl(ssl *types.SSL) {
+	name := crdName(ssl.ID)
+	hosts := ssl.SNIs
+	if ssl.SNI != "" {
+		hosts = append([]string{ssl.SNI}, hosts...)
+	}
+	c.manifests = append(c.manifests, &crdManifest{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   crdMetadata{Name: name, Namespace: c.namespace},
+		Type:       "kubernetes.io/tls",
+		StringData: map[string]string{"tls.crt": ssl.Cert, "tls.key": ssl.Key},
+	})
+	c.add("ApisixTls", name, &apisixTLSSpec{
+		Hosts:  hosts,
+		Secret: apisixTLSSecret{Name: name, Namespace: c.namespace},
+	})
+	if len(ssl.Certs) > 0 {
+		c.warn(SSLResourceType, ssl.ID, "only the first certificate is converted")
+	}
+	if ssl.Client != nil {
+		c.warn(SSLResourceType, ssl.ID, "the client verification of mTLS is not converted")
+	}
+}
+
+// WriteCRDs writes the resources of the events as the YAML manifests of
+// the CRDs of apisix-ingress-controller in the namespace, separated by
+// "---", so that they can be applied with kubectl. Routes are converted to
+// ApisixRoutes, their services are merged into them, upstreams to
+// ApisixUpstreams and SSLs to ApisixTls with the Secret of the certificate.
+// The inline upstream of a route is named after it with an "-upstream"
+// suffix.
+// Like ToConfiguration, the values of the events are written and deletes
+// are omitted.
+//
+// The warnings are about the resources, or some of their fields, which
+// can't be converted, they are not errors but the manifests lack them.
+func WriteCRDs(w io.Writer, events []*Event, namespace string) ([]string, error) {
+	if namespace == "" {
+		namespace = DefaultCRDNamespace
+	}
+	conf, err := ToConfiguration(events)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &crdConverter{namespace: namespace, services: make(map[string]*types.Service)}
+	for _, svc := range conf.Services {
+		c.services[svc.ID] = svc
+	}
+	for _, ups := range conf.Upstreams {
+		c.upstream(UpstreamResourceType, ups.ID, crdName(ups.ID), ups)
+	}
+	for _, route := range conf.Routes {
+		c.route(route)
+	}
+	for _, ssl := range conf.SSLs {
+		c.ssl(ssl)
+	}
+	for _, event := range events {
+		switch event.ResourceType {
+		case RouteResourceType, ServiceResourceType, UpstreamResourceType, SSLResourceType:
+			continue
+		}
+		if event.Option != DeleteOption {
+			c.warn(event.ResourceType, event.key(), "%s are not converted to CRDs", pluralName(event.ResourceType))
+		}
+	}
+
+	for i, manifest := range c.manifests {
+		out, err := yaml.Marshal(manifest)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal the CRDs")
+		}
+		if i > 0 {
+			out = append([]byte("---\n"), out...)
+		}
+		if _, err := w.Write(out); err != nil {
+			return nil, err
+		}
+	}
+	return c.warnings, nil
+}
@@ -0,0 +1,131 @@
+package data
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestWriteCRDs(t *testing.T) {
+	events := []*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: route},
+		{ResourceType: ConsumerResourceType, Option: CreateOption, Value: consumer},
+		{ResourceType: UpstreamResourceType, Option: DeleteOption, OldValue: &types.Upstream{ID: "old"}},
+		{ResourceType: SSLResourceType, Option: CreateOption, Value: &types.SSL{ID: "SSL_1", SNIs: []string{"apisix.dev"}, Cert: "cert", Key: "key"}},
+	}
+
+	// Test case 1: the service is merged into the route, deletes are omitted
+	var buf bytes.Buffer
+	warnings, err := WriteCRDs(&buf, events, "")
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, `apiVersion: apisix.apache.org/v2
+kind: ApisixUpstream
+metadata:
+  name: route-upstream
+  namespace: default
+spec:
+  externalNodes:
+  - name: httpbin.org
+    type: Domain
+---
+apiVersion: apisix.apache.org/v2
+kind: ApisixRoute
+metadata:
+  name: route
+  namespace: default
+spec:
+  http:
+  - match:
+      hosts:
+      - svc.example.com
+      methods:
+      - GET
+      paths:
+      - /get
+    name: route
+    upstreams:
+    - name: route-upstream
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: ssl-1
+  namespace: default
+stringData:
+  tls.crt: cert
+  tls.key: key
+type: kubernetes.io/tls
+---
+apiVersion: apisix.apache.org/v2
+kind: ApisixTls
+metadata:
+  name: ssl-1
+  namespace: default
+spec:
+  hosts:
+  - apisix.dev
+  secret:
+    name: ssl-1
+    namespace: default
+`, buf.String())
+	assert.Equal(t, []string{`consumer "jack": consumers are not converted to CRDs`}, warnings)
+
+	// Test case 2: unmappable fields
+	priority := 10
+	buf.Reset()
+	warnings, err = WriteCRDs(&buf, []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{
+			ID:         "vars",
+			Uri:        "/vars",
+			Priority:   &priority,
+			Vars:       types.Vars{{{StrVal: "arg_name"}, {StrVal: "=="}, {StrVal: "json"}}},
+			UpstreamID: "httpbin",
+			ServiceID:  "missing",
+		}},
+		{ResourceType: UpstreamResourceType, Option: UpdateOption, OldValue: &types.Upstream{ID: "httpbin"}, Value: &types.Upstream{
+			ID:     "httpbin",
+			Type:   "chash",
+			HashOn: "header",
+			Key:    "user",
+			Checks: &types.UpstreamHealthCheck{},
+		}},
+	}, "apisix")
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, buf.String(), "namespace: apisix")
+	assert.Contains(t, buf.String(), "loadbalancer:\n    hashOn: header\n    key: user\n    type: chash")
+	assert.Contains(t, buf.String(), "priority: 10")
+	assert.Contains(t, buf.String(), "upstreams:\n    - name: httpbin")
+	assert.Equal(t, []string{
+		`upstream "httpbin": health checks are not converted`,
+		`route "vars": service "missing" is not in the events, its upstream and plugins are not converted`,
+		`route "vars": vars are not converted, use the exprs of the match`,
+	}, warnings)
+
+	// Test case 3: the inline upstream of a route doesn't collide with an
+	// upstream with the same ID
+	buf.Reset()
+	warnings, err = WriteCRDs(&buf, []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{
+			ID:       "httpbin",
+			Uri:      "/get",
+			Upstream: &types.Upstream{Nodes: []types.UpstreamNode{{Host: "httpbin.org", Port: 80}}},
+		}},
+		{ResourceType: UpstreamResourceType, Option: CreateOption, Value: &types.Upstream{
+			ID:    "httpbin",
+			Nodes: []types.UpstreamNode{{Host: "mockbin.org", Port: 80}},
+		}},
+	}, "")
+	assert.Nil(t, err, "should not return error")
+	assert.Empty(t, warnings)
+	assert.Contains(t, buf.String(), "kind: ApisixUpstream\nmetadata:\n  name: httpbin\n")
+	assert.Contains(t, buf.String(), "kind: ApisixUpstream\nmetadata:\n  name: httpbin-upstream\n")
+	assert.Contains(t, buf.String(), "upstreams:\n    - name: httpbin-upstream")
+
+	// Test case 4: invalid events
+	_, err = WriteCRDs(&buf, []*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: svc}}, "")
+	assert.EqualError(t, err, "invalid route event: unexpected value *types.Service")
+}
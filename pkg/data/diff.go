@@ -0,0 +1,110 @@
+package data
+
+import (
+	"go.uber.org/multierr"
+)
+
+// resourceID identifies a resource across resource sets.
+type resourceID struct {
+	typ ResourceType
+	key string
+}
+
+// DiffOptions are the options of DiffWithOptions.
+type DiffOptions struct {
+	// Prune deletes the current resources missing in the desired ones. They
+	// are kept by default, so that the resources not managed by the caller
+	// are never deleted by accident.
+	Prune bool
+	// PruneFilter selects the current resources deleted by Prune if not
+	// nil, it is called with their delete events, e.g. ByLabels to only
+	// delete the resources labeled as managed by adc. The others are kept.
+	PruneFilter Filter
+}
+
+// Diff is DiffWithOptions without options, so the current resources missing
+// in the desired ones are kept.
+func Diff(desired, current []*Event) ([]*Event, error) {
+	return DiffWithOptions(desired, current, DiffOptions{})
+}
+
+// DiffWithOptions returns the events reconciling the current resources with
+// the desired ones, both given as create events like the ones of
+// LoadConfiguration and DumpCluster. The resources are matched by resource
+// type and identifier, see identifiers: the desired resources missing in
+// the current ones are created, the ones that differ are updated, and with
+// DiffOptions.Prune the current resources missing in the desired ones are
+// deleted. The resources that only differ in their canonical form are
+// unchanged, see IsNoOp. The events are sorted with SortEventsByKey.
+// An error is returned for the resources without identifier and for the
+// resources defined more than once in a set, see ValidateUnique.
+func DiffWithOptions(desired, current []*Event, opts DiffOptions) ([]*Event, error) {
+	desiredByID, err := indexResources(desired)
+	if err != nil {
+		return nil, err
+	}
+	currentByID, err := indexResources(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*Event
+	for _, event := range desired {
+		id := resourceID{typ: event.ResourceType, key: event.key()}
+		old, ok := currentByID[id]
+		if !ok {
+			events = append(events, &Event{
+				ResourceType: event.ResourceType,
+				Option:       CreateOption,
+				Value:        event.Value,
+			})
+			continue
+		}
+
+		update := &Event{
+			ResourceType: event.ResourceType,
+			Option:       UpdateOption,
+			OldValue:     old.Value,
+			Value:        event.Value,
+		}
+		noop, err := update.IsNoOp()
+		if err != nil {
+			return nil, err
+		}
+		if !noop {
+			events = append(events, update)
+		}
+	}
+	for _, event := range current {
+		if _, ok := desiredByID[resourceID{typ: event.ResourceType, key: event.key()}]; ok || !opts.Prune {
+			continue
+		}
+		deletion := &Event{
+			ResourceType: event.ResourceType,
+			Option:       DeleteOption,
+			OldValue:     event.Value,
+		}
+		if opts.PruneFilter == nil || opts.PruneFilter(deletion) {
+			events = append(events, deletion)
+		}
+	}
+	return SortEventsByKey(events), nil
+}
+
+// indexResources returns the create events by the resources they create.
+func indexResources(events []*Event) (map[resourceID]*Event, error) {
+	var errs error
+	index := make(map[resourceID]*Event, len(events))
+	for _, event := range events {
+		key, err := event.resourceKey()
+		if err == nil && key == "" {
+			err = &EventError{Event: event, Err: missingKeyError(event.ResourceType)}
+		}
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		index[resourceID{typ: event.ResourceType, key: key}] = event
+	}
+	return index, multierr.Append(errs, ValidateUnique(events))
+}
@@ -0,0 +1,87 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestDiff(t *testing.T) {
+	route1 := *route
+	route1.Description = "route1"
+	svc1 := *svc
+	svc1.Hosts = []string{"svc1.example.com"}
+	current := []*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: route},
+		{ResourceType: ConsumerResourceType, Option: CreateOption, Value: consumer},
+	}
+
+	// Test case 1: no change
+	events, err := Diff(current, current)
+	assert.Nil(t, err, "should not return error")
+	assert.Empty(t, events)
+
+	// Test case 2: add, change and remove
+	desired := []*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: &svc1},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: route},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "route2", Uris: []string{"/post"}}},
+	}
+	prune := DiffOptions{Prune: true}
+	events, err = DiffWithOptions(desired, current, prune)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: desired[2].Value},
+		{ResourceType: ServiceResourceType, Option: UpdateOption, OldValue: svc, Value: &svc1},
+		{ResourceType: ConsumerResourceType, Option: DeleteOption, OldValue: consumer},
+	}, events)
+
+	// Test case 3: the resources are matched by type and identifier
+	desired = []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &route1},
+		{ResourceType: UpstreamResourceType, Option: CreateOption, Value: &types.Upstream{ID: "svc", Type: "roundrobin"}},
+	}
+	events, err = DiffWithOptions(desired, current[:2], prune)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []*Event{
+		{ResourceType: UpstreamResourceType, Option: CreateOption, Value: desired[1].Value},
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: &route1},
+		{ResourceType: ServiceResourceType, Option: DeleteOption, OldValue: svc},
+	}, events)
+
+	// Test case 4: the events reconcile the cluster
+	cluster := newFakeCluster()
+	cluster.service.items["svc"] = svc
+	cluster.route.items["route"] = route
+	assert.Nil(t, ApplyAll(context.Background(), cluster, events, 1, false), "should not return error")
+	assert.Equal(t, map[string]*types.Route{"route": &route1}, cluster.route.items)
+	assert.Empty(t, cluster.service.items, "should delete the service")
+
+	// Test case 5: the current resources are only deleted with prune, and
+	// the ones selected by the prune filter
+	events, err = Diff(desired, current[:2])
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []*Event{
+		{ResourceType: UpstreamResourceType, Option: CreateOption, Value: desired[1].Value},
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: &route1},
+	}, events, "should not delete without prune")
+
+	events, err = DiffWithOptions(nil, current, DiffOptions{Prune: true, PruneFilter: ByResourceType(ConsumerResourceType)})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []*Event{
+		{ResourceType: ConsumerResourceType, Option: DeleteOption, OldValue: consumer},
+	}, events, "should only delete the consumer")
+
+	// Test case 6: invalid resource sets
+	_, err = Diff([]*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: &svc1},
+	}, nil)
+	assert.EqualError(t, err, `service "svc": defined 2 times`)
+	_, err = Diff(nil, []*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{}}})
+	assert.EqualError(t, err, `route "": id is required`)
+}
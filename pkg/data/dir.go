@@ -0,0 +1,113 @@
+package data
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the file listing the paths excluded by LoadDir.
+const IgnoreFileName = ".adcignore"
+
+// _configExtensions are the extensions of the files loaded by LoadDir.
+var _configExtensions = []string{".yaml", ".yml", ".json"}
+
+// LoadDir loads all the YAML and JSON files of the directory and its
+// subdirectories with LoadFiles. The files are sorted by path, so merging
+// them is reproducible.
+//
+// The paths matching a pattern of the IgnoreFileName file of the directory
+// are excluded. Each line is a glob pattern, see path.Match, matched against
+// the slash-separated path relative to the directory, or against the base
+// name if the pattern has no slash. A leading slash anchors a pattern like
+// "/drafts" to the directory, a trailing slash only matches directories,
+// whose files are all excluded. Empty lines and lines starting with "#"
+// are ignored.
+func LoadDir(dir string) ([]*Event, error) {
+	patterns, err := readIgnoreFile(filepath.Join(dir, IgnoreFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if ignored(patterns, filepath.ToSlash(rel), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && contains(_configExtensions, strings.ToLower(filepath.Ext(p))) {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// WalkDir walks in lexical order already
+	return LoadFiles(paths...)
+}
+
+func readIgnoreFile(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := path.Match(strings.Trim(line, "/"), ""); err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern %q", name, line)
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// ignored reports whether the slash-separated relative path matches one of
+// the patterns.
+func ignored(patterns []string, rel string, isDir bool) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/") {
+			if !isDir {
+				continue
+			}
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+		name := rel
+		if strings.HasPrefix(pattern, "/") {
+			pattern = strings.TrimPrefix(pattern, "/")
+		} else if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
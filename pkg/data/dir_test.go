@@ -0,0 +1,50 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	write("services.yaml", "services:\n- name: svc\n  hosts:\n  - svc.example.com\n")
+	write("teams/payments/routes.yml", "routes:\n- name: payments\n  uris:\n  - /pay\n")
+	write("teams/orders/routes.JSON", `{"routes":[{"name":"orders","uris":["/orders"]}]}`)
+	write("teams/orders/README.md", "not a configuration")
+	write("teams/orders/routes.yaml.bak", "routes: {")
+
+	// Test case 1: the configuration files of all the subdirectories
+	events, err := LoadDir(dir)
+	assert.Nil(t, err, "should not return error")
+	var keys []string
+	for _, event := range events {
+		keys = append(keys, event.key())
+	}
+	assert.Equal(t, []string{"svc", "orders", "payments"}, keys)
+
+	// Test case 2: the ignored paths
+	write("drafts/routes.yaml", "routes: {")
+	write("teams/drafts/routes.yaml", "routes:\n- name: drafts\n  uris:\n  - /drafts\n")
+	write("teams/orders/local.yaml", "routes: {")
+	write(IgnoreFileName, "# work in progress\n/drafts/\n\nlocal.yaml\nteams/payments/*.yml\n")
+	events, err = LoadDir(dir)
+	assert.Nil(t, err, "should not return error")
+	keys = nil
+	for _, event := range events {
+		keys = append(keys, event.key())
+	}
+	assert.Equal(t, []string{"svc", "drafts", "orders"}, keys, "should only anchor the patterns with a slash")
+
+	// Test case 3: an invalid pattern
+	write(IgnoreFileName, "[\n")
+	_, err = LoadDir(dir)
+	assert.EqualError(t, err, filepath.Join(dir, IgnoreFileName)+`: invalid pattern "["`)
+}
@@ -0,0 +1,78 @@
+package data
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// DeleteMode is how the deletes are applied, see ApplyOptions.DeleteMode.
+type DeleteMode string
+
+const (
+	// HardDelete deletes the resources, it's the default.
+	HardDelete DeleteMode = "delete"
+	// DisableDelete disables the routes and the SSLs instead of deleting
+	// them, see Event.Disable, so that the removals are staged and can be
+	// rolled back by enabling them again until they are deleted for good.
+	// The deletes of the other resource types are applied as usual, the
+	// ones still referenced by the disabled routes fail with
+	// apisix.ErrStillInUse.
+	DisableDelete DeleteMode = "disable"
+)
+
+// ParseDeleteMode parses the delete mode, an empty mode is HardDelete.
+func ParseDeleteMode(s string) (DeleteMode, error) {
+	switch mode := DeleteMode(s); mode {
+	case "":
+		return HardDelete, nil
+	case HardDelete, DisableDelete:
+		return mode, nil
+	}
+	return "", errors.Errorf("unknown delete mode %q, must be %s or %s", s, HardDelete, DisableDelete)
+}
+
+// DisabledLabel is the label of the resources disabled by Event.Disable.
+const DisabledLabel = "adc-disabled"
+
+// Disable returns the update disabling the resource deleted by the event
+// instead of deleting it, setting its status to 0 and the DisabledLabel
+// label to "true". It returns false for the events that are not deletes
+// and for the resource types without status, only routes and SSLs have one.
+func (e *Event) Disable() (Event, bool) {
+	if e.Option != DeleteOption {
+		return Event{}, false
+	}
+	var value interface{}
+	switch old := e.OldValue.(type) {
+	case *types.Route:
+		if old == nil {
+			return Event{}, false
+		}
+		route := *old
+		route.Status = types.PtrOf(0)
+		route.Labels = disabledLabels(old.Labels)
+		value = &route
+	case *types.SSL:
+		if old == nil {
+			return Event{}, false
+		}
+		ssl := *old
+		ssl.Status = types.PtrOf(0)
+		ssl.Labels = disabledLabels(old.Labels)
+		value = &ssl
+	default:
+		return Event{}, false
+	}
+	return Event{ResourceType: e.ResourceType, Option: UpdateOption, OldValue: e.OldValue, Value: value}, true
+}
+
+// disabledLabels returns a copy of the labels with DisabledLabel.
+func disabledLabels(labels types.Labels) types.Labels {
+	disabled := make(types.Labels, len(labels)+1)
+	for k, v := range labels {
+		disabled[k] = v
+	}
+	disabled[DisabledLabel] = "true"
+	return disabled
+}
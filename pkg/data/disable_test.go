@@ -0,0 +1,65 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestEventDisable(t *testing.T) {
+	labeled := *route
+	labeled.Labels = types.Labels{"team": "payments"}
+
+	// Test case 1: routes and SSLs are disabled
+	disabled, ok := (&Event{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: &labeled}).Disable()
+	assert.True(t, ok, "should disable the route")
+	assert.Equal(t, UpdateOption, disabled.Option)
+	assert.Equal(t, &labeled, disabled.OldValue)
+	value := disabled.Value.(*types.Route)
+	assert.Equal(t, 0, *value.Status)
+	assert.Equal(t, types.Labels{"team": "payments", DisabledLabel: "true"}, value.Labels)
+	assert.Equal(t, types.Labels{"team": "payments"}, labeled.Labels, "should not change the old value")
+
+	ssl := &types.SSL{ID: "ssl", SNIs: []string{"example.com"}}
+	disabled, ok = (&Event{ResourceType: SSLResourceType, Option: DeleteOption, OldValue: ssl}).Disable()
+	assert.True(t, ok, "should disable the ssl")
+	assert.Equal(t, 0, *disabled.Value.(*types.SSL).Status)
+
+	// Test case 2: the other resource types and options
+	_, ok = (&Event{ResourceType: ServiceResourceType, Option: DeleteOption, OldValue: svc}).Disable()
+	assert.False(t, ok, "services have no status")
+	_, ok = (&Event{ResourceType: RouteResourceType, Option: CreateOption, Value: route}).Disable()
+	assert.False(t, ok, "should only disable deletes")
+}
+
+func TestApplyAllDisableDelete(t *testing.T) {
+	cluster := newFakeCluster()
+	cluster.route.items["route"] = route
+	cluster.consumer.items["jack"] = consumer
+	events := []*Event{
+		{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: route},
+		{ResourceType: ConsumerResourceType, Option: DeleteOption, OldValue: consumer},
+	}
+
+	// Test case 1: the route is disabled, the consumer deleted
+	results, err := ApplyAllWithResults(context.Background(), cluster, events, ApplyOptions{DeleteMode: DisableDelete})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []string{"update:route"}, cluster.route.calls)
+	assert.Equal(t, 0, *cluster.route.items["route"].Status)
+	assert.Empty(t, cluster.consumer.items, "should delete the consumer")
+
+	// Test case 2: the rollback enables the route again
+	assert.Nil(t, Rollback(cluster, results), "should roll back successfully")
+	assert.Equal(t, route, cluster.route.items["route"])
+	assert.Equal(t, consumer, cluster.consumer.items["jack"])
+
+	// Test case 3: the delete modes
+	mode, err := ParseDeleteMode("")
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, HardDelete, mode)
+	_, err = ParseDeleteMode("archive")
+	assert.EqualError(t, err, `unknown delete mode "archive", must be delete or disable`)
+}
@@ -0,0 +1,84 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// DryRunResult is the plan computed by DryRun.
+type DryRunResult struct {
+	// Outputs are the outputs of the events, in the order they would be applied.
+	Outputs []string `json:"outputs"`
+	Summary
+}
+
+// DryRun computes what applying the events would change without mutating
+// the cluster. The target of every update event is fetched from the cluster,
+// an error is returned for each one that doesn't exist, for each duplicated
+// resource and for each route referencing a missing service, see
+// ValidateUnique and ValidateReferences.
+func DryRun(ctx context.Context, cluster apisix.Cluster, events []*Event) (*DryRunResult, error) {
+	var errs error
+	result := &DryRunResult{
+		Summary: Summarize(events),
+	}
+	for _, event := range SortEventsByKey(events) {
+		if event.Option == UpdateOption {
+			errs = multierr.Append(errs, event.checkTarget(ctx, cluster))
+		}
+
+		output, err := event.Output(true)
+		if err != nil {
+			return nil, err
+		}
+		result.Outputs = append(result.Outputs, output)
+	}
+	errs = multierr.Combine(errs, ValidateUnique(events), ValidateReferences(ctx, cluster, events))
+	return result, errs
+}
+
+func checkTarget[T any](ctx context.Context, client apisix.ResourceClient[T], event *Event) error {
+	key := apisix.GetResourceUniqueKey(event.Value)
+	_, err := client.Get(ctx, key)
+	if errors.Is(err, apisix.ErrNotFound) {
+		return fmt.Errorf("%s \"%s\" to update doesn't exist", event.ResourceType, key)
+	}
+	return err
+}
+
+// checkTarget checks the resource to update exists in the cluster.
+func (e *Event) checkTarget(ctx context.Context, cluster apisix.Cluster) error {
+	switch e.ResourceType {
+	case ServiceResourceType:
+		return checkTarget[types.Service](ctx, cluster.Service(), e)
+	case RouteResourceType:
+		return checkTarget[types.Route](ctx, cluster.Route(), e)
+	case ConsumerResourceType:
+		return checkTarget[types.Consumer](ctx, cluster.Consumer(), e)
+	case SSLResourceType:
+		return checkTarget[types.SSL](ctx, cluster.SSL(), e)
+	case GlobalRuleResourceType:
+		return checkTarget[types.GlobalRule](ctx, cluster.GlobalRule(), e)
+	case PluginConfigResourceType:
+		return checkTarget[types.PluginConfig](ctx, cluster.PluginConfig(), e)
+	case ConsumerGroupResourceType:
+		return checkTarget[types.ConsumerGroup](ctx, cluster.ConsumerGroup(), e)
+	case PluginMetadataResourceType:
+		return checkTarget[types.PluginMetadata](ctx, cluster.PluginMetadata(), e)
+	case StreamRouteResourceType:
+		return checkTarget[types.StreamRoute](ctx, cluster.StreamRoute(), e)
+	case UpstreamResourceType:
+		return checkTarget[types.Upstream](ctx, cluster.Upstream(), e)
+	case SecretResourceType:
+		return checkTarget[types.Secret](ctx, cluster.Secret(), e)
+	case ProtoResourceType:
+		return checkTarget[types.Proto](ctx, cluster.Proto(), e)
+	}
+	return nil
+}
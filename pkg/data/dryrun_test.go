@@ -0,0 +1,73 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestDryRun(t *testing.T) {
+	svc1 := *svc
+	svc1.Hosts = []string{"svc1.example.com"}
+	events := []*Event{
+		{
+			ResourceType: RouteResourceType,
+			Option:       CreateOption,
+			Value:        route,
+		},
+		{
+			ResourceType: ServiceResourceType,
+			Option:       UpdateOption,
+			OldValue:     svc,
+			Value:        &svc1,
+		},
+		{
+			ResourceType: ConsumerResourceType,
+			Option:       DeleteOption,
+			OldValue:     consumer,
+		},
+	}
+
+	// Test case 1: the service to update exists
+	cluster := newFakeCluster()
+	cluster.service.items["svc"] = svc
+	result, err := DryRun(context.Background(), cluster, events)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Deleted)
+	assert.Len(t, result.Outputs, 3)
+	assert.Equal(t, "+++ route: \"route\"", result.Outputs[0])
+	assert.Contains(t, result.Outputs[1], "update service: \"svc\"")
+	assert.Equal(t, "--- consumer: \"jack\"", result.Outputs[2])
+	assert.Empty(t, cluster.route.calls, "should not create the route")
+	assert.Empty(t, cluster.service.calls, "should not update the service")
+	assert.Empty(t, cluster.consumer.calls, "should not delete the consumer")
+	assert.Equal(t, svc, cluster.service.items["svc"])
+
+	out, err := json.Marshal(result)
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, string(out), `"created":1,"updated":1,"deleted":1`)
+
+	// Test case 2: the service to update doesn't exist
+	cluster = newFakeCluster()
+	result, err = DryRun(context.Background(), cluster, events)
+	assert.Equal(t, "service \"svc\" to update doesn't exist", err.Error())
+	assert.Len(t, result.Outputs, 3, "should still return the plan")
+
+	// Test case 3: plugin metadata are keyed by the plugin name
+	cluster = newFakeCluster()
+	_, err = DryRun(context.Background(), cluster, []*Event{
+		{
+			ResourceType: PluginMetadataResourceType,
+			Option:       UpdateOption,
+			OldValue:     &types.PluginMetadata{ID: "http-logger"},
+			Value:        &types.PluginMetadata{ID: "http-logger", Config: map[string]interface{}{"k": "v"}},
+		},
+	})
+	assert.Equal(t, "plugin_metadata \"http-logger\" to update doesn't exist", err.Error())
+}
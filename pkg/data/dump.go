@@ -0,0 +1,22 @@
+package data
+
+import (
+	"context"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/common"
+)
+
+// DumpCluster lists the resources of every supported type from the cluster
+// and returns them as create events, which represent the current state of
+// the cluster. The types are listed concurrently, see
+// common.GetContentFromRemoteWithContext, and the returned error combines
+// the errors of all the failed lists. The events are sorted with
+// SortEventsByKey.
+func DumpCluster(ctx context.Context, cluster apisix.Cluster) ([]*Event, error) {
+	conf, err := common.GetContentFromRemoteWithContext(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return SortEventsByKey(FromConfiguration(conf)), nil
+}
@@ -0,0 +1,71 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestDumpCluster(t *testing.T) {
+	cluster := newFakeCluster()
+	cluster.service.items["svc"] = svc
+	cluster.route.items["route"] = route
+	cluster.consumer.items["jack"] = consumer
+	proto := &types.Proto{ID: "proto", Content: "syntax = \"proto3\";"}
+	cluster.proto.items["proto"] = proto
+
+	// Test case 1: every resource is a create event
+	events, err := DumpCluster(context.Background(), cluster)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []*Event{
+		{ResourceType: ConsumerResourceType, Option: CreateOption, Value: consumer},
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: ProtoResourceType, Option: CreateOption, Value: proto},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: route},
+	}, events)
+
+	// Test case 2: the secret API is missing in APISIX 2.x
+	cluster.secret.listErr = apisix.ErrNotFound
+	events, err = DumpCluster(context.Background(), cluster)
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, events, 4)
+
+	// Test case 3: errors are wrapped
+	cluster.route.listErr = errors.New("connection refused")
+	_, err = DumpCluster(context.Background(), cluster)
+	assert.EqualError(t, err, "failed to list route: connection refused")
+
+	// Test case 4: the errors of all the types are combined
+	cluster.consumer.listErr = errors.New("connection reset")
+	_, err = DumpCluster(context.Background(), cluster)
+	assert.EqualError(t, err, "failed to list route: connection refused; failed to list consumer: connection reset")
+}
+
+func TestDumpClusterConcurrently(t *testing.T) {
+	cluster := newFakeCluster()
+	delay := 50 * time.Millisecond
+	cluster.service.delay = delay
+	cluster.route.delay = delay
+	cluster.consumer.delay = delay
+	cluster.ssl.delay = delay
+	cluster.globalRule.delay = delay
+	cluster.pluginConfig.delay = delay
+	cluster.consumerGroup.delay = delay
+	cluster.pluginMetadata.delay = delay
+	cluster.streamRoute.delay = delay
+	cluster.upstream.delay = delay
+	cluster.secret.delay = delay
+	cluster.proto.delay = delay
+
+	start := time.Now()
+	_, err := DumpCluster(context.Background(), cluster)
+	assert.Nil(t, err, "should not return error")
+	// 12 types take 600ms serially and 150ms with 4 concurrent requests
+	assert.Less(t, time.Since(start), 400*time.Millisecond, "should list the types concurrently")
+}
@@ -0,0 +1,46 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// _envRegex matches "$$", "${VAR}" and "${VAR:-default}".
+var _envRegex = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandEnv replaces the "${VAR}" and "${VAR:-default}" placeholders of the
+// content with the environment variables. Like in the shell, the default
+// is used when the variable is unset or empty, and "$$" is a literal "$",
+// e.g. "$${VAR}" is kept as "${VAR}". An error naming the variables is
+// returned when some without default are unset.
+func ExpandEnv(content []byte) ([]byte, error) {
+	return expandEnv(content, os.LookupEnv)
+}
+
+func expandEnv(content []byte, lookup func(string) (string, bool)) ([]byte, error) {
+	var missing []string
+	expanded := _envRegex.ReplaceAllFunc(content, func(match []byte) []byte {
+		if string(match) == "$$" {
+			return []byte("$")
+		}
+		groups := _envRegex.FindSubmatch(match)
+		name, hasDefault := string(groups[1]), len(groups[2]) > 0
+		value, ok := lookup(name)
+		if hasDefault && value == "" {
+			return groups[3]
+		}
+		if !ok {
+			if !contains(missing, name) {
+				missing = append(missing, name)
+			}
+			return match
+		}
+		return []byte(value)
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("environment variables are not set: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
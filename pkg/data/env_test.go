@@ -0,0 +1,59 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{
+		"UPSTREAM_HOST": "httpbin.org",
+		"EMPTY":         "",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"host: ${UPSTREAM_HOST}", "host: httpbin.org"},
+		{"host: ${UPSTREAM_HOST:-localhost}", "host: httpbin.org"},
+		{"host: ${UPSTREAM_PORT:-80}", "host: 80"},
+		{"host: ${EMPTY:-localhost}", "host: localhost"},
+		{"host: ${EMPTY}", "host: "},
+		{"host: ${UPSTREAM_PORT:-}", "host: "},
+		{"uri: $uri $1 {a}", "uri: $uri $1 {a}"},
+		{"uri: $${UPSTREAM_HOST} $$$$", "uri: ${UPSTREAM_HOST} $$"},
+		{"${UPSTREAM_HOST}:${UPSTREAM_PORT:-80}", "httpbin.org:80"},
+	}
+	for _, tc := range tests {
+		expanded, err := expandEnv([]byte(tc.content), lookup)
+		assert.Nil(t, err, "should not return error")
+		assert.Equal(t, tc.want, string(expanded), tc.content)
+	}
+
+	// Test case 2: the unset variables are named
+	_, err := expandEnv([]byte("${A} ${UPSTREAM_HOST} ${B} ${A}"), lookup)
+	assert.EqualError(t, err, "environment variables are not set: A, B")
+}
+
+func TestLoadConfigurationEnv(t *testing.T) {
+	t.Setenv("ADC_TEST_HOST", "svc.example.com")
+
+	events, err := LoadConfiguration([]byte(`services:
+- name: svc
+  hosts:
+  - ${ADC_TEST_HOST}
+  - ${ADC_TEST_HOST_2:-svc2.example.com}
+`))
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []string{"svc.example.com", "svc2.example.com"}, events[0].Value.(*types.Service).Hosts)
+
+	_, err = LoadConfiguration([]byte("services:\n- name: ${ADC_TEST_NAME}\n"))
+	assert.EqualError(t, err, "environment variables are not set: ADC_TEST_NAME")
+}
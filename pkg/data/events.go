@@ -1,17 +1,22 @@
 package data
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
 
-	"github.com/hexops/gotextdiff"
 	"github.com/hexops/gotextdiff/myers"
 	"github.com/hexops/gotextdiff/span"
 	"github.com/pkg/errors"
 
 	"github.com/api7/adc/pkg/api/apisix"
 	"github.com/api7/adc/pkg/api/apisix/types"
+	"github.com/api7/adc/pkg/tracing"
 )
 
 // ResourceType is the type of resource
@@ -38,146 +43,598 @@ var (
 	StreamRouteResourceType ResourceType = "stream_route"
 	// UpstreamResourceType is the resource type of upstream
 	UpstreamResourceType ResourceType = "upstream"
+	// SecretResourceType is the resource type of secret
+	SecretResourceType ResourceType = "secret"
+	// ProtoResourceType is the resource type of proto
+	ProtoResourceType ResourceType = "proto"
 )
 
+// Option is the change of an event
+type Option int
+
 const (
 	// CreateOption is the option of create
-	CreateOption = iota
+	CreateOption Option = iota
 	// DeleteOption is the option of delete
 	DeleteOption
 	// UpdateOption is the option of update
 	UpdateOption
+	// UpsertOption is the option of create or update, the resource is
+	// updated if it exists and created otherwise. The old value is the
+	// remote resource if known, it is optional.
+	UpsertOption
+	// NoOpOption is the option of an unchanged resource, it is reported
+	// but not applied. The old value is the remote resource if known.
+	NoOpOption
 )
 
+// String returns "create", "delete", "update", "upsert" or "noop", and
+// "unknown" for the other options.
+func (o Option) String() string {
+	switch o {
+	case CreateOption:
+		return "create"
+	case DeleteOption:
+		return "delete"
+	case UpdateOption:
+		return "update"
+	case UpsertOption:
+		return "upsert"
+	case NoOpOption:
+		return "noop"
+	}
+	return "unknown"
+}
+
+// MarshalJSON encodes the option as its name, e.g. "create". The unknown
+// options are encoded as numbers.
+func (o Option) MarshalJSON() ([]byte, error) {
+	if o.String() == "unknown" {
+		return json.Marshal(int(o))
+	}
+	return json.Marshal(o.String())
+}
+
+// UnmarshalJSON decodes the option from its name or its number.
+func (o *Option) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		var number int
+		if err := json.Unmarshal(data, &number); err != nil {
+			return errors.Errorf("invalid option %s", data)
+		}
+		*o = Option(number)
+		return nil
+	}
+	for _, option := range []Option{CreateOption, DeleteOption, UpdateOption, UpsertOption, NoOpOption} {
+		if option.String() == name {
+			*o = option
+			return nil
+		}
+	}
+	return errors.Errorf("unknown option %q", name)
+}
+
 // Event is the event of adc
 type Event struct {
 	ResourceType ResourceType `json:"resource_type"`
-	Option       int          `json:"option"`
+	Option       Option       `json:"option"`
 	OldValue     interface{}  `json:"old_value"`
 	Value        interface{}  `json:"value"`
 }
 
+// NewCreate returns the event creating the value, an error is returned if
+// the event is invalid, see Event.Validate.
+func NewCreate(typ ResourceType, value interface{}) (*Event, error) {
+	return newEvent(&Event{ResourceType: typ, Option: CreateOption, Value: value})
+}
+
+// NewUpdate returns the event updating the old value to the value, an error
+// is returned if the event is invalid, see Event.Validate. Unlike Validate,
+// the old value is required.
+func NewUpdate(typ ResourceType, oldValue, value interface{}) (*Event, error) {
+	return newEvent(&Event{ResourceType: typ, Option: UpdateOption, OldValue: oldValue, Value: value})
+}
+
+// NewDelete returns the event deleting the old value, an error is returned
+// if the event is invalid, see Event.Validate.
+func NewDelete(typ ResourceType, oldValue interface{}) (*Event, error) {
+	return newEvent(&Event{ResourceType: typ, Option: DeleteOption, OldValue: oldValue})
+}
+
+func newEvent(e *Event) (*Event, error) {
+	if err := e.checkValues(); err != nil {
+		return nil, err
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// newValues return a new value of the Go type of each resource type.
+var newValues = map[ResourceType]func() interface{}{
+	ServiceResourceType:        func() interface{} { return new(types.Service) },
+	RouteResourceType:          func() interface{} { return new(types.Route) },
+	ConsumerResourceType:       func() interface{} { return new(types.Consumer) },
+	SSLResourceType:            func() interface{} { return new(types.SSL) },
+	GlobalRuleResourceType:     func() interface{} { return new(types.GlobalRule) },
+	PluginConfigResourceType:   func() interface{} { return new(types.PluginConfig) },
+	ConsumerGroupResourceType:  func() interface{} { return new(types.ConsumerGroup) },
+	PluginMetadataResourceType: func() interface{} { return new(types.PluginMetadata) },
+	StreamRouteResourceType:    func() interface{} { return new(types.StreamRoute) },
+	UpstreamResourceType:       func() interface{} { return new(types.Upstream) },
+	SecretResourceType:         func() interface{} { return new(types.Secret) },
+	ProtoResourceType:          func() interface{} { return new(types.Proto) },
+}
+
+// UnmarshalJSON decodes the values into the Go type of the resource type,
+// e.g. *types.Route for routes, so that a decoded event can be applied.
+// Missing and null values are nil.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ResourceType ResourceType    `json:"resource_type"`
+		Option       Option          `json:"option"`
+		OldValue     json.RawMessage `json:"old_value"`
+		Value        json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	newValue, ok := newValues[raw.ResourceType]
+	if !ok {
+		return errors.Errorf("unsupported resource type %q", raw.ResourceType)
+	}
+
+	event := Event{ResourceType: raw.ResourceType, Option: raw.Option}
+	var err error
+	if event.OldValue, err = decodeValue(raw.OldValue, newValue); err != nil {
+		return errors.Wrapf(err, "invalid %s event: old value", raw.ResourceType)
+	}
+	if event.Value, err = decodeValue(raw.Value, newValue); err != nil {
+		return errors.Wrapf(err, "invalid %s event: value", raw.ResourceType)
+	}
+	*e = event
+	return nil
+}
+
+func decodeValue(raw json.RawMessage, newValue func() interface{}) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	value := newValue()
+	if err := json.Unmarshal(raw, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
 // Output returns the output of event,
 // if the event is create, it will return the message of creating resource.
 // if the event is update, it will return the diff of old value and new value.
 // if the event is delete, it will return the message of deleting resource.
+// if the event is upsert, it will be like an update if the old value is
+// set, and like a create otherwise.
+// if the event is noop, it will return the message of unchanged resource.
+// It's built with WriteOutput.
 func (e *Event) Output(diffOnly bool) (string, error) {
-	var output string
-	switch e.Option {
+	return e.OutputWithOptions(&OutputOptions{DiffOnly: diffOnly})
+}
+
+// OutputWithOptions is Output with options, nil options is the same as
+// Output(false).
+func (e *Event) OutputWithOptions(opts *OutputOptions) (string, error) {
+	var out strings.Builder
+	if err := e.WriteOutputWithOptions(&out, opts); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// WriteOutput writes the output of the event to w like Output(false),
+// without building it in memory first, e.g. for the diffs of large
+// resources.
+func (e *Event) WriteOutput(w io.Writer) error {
+	return e.WriteOutputWithOptions(w, nil)
+}
+
+// WriteOutputWithOptions is WriteOutput with options, nil options is the
+// same as WriteOutput. Nothing is written if an error is returned before
+// the diff is computed.
+func (e *Event) WriteOutputWithOptions(w io.Writer, opts *OutputOptions) error {
+	if opts == nil {
+		opts = &OutputOptions{}
+	}
+	diffOnly := opts.DiffOnly
+
+	name, err := e.resourceKey()
+	if err != nil {
+		return err
+	}
+
+	var (
+		title string
+		// body writes the lines below the title
+		body func(w io.Writer) error
+	)
+	switch e.resolvedOption() {
 	case CreateOption:
 		if diffOnly {
-			output = fmt.Sprintf("+++ %s: \"%s\"", e.ResourceType, apisix.GetResourceUniqueKey(e.Value))
+			title = fmt.Sprintf("+++ %s: \"%s\"", e.ResourceType, name)
 		} else {
-			output = fmt.Sprintf("creating %s: \"%s\"", e.ResourceType, apisix.GetResourceUniqueKey(e.Value))
+			title = fmt.Sprintf("creating %s: \"%s\"", e.ResourceType, name)
+		}
+		if opts.ShowBody {
+			local, err := marshal(e.ResourceType, e.Value, opts.redactedFields(), nil)
+			if err != nil {
+				return err
+			}
+			local = append(local, '\n')
+			body = func(w io.Writer) error {
+				return writeUnifiedDiff(w, nil, local, opts)
+			}
 		}
 	case DeleteOption:
 		if diffOnly {
-			output = fmt.Sprintf("--- %s: \"%s\"", e.ResourceType, apisix.GetResourceUniqueKey(e.OldValue))
+			title = fmt.Sprintf("--- %s: \"%s\"", e.ResourceType, name)
 		} else {
-			output = fmt.Sprintf("deleting %s: \"%s\"", e.ResourceType, apisix.GetResourceUniqueKey(e.OldValue))
+			title = fmt.Sprintf("deleting %s: \"%s\"", e.ResourceType, name)
 		}
 	case UpdateOption:
-		remote, err := json.MarshalIndent(e.OldValue, "", "\t")
+		ignored, err := e.ignoredFields(opts)
 		if err != nil {
-			return "", err
+			return err
 		}
-		remote = append(remote, '\n')
 
-		local, err := json.MarshalIndent(e.Value, "", "\t")
+		if opts.SplitPlugins {
+			body, err = splitPluginsDiff(e.ResourceType, e.OldValue, e.Value, opts, ignored)
+		} else {
+			var remote, local []byte
+			remote, local, err = marshalUpdate(e.ResourceType, e.OldValue, e.Value, opts.redactedFields(), ignored)
+			body = func(w io.Writer) error {
+				return writeDiff(w, remote, local, opts)
+			}
+		}
 		if err != nil {
-			return "", err
+			return err
 		}
-		local = append(local, '\n')
-
-		edits := myers.ComputeEdits(span.URIFromPath("remote"), string(remote), string(local))
-		diff := fmt.Sprint(gotextdiff.ToUnified("remote", "local", string(remote), edits))
 		if diffOnly {
-			output = fmt.Sprintf("update %s: \"%s\"\n%s", e.ResourceType, apisix.GetResourceUniqueKey(e.Value), diff)
+			title = fmt.Sprintf("update %s: \"%s\"", e.ResourceType, name)
 		} else {
-			output = fmt.Sprintf("updating %s: \"%s\"\n%s", e.ResourceType, apisix.GetResourceUniqueKey(e.Value), diff)
+			title = fmt.Sprintf("updating %s: \"%s\"", e.ResourceType, name)
 		}
+	case NoOpOption:
+		title = fmt.Sprintf("unchanged %s: \"%s\"", e.ResourceType, name)
 	}
 
-	return output, nil
+	lw := newOutputWriter(w, e.resolvedOption(), opts.Color && !noColor())
+	if _, err := io.WriteString(lw, title); err != nil {
+		return err
+	}
+	if body != nil {
+		if _, err := io.WriteString(lw, "\n"); err != nil {
+			return err
+		}
+		if err := body(lw); err != nil {
+			return err
+		}
+	}
+	return lw.Flush()
 }
 
-func apply[T any](client apisix.ResourceClient[T], event *Event) error {
-	var err error
+// isNil reports whether the value is nil or a nil pointer.
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// marshal renders the value for the update diff.
+// The content of proto is printed as is instead of an escaped JSON string,
+// so that the diff points at the changed lines of the .proto source.
+// For other types, the sensitive fields are redacted and the ignored
+// top-level fields are removed.
+func marshal(typ ResourceType, value interface{}, fields RedactedFields, ignored []string) ([]byte, error) {
+	proto, ok := value.(*types.Proto)
+	if !ok || proto == nil {
+		raw, err := redactedRaw(typ, value, fields, ignored)
+		if err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, raw, "", "\t"); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	}
+
+	out, err := json.MarshalIndent(struct {
+		ID     string       `json:"id"`
+		Desc   string       `json:"desc,omitempty"`
+		Labels types.Labels `json:"labels,omitempty"`
+	}{
+		ID:     proto.ID,
+		Desc:   proto.Desc,
+		Labels: proto.Labels,
+	}, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, '\n')
+	out = append(out, strings.TrimSuffix(proto.Content, "\n")...)
+	return out, nil
+}
+
+// marshalUpdate renders both sides of the update diff like marshal, with
+// a trailing newline. They are in the canonical form of the resource type
+// with the remote keys ordered like the local ones, so that the diff only
+// shows the real changes, see canonicalPair. The changed sensitive fields
+// are marked, see unmarkChanged. A missing remote value is rendered as
+// nothing.
+func marshalUpdate(typ ResourceType, oldValue, value interface{}, fields RedactedFields, ignored []string) (remote, local []byte, err error) {
+	if _, ok := value.(*types.Proto); ok {
+		if !isNil(oldValue) {
+			if remote, err = marshal(typ, oldValue, fields, ignored); err != nil {
+				return nil, nil, err
+			}
+			remote = append(remote, '\n')
+		}
+		if local, err = marshal(typ, value, fields, ignored); err != nil {
+			return nil, nil, err
+		}
+		return remote, append(local, '\n'), nil
+	}
+
+	oldNode, node, err := updateNodes(typ, oldValue, value, fields, ignored)
+	if err != nil {
+		return nil, nil, err
+	}
+	remote, local = marshalNodes(oldNode, node)
+	return remote, local, nil
+}
+
+// updateNodes returns both sides of the update diff before rendering them,
+// see marshalUpdate. The old node is nil if the old value is.
+func updateNodes(typ ResourceType, oldValue, value interface{}, fields RedactedFields, ignored []string) (oldNode, node *jsonNode, err error) {
+	oldRaw, raw, err := redactedPairJSON(typ, oldValue, value, fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	if oldRaw != nil {
+		if oldRaw, err = stripFields(oldRaw, ignored); err != nil {
+			return nil, nil, err
+		}
+	}
+	if raw, err = stripFields(raw, ignored); err != nil {
+		return nil, nil, err
+	}
+	return canonicalPair(typ, oldRaw, raw)
+}
+
+// marshalNodes renders both sides of the update diff with a trailing
+// newline, a nil node is rendered as nothing.
+func marshalNodes(oldNode, node *jsonNode) (remote, local []byte) {
+	if oldNode != nil {
+		remote = append(oldNode.marshalIndent(), '\n')
+	}
+	if node != nil {
+		local = append(node.marshalIndent(), '\n')
+	}
+	return remote, local
+}
+
+// writeUnifiedDiff writes the unified diff of both sides of an update.
+func writeUnifiedDiff(w io.Writer, remote, local []byte, opts *OutputOptions) error {
+	edits := myers.ComputeEdits(span.URIFromPath("remote"), string(remote), string(local))
+	_, err := fmt.Fprint(w, toUnified("remote", "local", string(remote), edits, opts.contextLines()))
+	return err
+}
+
+// redactedRaw is the compact JSON of the value with the sensitive fields
+// redacted and the ignored top-level fields removed.
+func redactedRaw(typ ResourceType, value interface{}, fields RedactedFields, ignored []string) ([]byte, error) {
+	raw, err := redactedJSON(typ, value, fields)
+	if err != nil {
+		return nil, err
+	}
+	return stripFields(raw, ignored)
+}
+
+func apply[T any](ctx context.Context, client apisix.ResourceClient[T], event *Event) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "cancelled before applying "+string(event.ResourceType))
+	}
+
+	key, err := event.resourceKey()
+	if err != nil {
+		return err
+	}
+	var value *T
+	if event.Option != DeleteOption {
+		var ok bool
+		if value, ok = event.Value.(*T); !ok {
+			return errors.Errorf("invalid %s event: value must be %T, got %T", event.ResourceType, value, event.Value)
+		}
+	}
+
 	switch event.Option {
 	case CreateOption:
-		_, err = client.Create(context.Background(), event.Value.(*T))
+		_, err = client.Create(ctx, value)
 	case DeleteOption:
-		err = client.Delete(context.Background(), apisix.GetResourceUniqueKey(event.OldValue))
+		// resources are deleted by their identifiers, an empty one would
+		// address the whole collection
+		if key == "" {
+			return errors.Errorf("invalid %s event: %s", event.ResourceType, missingKeyError(event.ResourceType))
+		}
+		err = client.Delete(ctx, key)
+		if errors.Is(err, apisix.ErrStillInUse) {
+			return errors.Wrapf(err, "failed to delete %s \"%s\", it is still referenced by other resources", event.ResourceType, key)
+		}
 	case UpdateOption:
-		_, err = client.Update(context.Background(), event.Value.(*T))
+		_, err = client.Update(ctx, value)
+	case UpsertOption:
+		// the resource is looked up by its identifier like for deletes
+		if key == "" {
+			return errors.Errorf("invalid %s event: %s", event.ResourceType, missingKeyError(event.ResourceType))
+		}
+		err = upsert(ctx, client, key, value)
 	}
 
+	if err != nil && ctx.Err() != nil {
+		// the in-flight request is cancelled, the cluster may or may not have applied it
+		return errors.Wrap(err, "cancelled while applying "+string(event.ResourceType))
+	}
 	return errors.Wrap(err, "failed to apply "+string(event.ResourceType))
 }
 
-func applyService(cluster apisix.Cluster, event *Event) error {
-	return apply[types.Service](cluster.Service(), event)
+// upsert updates the resource if it exists in the cluster and creates it
+// otherwise.
+func upsert[T any](ctx context.Context, client apisix.ResourceClient[T], key string, value *T) error {
+	_, err := client.Get(ctx, key)
+	if errors.Is(err, apisix.ErrNotFound) {
+		_, err = client.Create(ctx, value)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	_, err = client.Update(ctx, value)
+	return err
+}
+
+// resolvedOption returns the option an upsert amounts to as far as it is
+// known: an update of the old value if set, a create otherwise. The other
+// options are returned as is.
+func (e *Event) resolvedOption() Option {
+	if e.Option != UpsertOption {
+		return e.Option
+	}
+	if isNil(e.OldValue) {
+		return CreateOption
+	}
+	return UpdateOption
 }
 
-func applyRoute(cluster apisix.Cluster, event *Event) error {
-	return apply[types.Route](cluster.Route(), event)
+func applyService(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	return apply[types.Service](ctx, cluster.Service(), event)
 }
 
-func applyConsumer(cluster apisix.Cluster, event *Event) error {
-	return apply[types.Consumer](cluster.Consumer(), event)
+func applyRoute(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	return apply[types.Route](ctx, cluster.Route(), event)
 }
 
-func applySSL(cluster apisix.Cluster, event *Event) error {
-	return apply[types.SSL](cluster.SSL(), event)
+func applyConsumer(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	return apply[types.Consumer](ctx, cluster.Consumer(), event)
 }
 
-func applyGlobalRule(cluster apisix.Cluster, event *Event) error {
-	return apply[types.GlobalRule](cluster.GlobalRule(), event)
+func applySSL(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	return apply[types.SSL](ctx, cluster.SSL(), event)
 }
 
-func applyPluginConfig(cluster apisix.Cluster, event *Event) error {
-	return apply[types.PluginConfig](cluster.PluginConfig(), event)
+func applyGlobalRule(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	return apply[types.GlobalRule](ctx, cluster.GlobalRule(), event)
 }
 
-func applyConsumerGroup(cluster apisix.Cluster, event *Event) error {
-	return apply[types.ConsumerGroup](cluster.ConsumerGroup(), event)
+func applyPluginConfig(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	return apply[types.PluginConfig](ctx, cluster.PluginConfig(), event)
 }
 
-func applyPluginMetadata(cluster apisix.Cluster, event *Event) error {
-	return apply[types.PluginMetadata](cluster.PluginMetadata(), event)
+func applyConsumerGroup(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	return apply[types.ConsumerGroup](ctx, cluster.ConsumerGroup(), event)
 }
 
-func applyStreamRoute(cluster apisix.Cluster, event *Event) error {
-	return apply[types.StreamRoute](cluster.StreamRoute(), event)
+func applyPluginMetadata(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	return apply[types.PluginMetadata](ctx, cluster.PluginMetadata(), event)
 }
 
-func applyUpstream(cluster apisix.Cluster, event *Event) error {
-	return apply[types.Upstream](cluster.Upstream(), event)
+func applyStreamRoute(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	return apply[types.StreamRoute](ctx, cluster.StreamRoute(), event)
 }
 
-func (e *Event) Apply(cluster apisix.Cluster) error {
+func applyUpstream(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	return apply[types.Upstream](ctx, cluster.Upstream(), event)
+}
+
+func applySecret(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	return apply[types.Secret](ctx, cluster.Secret(), event)
+}
+
+func applyProto(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	return apply[types.Proto](ctx, cluster.Proto(), event)
+}
+
+// Apply applies the event to the cluster, the in-flight request is
+// cancelled when the ctx is done. No-op updates are skipped, unless ctx is
+// a ContextWithForce context. Resource types unsupported by the version of
+// the cluster fail with the error of Version.CheckResourceType, see
+// NewVersionedCluster.
+// With a tracer in ctx, the event is traced in an "adc.apply" span, see
+// tracing.ContextWithTracer. With a logger in ctx, see ContextWithLogger,
+// its start and its outcome are logged.
+func (e *Event) Apply(ctx context.Context, cluster apisix.Cluster) (err error) {
+	ctx, span := tracing.Start(ctx, "adc.apply",
+		tracing.String("adc.resource_type", string(e.ResourceType)),
+		tracing.String("adc.option", e.Option.String()),
+		tracing.String("adc.name", e.key()),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	logger := loggerFromContext(ctx)
+	if logger == nil {
+		return e.apply(ctx, cluster)
+	}
+	start := time.Now()
+	logStart(logger, e)
+	err = e.apply(ctx, cluster)
+	logFinish(logger, e, start, err)
+	return err
+}
+
+func (e *Event) apply(ctx context.Context, cluster apisix.Cluster) error {
+	if err := e.checkVersion(cluster); err != nil {
+		return errors.Wrapf(err, "failed to apply %s", e.ResourceType)
+	}
+	// skip the update that changes nothing to avoid churning the cluster,
+	// unless forced
+	noop, err := e.IsNoOp()
+	if err != nil {
+		return err
+	}
+	if noop {
+		if !forced(ctx) {
+			return nil
+		}
+		e = e.forcedUpdate()
+	}
+
 	switch e.ResourceType {
 	case ServiceResourceType:
-		return applyService(cluster, e)
+		return applyService(ctx, cluster, e)
 	case RouteResourceType:
-		return applyRoute(cluster, e)
+		return applyRoute(ctx, cluster, e)
 	case ConsumerResourceType:
-		return applyConsumer(cluster, e)
+		return applyConsumer(ctx, cluster, e)
 	case SSLResourceType:
-		return applySSL(cluster, e)
+		return applySSL(ctx, cluster, e)
 	case GlobalRuleResourceType:
-		return applyGlobalRule(cluster, e)
+		return applyGlobalRule(ctx, cluster, e)
 	case PluginConfigResourceType:
-		return applyPluginConfig(cluster, e)
+		return applyPluginConfig(ctx, cluster, e)
 	case ConsumerGroupResourceType:
-		return applyConsumerGroup(cluster, e)
+		return applyConsumerGroup(ctx, cluster, e)
 	case PluginMetadataResourceType:
-		return applyPluginMetadata(cluster, e)
+		return applyPluginMetadata(ctx, cluster, e)
 	case StreamRouteResourceType:
-		return applyStreamRoute(cluster, e)
+		return applyStreamRoute(ctx, cluster, e)
 	case UpstreamResourceType:
-		return applyUpstream(cluster, e)
+		return applyUpstream(ctx, cluster, e)
+	case SecretResourceType:
+		return applySecret(ctx, cluster, e)
+	case ProtoResourceType:
+		return applyProto(ctx, cluster, e)
 	}
 
-	return nil
+	return errors.Errorf("unsupported resource type %q", e.ResourceType)
 }
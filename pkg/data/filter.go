@@ -0,0 +1,130 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// Filter reports whether the event is selected.
+type Filter func(event *Event) bool
+
+// FilterEvents returns the events selected by all the filters, in the same
+// order. All the events are selected without filters.
+func FilterEvents(events []*Event, filters ...Filter) []*Event {
+	if len(filters) == 0 {
+		return events
+	}
+	selected := make([]*Event, 0, len(events))
+	for _, event := range events {
+		if matchAll(event, filters) {
+			selected = append(selected, event)
+		}
+	}
+	return selected
+}
+
+func matchAll(event *Event, filters []Filter) bool {
+	for _, filter := range filters {
+		if filter != nil && !filter(event) {
+			return false
+		}
+	}
+	return true
+}
+
+// Not selects the events not selected by the filter, e.g. Not(ByName(re))
+// excludes the resources matching re.
+func Not(filter Filter) Filter {
+	return func(event *Event) bool {
+		return !filter(event)
+	}
+}
+
+// ByResourceType selects the events changing resources of the given types.
+func ByResourceType(types ...ResourceType) Filter {
+	allowed := make(map[ResourceType]struct{}, len(types))
+	for _, typ := range types {
+		allowed[typ] = struct{}{}
+	}
+	return func(event *Event) bool {
+		_, ok := allowed[event.ResourceType]
+		return ok
+	}
+}
+
+// ByName selects the events changing resources whose identifier matches
+// the regular expression, see identifiers. Deletes are matched with the
+// identifier of the old value.
+func ByName(re *regexp.Regexp) Filter {
+	return func(event *Event) bool {
+		key, err := event.resourceKey()
+		return err == nil && re.MatchString(key)
+	}
+}
+
+// ByNameGlob is ByName with a glob pattern matching the whole identifier,
+// "*" matches any sequence of characters, "?" matches any single character
+// and "\\" escapes the next character.
+func ByNameGlob(pattern string) (Filter, error) {
+	re, err := compileGlob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return ByName(re), nil
+}
+
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '\\':
+			i++
+			if i == len(runes) {
+				return nil, fmt.Errorf("invalid glob %q: trailing backslash", pattern)
+			}
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// ByLabels selects the events changing resources with all the labels, like
+// the labels filter of the dump command. Resources without labels, and the
+// types which don't support labels, are never selected. Deletes are matched
+// with the labels of the old value.
+func ByLabels(labels types.Labels) Filter {
+	return func(event *Event) bool {
+		value := event.Value
+		if event.Option == DeleteOption {
+			value = event.OldValue
+		}
+		res, ok := value.(types.HasLabels)
+		return ok && !isNil(res) && types.MatchLabels(labels, res.GetLabels())
+	}
+}
+
+// ParseResourceTypes parses resource type names like "route" or
+// "global_rule", an error is returned for unknown types.
+func ParseResourceTypes(names []string) ([]ResourceType, error) {
+	types := make([]ResourceType, 0, len(names))
+	for _, name := range names {
+		typ := ResourceType(name)
+		if _, ok := identifiers[typ]; !ok {
+			return nil, fmt.Errorf("unknown resource type %q", name)
+		}
+		types = append(types, typ)
+	}
+	return types, nil
+}
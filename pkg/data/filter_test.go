@@ -0,0 +1,154 @@
+package data
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestFilterEvents(t *testing.T) {
+	events := []*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: route},
+		{ResourceType: ConsumerResourceType, Option: DeleteOption, OldValue: consumer},
+	}
+
+	// Test case 1: no filters selects all the events
+	assert.Equal(t, events, FilterEvents(events))
+
+	// Test case 2: by resource type
+	assert.Equal(t, events[:2], FilterEvents(events, ByResourceType(RouteResourceType, ServiceResourceType)))
+	assert.Equal(t, events[2:], FilterEvents(events, ByResourceType(ConsumerResourceType)), "should filter deletes too")
+	assert.Empty(t, FilterEvents(events, ByResourceType()), "should select nothing")
+
+	// Test case 3: the filters are combined
+	onlyCreates := func(event *Event) bool { return event.Option == CreateOption }
+	assert.Equal(t, events[1:2], FilterEvents(events, ByResourceType(RouteResourceType, ConsumerResourceType), onlyCreates))
+
+	// Test case 4: exclusions
+	assert.Equal(t, events[2:], FilterEvents(events, Not(onlyCreates)))
+	assert.Equal(t, events[1:2], FilterEvents(events, onlyCreates, Not(ByResourceType(ServiceResourceType))))
+}
+
+func TestByNameGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*", "users-1", true},
+		{"*", "", true},
+		{"users-*", "users-1", true},
+		{"users-*", "users-", true},
+		{"users-*", "admin-users-1", false},
+		{"api?-v2", "api1-v2", true},
+		{"api?-v2", "api-v2", false},
+		{"api?-v2", "api12-v2", false},
+		{"api?-v2", "api1-v21", false},
+		{"api?-v2", "apié-v2", true},
+		{"a.b", "a.b", true},
+		{"a.b", "axb", false},
+		{`a\*`, "a*", true},
+		{`a\*`, "ab", false},
+	}
+	for _, tc := range tests {
+		filter, err := ByNameGlob(tc.pattern)
+		assert.Nil(t, err, "should not return error")
+		event := &Event{
+			ResourceType: RouteResourceType,
+			Option:       CreateOption,
+			Value:        &types.Route{ID: tc.name},
+		}
+		assert.Equal(t, tc.match, filter(event), "%q should match %q: %v", tc.pattern, tc.name, tc.match)
+	}
+
+	_, err := ByNameGlob(`users-\`)
+	assert.Equal(t, `invalid glob "users-\\": trailing backslash`, err.Error())
+}
+
+func TestByName(t *testing.T) {
+	events := []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "users-1"}},
+		{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: &types.Route{ID: "orders-1"}},
+		{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: &types.Route{ID: "users-2"}},
+		{ResourceType: ConsumerResourceType, Option: UpdateOption, OldValue: consumer, Value: consumer},
+	}
+
+	// Test case 1: deletes are matched on the old value
+	filter, err := ByNameGlob("users-*")
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []*Event{events[0], events[2]}, FilterEvents(events, filter))
+
+	// Test case 2: regular expressions are not anchored
+	assert.Equal(t, events[1:2], FilterEvents(events, ByName(regexp.MustCompile(`^orders-\d$`))))
+	assert.Equal(t, events[3:], FilterEvents(events, ByName(regexp.MustCompile("ac"))), "should match the username")
+
+	// Test case 3: composed with the resource types
+	filter, err = ByNameGlob("*")
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, events[3:], FilterEvents(events, ByResourceType(ConsumerResourceType), filter))
+}
+
+func TestParseResourceTypes(t *testing.T) {
+	// Test case 1: known types
+	types, err := ParseResourceTypes([]string{"route", "global_rule"})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []ResourceType{RouteResourceType, GlobalRuleResourceType}, types)
+
+	// Test case 2: unknown types
+	_, err = ParseResourceTypes([]string{"route", "routes"})
+	assert.Equal(t, `unknown resource type "routes"`, err.Error())
+}
+
+func TestApplyAllFilters(t *testing.T) {
+	fake := newFakeCluster()
+	events := append(routeEvents(2), &Event{
+		ResourceType: ServiceResourceType,
+		Option:       DeleteOption,
+		OldValue:     svc,
+	})
+	err := ApplyAllWithOptions(context.Background(), fake, events, ApplyOptions{
+		Filters: []Filter{ByResourceType(RouteResourceType)},
+	})
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, fake.route.items, 2, "should apply the routes")
+	assert.Empty(t, fake.service.calls, "should not apply the service")
+
+	// Test case 2: a custom predicate excludes the routes with a plugin
+	fake = newFakeCluster()
+	events = routeEvents(2)
+	events[1].Value.(*types.Route).Plugins = types.Plugins{"ip-restriction": {}}
+	withPlugin := func(event *Event) bool {
+		route, ok := event.Value.(*types.Route)
+		return ok && route.Plugins["ip-restriction"] != nil
+	}
+	err = ApplyAllWithOptions(context.Background(), fake, events, ApplyOptions{
+		Filters: []Filter{ByResourceType(RouteResourceType), Not(withPlugin)},
+	})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []string{"create:route0"}, fake.route.calls)
+}
+
+func TestByLabels(t *testing.T) {
+	payments := types.Labels{"team": "payments", "env": "prod"}
+	events := []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "route1", Labels: payments}},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "route2", Labels: types.Labels{"team": "orders"}}},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "route3"}},
+		{ResourceType: ServiceResourceType, Option: DeleteOption, OldValue: &types.Service{ID: "svc", Labels: payments}},
+		{ResourceType: GlobalRuleResourceType, Option: CreateOption, Value: &types.GlobalRule{ID: "rule"}},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: (*types.Route)(nil)},
+	}
+
+	// Test case 1: a single label
+	filter := ByLabels(types.Labels{"team": "payments"})
+	assert.Equal(t, []*Event{events[0], events[3]}, FilterEvents(events, filter), "should match the deletes by the old value")
+
+	// Test case 2: all the labels must match
+	assert.Equal(t, events[:1], FilterEvents(events, ByLabels(types.Labels{"team": "payments", "env": "prod"}), ByResourceType(RouteResourceType)))
+	assert.Empty(t, FilterEvents(events, ByLabels(types.Labels{"team": "payments", "env": "dev"})))
+}
@@ -0,0 +1,28 @@
+package data
+
+import "context"
+
+type forceKey struct{}
+
+// ContextWithForce returns a copy of ctx forcing the events applied with it
+// to be written even if they change nothing, e.g. to make APISIX re-read
+// the unchanged resources after upgrading a plugin. By default, Apply skips
+// the updates that change nothing, with ctx they are sent anyway and the
+// noop events are applied as updates.
+func ContextWithForce(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceKey{}, true)
+}
+
+// forced reports whether ctx is of ContextWithForce.
+func forced(ctx context.Context) bool {
+	force, _ := ctx.Value(forceKey{}).(bool)
+	return force
+}
+
+// forcedUpdate returns the update writing the value of the event that
+// changes nothing, see ContextWithForce.
+func (e *Event) forcedUpdate() *Event {
+	update := *e
+	update.Option = UpdateOption
+	return &update
+}
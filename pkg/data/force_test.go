@@ -0,0 +1,36 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForceApply(t *testing.T) {
+	update := &Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: route}
+	noop := &Event{ResourceType: ServiceResourceType, Option: NoOpOption, OldValue: svc, Value: svc}
+
+	// Test case 1: the events changing nothing are skipped by default
+	cluster := newFakeCluster()
+	assert.Nil(t, update.Apply(context.Background(), cluster), "should not return error")
+	assert.Nil(t, noop.Apply(context.Background(), cluster), "should not return error")
+	assert.Empty(t, cluster.route.calls)
+	assert.Empty(t, cluster.service.calls)
+
+	// Test case 2: forced, they are written as updates
+	ctx := ContextWithForce(context.Background())
+	assert.Nil(t, update.Apply(ctx, cluster), "should not return error")
+	assert.Nil(t, noop.Apply(ctx, cluster), "should not return error")
+	assert.Equal(t, []string{"update:route"}, cluster.route.calls)
+	assert.Equal(t, []string{"update:svc"}, cluster.service.calls)
+	assert.Equal(t, NoOpOption, noop.Option, "should not change the event")
+
+	// Test case 3: the force option of ApplyAll
+	cluster = newFakeCluster()
+	results, err := ApplyAllWithResults(context.Background(), cluster, []*Event{update, noop}, ApplyOptions{Force: true})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []*Event{update, noop}, results.Events(AppliedStatus), "should not skip the events")
+	assert.Equal(t, []string{"update:route"}, cluster.route.calls)
+	assert.Equal(t, []string{"update:svc"}, cluster.service.calls)
+}
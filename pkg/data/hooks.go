@@ -0,0 +1,32 @@
+package data
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// BeforeApplyFunc is called before each event is applied, the event is
+// skipped if it returns an error.
+type BeforeApplyFunc func(ctx context.Context, event *Event) error
+
+// AfterApplyFunc is called after each event is applied or skipped, err is
+// the error of applying the event, or nil on success.
+type AfterApplyFunc func(ctx context.Context, event *Event, err error)
+
+// beforeApply calls the before hook, if there is one. The error of an
+// aborting hook is the error of the event.
+func (opts *ApplyOptions) beforeApply(ctx context.Context, event *Event) error {
+	if opts.BeforeApply == nil {
+		return nil
+	}
+	return errors.Wrap(opts.BeforeApply(ctx, event), "aborted by the before apply hook")
+}
+
+// afterApply calls the after hook, if there is one.
+func (opts *ApplyOptions) afterApply(ctx context.Context, event *Event, err error) {
+	if opts.AfterApply == nil {
+		return
+	}
+	opts.AfterApply(ctx, event, err)
+}
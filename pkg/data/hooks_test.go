@@ -0,0 +1,96 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestApplyAllHooks(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		before []string
+		after  = map[string]error{}
+	)
+	opts := ApplyOptions{
+		Concurrency:     2,
+		ContinueOnError: true,
+		BeforeApply: func(_ context.Context, event *Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			before = append(before, event.key())
+			if event.Option == DeleteOption {
+				return errors.New("deleting is frozen")
+			}
+			return nil
+		},
+		AfterApply: func(_ context.Context, event *Event, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			after[event.key()] = err
+		},
+	}
+
+	// Test case 1: an aborting hook skips the event
+	cluster := newFakeCluster()
+	cluster.service.items["svc"] = svc
+	events := append(routeEvents(2), &Event{
+		ResourceType: ServiceResourceType,
+		Option:       DeleteOption,
+		OldValue:     svc,
+	})
+	err := ApplyAllWithOptions(context.Background(), cluster, events, opts)
+	assert.EqualError(t, err, `service "svc": aborted by the before apply hook: deleting is frozen`)
+	assert.Len(t, cluster.route.items, 2, "should apply the routes")
+	assert.Empty(t, cluster.service.calls, "should skip the delete")
+
+	sort.Strings(before)
+	assert.Equal(t, []string{"route0", "route1", "svc"}, before)
+	assert.Len(t, after, 3, "should call the after hook for every event")
+	assert.Nil(t, after["route0"])
+	assert.EqualError(t, after["svc"], "aborted by the before apply hook: deleting is frozen")
+
+	// Test case 2: the after hook gets the error of the event
+	cluster = newFakeCluster()
+	cluster.route.err = errors.New("unexpected status code 400")
+	after = map[string]error{}
+	err = ApplyAllWithOptions(context.Background(), cluster, routeEvents(1), opts)
+	assert.NotNil(t, err, "should return error")
+	assert.EqualError(t, after["route0"], "failed to apply route: unexpected status code 400")
+
+	// Test case 3: an aborted bulk phase writes nothing
+	bulk := &bulkCluster{fakeCluster: newFakeCluster()}
+	opts.Bulk = true
+	opts.BeforeApply = func(_ context.Context, event *Event) error {
+		if event.key() == "route1" {
+			return errors.New("not now")
+		}
+		return nil
+	}
+	err = ApplyAllWithOptions(context.Background(), bulk, routeEvents(3), opts)
+	assert.EqualError(t, err, `route "route1": aborted by the before apply hook: not now`)
+	assert.Equal(t, 0, bulk.requests, "should not write the batch")
+
+	// Test case 4: the after hook is called for the invalid events of a bulk
+	// phase
+	bulk = &bulkCluster{fakeCluster: newFakeCluster()}
+	opts.BeforeApply = nil
+	after = map[string]error{}
+	events = routeEvents(3)
+	events[2].Value = &types.Route{ID: "route2"}
+	err = ApplyAllWithOptions(context.Background(), bulk, events, opts)
+	assert.NotNil(t, err, "should return error")
+	assert.Equal(t, 0, bulk.requests, "should not write the batch")
+	assert.Len(t, after, 1, "should call the after hook for the invalid event")
+	assert.Contains(t, after["route2"].Error(), "invalid route event")
+
+	// Test case 5: the hooks are optional
+	err = ApplyAllWithOptions(context.Background(), newFakeCluster(), routeEvents(1), ApplyOptions{})
+	assert.Nil(t, err, "should not return error")
+}
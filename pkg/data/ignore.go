@@ -0,0 +1,74 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// DefaultIgnoredFields are the fields managed by APISIX, they are removed
+// from both sides of update diffs. The id generated by APISIX is ignored
+// too when the local resource doesn't set one.
+var DefaultIgnoredFields = []string{
+	"create_time",
+	"update_time",
+}
+
+// ignoredFields returns the top-level fields removed from the update diff
+// of the event.
+func (e *Event) ignoredFields(opts *OutputOptions) ([]string, error) {
+	fields := opts.ignoredFields()
+	raw, err := json.Marshal(e.Value)
+	if err != nil {
+		return nil, err
+	}
+	hasID, err := isSet(raw, "id")
+	if err != nil {
+		return nil, err
+	}
+	if !hasID {
+		fields = append(fields[:len(fields):len(fields)], "id")
+	}
+	return fields, nil
+}
+
+// isSet reports whether the top-level field of the raw JSON object is
+// neither missing, null nor an empty string.
+func isSet(raw json.RawMessage, field string) (bool, error) {
+	if len(bytes.TrimSpace(raw)) == 0 || raw[0] != '{' {
+		return false, nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return false, err
+	}
+	value, ok := obj[field]
+	if !ok {
+		return false, nil
+	}
+	value = bytes.TrimSpace(value)
+	return string(value) != "null" && string(value) != `""`, nil
+}
+
+// stripFields removes the top-level fields from the raw JSON object, the
+// order of the remaining fields is kept.
+func stripFields(raw json.RawMessage, fields []string) (json.RawMessage, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(fields) == 0 || len(raw) == 0 || raw[0] != '{' {
+		return raw, nil
+	}
+	return walkObject(raw, func(key string, value json.RawMessage) (json.RawMessage, error) {
+		if contains(fields, key) {
+			return nil, nil
+		}
+		return value, nil
+	})
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
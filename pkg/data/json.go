@@ -0,0 +1,153 @@
+package data
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Change is a changed field of an update event.
+type Change struct {
+	// Path is the JSON pointer (RFC 6901) of the field, e.g. "/plugins/key-auth/key".
+	Path string `json:"path"`
+	// Old is the value in the cluster, it is omitted for added fields.
+	Old interface{} `json:"old,omitempty"`
+	// New is the value in the configuration, it is omitted for removed fields.
+	New interface{} `json:"new,omitempty"`
+}
+
+// EventOutput is the machine-readable output of an event.
+type EventOutput struct {
+	ResourceType ResourceType `json:"resource_type"`
+	Option       string       `json:"option"`
+	Name         string       `json:"name"`
+	// Changes are only set for update events, sorted by path.
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// StructuredOutput returns the machine-readable output of the event.
+// Sensitive fields are redacted like in Output.
+func (e *Event) StructuredOutput() (*EventOutput, error) {
+	out := &EventOutput{
+		ResourceType: e.ResourceType,
+		Option:       e.Option.String(),
+		Name:         e.key(),
+	}
+	if e.resolvedOption() != UpdateOption {
+		return out, nil
+	}
+
+	oldValue, err := normalizeRedacted(e.ResourceType, e.OldValue)
+	if err != nil {
+		return nil, err
+	}
+	value, err := normalizeRedacted(e.ResourceType, e.Value)
+	if err != nil {
+		return nil, err
+	}
+	out.Changes = diffValues("", oldValue, value, nil)
+	return out, nil
+}
+
+// OutputJSON returns the JSON encoded StructuredOutput of the event,
+// the result is deterministic.
+func (e *Event) OutputJSON() ([]byte, error) {
+	out, err := e.StructuredOutput()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// normalizeRedacted is normalize with the DefaultRedactedFields redacted.
+func normalizeRedacted(typ ResourceType, value interface{}) (interface{}, error) {
+	raw, err := redactedJSON(typ, value, DefaultRedactedFields)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// FieldDiff returns the changed fields between the old and new values of
+// the event, sorted by path. Added fields have no Old value, removed fields
+// have no New value, arrays are compared element by element. Creates and
+// deletes are compared with an empty object. Unlike StructuredOutput, the
+// values are not redacted.
+func (e *Event) FieldDiff() ([]Change, error) {
+	oldValue, err := normalizeOrEmpty(e.OldValue)
+	if err != nil {
+		return nil, err
+	}
+	value, err := normalizeOrEmpty(e.Value)
+	if err != nil {
+		return nil, err
+	}
+	return diffValues("", oldValue, value, nil), nil
+}
+
+func normalizeOrEmpty(value interface{}) (interface{}, error) {
+	if isNil(value) {
+		return map[string]interface{}{}, nil
+	}
+	return normalize(value)
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// diffValues appends the changes between the generic JSON values.
+func diffValues(path string, oldValue, value interface{}, changes []Change) []Change {
+	switch {
+	case isObject(oldValue) && isObject(value):
+		oldObj, obj := oldValue.(map[string]interface{}), value.(map[string]interface{})
+		keys := make([]string, 0, len(oldObj)+len(obj))
+		for k := range oldObj {
+			keys = append(keys, k)
+		}
+		for k := range obj {
+			if _, ok := oldObj[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			changes = diffValues(path+"/"+escapePointer(k), oldObj[k], obj[k], changes)
+		}
+	case isArray(oldValue) && isArray(value):
+		oldArr, arr := oldValue.([]interface{}), value.([]interface{})
+		for i := 0; i < len(oldArr) || i < len(arr); i++ {
+			var o, n interface{}
+			if i < len(oldArr) {
+				o = oldArr[i]
+			}
+			if i < len(arr) {
+				n = arr[i]
+			}
+			changes = diffValues(path+"/"+strconv.Itoa(i), o, n, changes)
+		}
+	case !reflect.DeepEqual(oldValue, value):
+		changes = append(changes, Change{
+			Path: path,
+			Old:  oldValue,
+			New:  value,
+		})
+	}
+	return changes
+}
+
+func isObject(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
+
+func isArray(v interface{}) bool {
+	_, ok := v.([]interface{})
+	return ok
+}
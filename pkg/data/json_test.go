@@ -0,0 +1,132 @@
+package data
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestOutputJSON(t *testing.T) {
+	// Test case 1: create and delete
+	out, err := (&Event{ResourceType: RouteResourceType, Option: CreateOption, Value: route}).OutputJSON()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, `{"resource_type":"route","option":"create","name":"route"}`, string(out))
+	out, err = (&Event{ResourceType: ConsumerResourceType, Option: DeleteOption, OldValue: consumer}).OutputJSON()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, `{"resource_type":"consumer","option":"delete","name":"jack"}`, string(out))
+
+	// Test case 2: update
+	svc1 := *svc
+	svc1.Hosts = []string{"svc.example.com", "svc1.example.com"}
+	svc1.Labels = map[string]string{"label1": "v1", "label/2": "v3"}
+	svc1.Upstream = nil
+	event := &Event{
+		ResourceType: ServiceResourceType,
+		Option:       UpdateOption,
+		OldValue:     svc,
+		Value:        &svc1,
+	}
+	structured, err := event.StructuredOutput()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []Change{
+		{Path: "/hosts/1", New: "svc1.example.com"},
+		{Path: "/labels/label~12", New: "v3"},
+		{Path: "/labels/label2", Old: "v2"},
+		{Path: "/upstream", Old: map[string]interface{}{
+			"id":    "",
+			"name":  "upstream1",
+			"nodes": []interface{}{map[string]interface{}{"host": "httpbin.org", "port": float64(0)}},
+		}},
+	}, structured.Changes)
+
+	out, err = event.OutputJSON()
+	assert.Nil(t, err, "should not return error")
+	for i := 0; i < 10; i++ {
+		again, err := event.OutputJSON()
+		assert.Nil(t, err, "should not return error")
+		assert.Equal(t, string(out), string(again), "should be deterministic")
+	}
+
+	// Test case 3: sensitive fields are redacted
+	out, err = (&Event{
+		ResourceType: SSLResourceType,
+		Option:       UpdateOption,
+		OldValue:     &types.SSL{ID: "ssl", Key: "old-key", SNIs: []string{"a.com"}},
+		Value:        &types.SSL{ID: "ssl", Key: "new-key", SNIs: []string{"b.com"}},
+	}).OutputJSON()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, `{"resource_type":"ssl","option":"update","name":"ssl","changes":[{"path":"/snis/0","old":"a.com","new":"b.com"}]}`, string(out))
+}
+
+func TestFieldDiff(t *testing.T) {
+	route1 := &types.Route{
+		ID:   "route",
+		Name: "route",
+		Labels: map[string]string{
+			"label1": "v1",
+		},
+		Methods:   []string{http.MethodGet, http.MethodPost},
+		Uris:      []string{"/anything"},
+		ServiceID: "svc",
+		Plugins: types.Plugins{
+			"jwt-auth": types.Plugin{},
+		},
+	}
+	route2 := *route1
+	route2.Plugins = types.Plugins{
+		"key-auth": types.Plugin{},
+	}
+
+	// Test case 1: added, removed and changed fields
+	changes, err := (&Event{
+		ResourceType: RouteResourceType,
+		Option:       UpdateOption,
+		OldValue:     route,
+		Value:        route1,
+	}).FieldDiff()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []Change{
+		{Path: "/labels/label2", Old: "v2"},
+		{Path: "/methods/1", New: http.MethodPost},
+		{Path: "/plugins", New: map[string]interface{}{"jwt-auth": map[string]interface{}{}}},
+		{Path: "/uris/0", Old: "/get", New: "/anything"},
+	}, changes)
+
+	// Test case 2: detect disabling a plugin
+	changes, err = (&Event{
+		ResourceType: RouteResourceType,
+		Option:       UpdateOption,
+		OldValue:     route1,
+		Value:        &route2,
+	}).FieldDiff()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []Change{
+		{Path: "/plugins/jwt-auth", Old: map[string]interface{}{}},
+		{Path: "/plugins/key-auth", New: map[string]interface{}{}},
+	}, changes)
+
+	// Test case 3: values are not redacted
+	changes, err = (&Event{
+		ResourceType: SSLResourceType,
+		Option:       UpdateOption,
+		OldValue:     &types.SSL{ID: "ssl", Key: "old-key"},
+		Value:        &types.SSL{ID: "ssl", Key: "new-key"},
+	}).FieldDiff()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []Change{{Path: "/key", Old: "old-key", New: "new-key"}}, changes)
+
+	// Test case 4: create compares with an empty object
+	changes, err = (&Event{
+		ResourceType: ConsumerResourceType,
+		Option:       CreateOption,
+		Value:        consumer,
+	}).FieldDiff()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []Change{
+		{Path: "/plugins", New: map[string]interface{}{"key-auth": map[string]interface{}{"key": "auth-one"}}},
+		{Path: "/username", New: "jack"},
+	}, changes)
+}
@@ -0,0 +1,75 @@
+package data
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"sigs.k8s.io/yaml"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+	"github.com/api7/adc/pkg/common"
+)
+
+// LoadConfiguration parses the declarative configuration, in YAML or JSON,
+// and returns the create events of its resources like FromConfiguration.
+// The environment variables are expanded before parsing, see ExpandEnv.
+// The resources are normalized like the configuration files of sync, e.g.
+// the id of a route defaults to its name.
+func LoadConfiguration(content []byte) ([]*Event, error) {
+	content, err := ExpandEnv(content)
+	if err != nil {
+		return nil, err
+	}
+	var conf types.Configuration
+	if err := yaml.Unmarshal(content, &conf); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the configuration")
+	}
+	common.NormalizeConfiguration(&conf)
+	return FromConfiguration(&conf), nil
+}
+
+// LoadFile is LoadConfiguration with the content of the file.
+func LoadFile(path string) ([]*Event, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	events, err := LoadConfiguration(content)
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+	return events, nil
+}
+
+// LoadFiles loads the files with LoadFile and merges their events, sorted
+// with SortEventsByKey so the order of the files doesn't matter. An error
+// is returned for every resource defined in more than one file, the
+// duplicates of a single file are reported by ValidateUnique instead.
+func LoadFiles(paths ...string) ([]*Event, error) {
+	var (
+		events  []*Event
+		errs    error
+		defined = make(map[string]string)
+	)
+	for _, path := range paths {
+		loaded, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range loaded {
+			id := string(event.ResourceType) + "/" + event.key()
+			if first, ok := defined[id]; ok && first != path {
+				errs = multierr.Append(errs, fmt.Errorf("%s \"%s\" is defined in both %s and %s", event.ResourceType, event.key(), first, path))
+				continue
+			}
+			defined[id] = path
+		}
+		events = append(events, loaded...)
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return SortEventsByKey(events), nil
+}
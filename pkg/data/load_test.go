@@ -0,0 +1,103 @@
+package data
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+const loadConfig = `name: test
+version: "1.0.0"
+services:
+- name: svc
+  hosts:
+  - svc.example.com
+routes:
+- name: route
+  service_id: svc
+  uris:
+  - /get
+consumers:
+- username: jack
+`
+
+func TestLoadConfiguration(t *testing.T) {
+	// Test case 1: YAML
+	events, err := LoadConfiguration([]byte(loadConfig))
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, events, 3)
+	for _, event := range events {
+		assert.Equal(t, CreateOption, event.Option)
+		assert.Nil(t, event.Validate(), "should be valid")
+	}
+	assert.Equal(t, ServiceResourceType, events[0].ResourceType)
+	assert.Equal(t, "svc", events[0].Value.(*types.Service).ID, "should default the id to the name")
+	assert.Equal(t, "route", events[1].key())
+	assert.Equal(t, "jack", events[2].key())
+
+	// Test case 2: JSON
+	events, err = LoadConfiguration([]byte(`{"routes":[{"id":"route","uris":["/get"]}]}`))
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, events, 1)
+	assert.Equal(t, "route", events[0].Value.(*types.Route).Name, "should default the name to the id")
+
+	// Test case 3: invalid
+	_, err = LoadConfiguration([]byte("routes: {"))
+	assert.Contains(t, err.Error(), "failed to parse the configuration")
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apisix.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(loadConfig), 0o600))
+
+	// Test case 1: the events of the file
+	events, err := LoadFile(path)
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, events, 3)
+
+	// Test case 2: the errors contain the path
+	assert.Nil(t, os.WriteFile(path, []byte("routes: {"), 0o600))
+	_, err = LoadFile(path)
+	assert.Contains(t, err.Error(), path+": failed to parse the configuration")
+	_, err = LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.True(t, os.IsNotExist(err), "should return the open error")
+}
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	services := write("services.yaml", "services:\n- name: svc\n  hosts:\n  - svc.example.com\n")
+	routes := write("routes.yaml", "routes:\n- name: route1\n  service_id: svc\n  uris:\n  - /get\n- name: route2\n  service_id: svc\n  uris:\n  - /post\n")
+	payments := write("payments.yaml", "routes:\n- name: payments\n  service_id: svc\n  uris:\n  - /pay\nconsumers:\n- username: jack\n")
+
+	// Test case 1: the order of the files doesn't matter
+	events, err := LoadFiles(services, routes, payments)
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, events, 5)
+	reversed, err := LoadFiles(payments, routes, services)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, events, reversed)
+	assert.Nil(t, multierr.Combine(ValidateEvents(events), ValidateUnique(events), ValidateReferences(context.Background(), nil, events)))
+
+	// Test case 2: the resources defined in several files
+	dup := write("dup.yaml", "routes:\n- name: route2\n  uris:\n  - /get\n- name: payments\n  uris:\n  - /get\n")
+	_, err = LoadFiles(services, routes, payments, dup)
+	errs := multierr.Errors(err)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "route \"route2\" is defined in both "+routes+" and "+dup, errs[0].Error())
+	assert.Equal(t, "route \"payments\" is defined in both "+payments+" and "+dup, errs[1].Error())
+
+	// Test case 3: the errors of loading a file
+	_, err = LoadFiles(services, filepath.Join(dir, "missing.yaml"))
+	assert.True(t, os.IsNotExist(err), "should return the open error")
+}
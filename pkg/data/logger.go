@@ -0,0 +1,73 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Logger is the structured logger of ApplyAllWithOptions, the messages come
+// with alternating keys and values. It is a subset of *zap.SugaredLogger,
+// which can be used as is.
+type Logger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// eventFields are the fields identifying the event in the logs.
+func eventFields(event *Event, fields ...interface{}) []interface{} {
+	return append([]interface{}{
+		"resource_type", string(event.ResourceType),
+		"option", event.Option.String(),
+		"name", event.key(),
+	}, fields...)
+}
+
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of ctx with the logger, so that
+// Event.Apply, and ApplyWithRetry for every attempt, log the start and the
+// outcome of the event with it. ApplyAllWithOptions uses it if
+// ApplyOptions.Logger isn't set.
+func ContextWithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFromContext returns the logger of ctx, or nil if there is none.
+func loggerFromContext(ctx context.Context) Logger {
+	logger, _ := ctx.Value(loggerKey{}).(Logger)
+	return logger
+}
+
+// logStart logs that the event is about to be applied, if there is a logger.
+func (opts *ApplyOptions) logStart(event *Event) {
+	logStart(opts.Logger, event)
+}
+
+// logFinish logs the outcome of applying the event since start, if there is
+// a logger.
+func (opts *ApplyOptions) logFinish(event *Event, start time.Time, err error) {
+	logFinish(opts.Logger, event, start, err)
+}
+
+func logStart(logger Logger, event *Event) {
+	if logger == nil {
+		return
+	}
+	logger.Debugw("applying event", eventFields(event)...)
+}
+
+// logFinish logs the outcome of the event, a failure is logged with the
+// root cause of the error too.
+func logFinish(logger Logger, event *Event, start time.Time, err error) {
+	if logger == nil {
+		return
+	}
+	if err != nil {
+		logger.Errorw("failed to apply event", eventFields(event, "error", err.Error(), "cause", errors.Cause(err).Error())...)
+		return
+	}
+	logger.Infow("applied event", eventFields(event, "duration", time.Since(start))...)
+}
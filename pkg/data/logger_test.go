@@ -0,0 +1,57 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestApplyAllLogger(t *testing.T) {
+	// Test case 1: the start and the outcome of every event are logged
+	core, logs := observer.New(zapcore.DebugLevel)
+	cluster := newFakeCluster()
+	err := ApplyAllWithOptions(context.Background(), cluster, routeEvents(2), ApplyOptions{
+		Logger: zap.New(core).Sugar(),
+	})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, 2, logs.FilterMessage("applying event").FilterField(zap.String("option", "create")).Len())
+	applied := logs.FilterMessage("applied event").FilterField(zap.String("name", "route1")).All()
+	assert.Len(t, applied, 1, "should log the applied route")
+	assert.Equal(t, zapcore.InfoLevel, applied[0].Level)
+	assert.Equal(t, string(RouteResourceType), applied[0].ContextMap()["resource_type"])
+
+	// Test case 2: failures are logged with the root cause
+	core, logs = observer.New(zapcore.InfoLevel)
+	cluster = newFakeCluster()
+	cluster.route.err = errors.Wrap(errors.New("connection refused"), "failed to create route")
+	err = ApplyAllWithOptions(context.Background(), cluster, routeEvents(1), ApplyOptions{
+		Logger: zap.New(core).Sugar(),
+	})
+	assert.NotNil(t, err, "should return error")
+	failed := logs.FilterMessage("failed to apply event").All()
+	assert.Len(t, failed, 1, "should log the failure")
+	assert.Equal(t, zapcore.ErrorLevel, failed[0].Level)
+	assert.Equal(t, "route0", failed[0].ContextMap()["name"])
+	assert.Equal(t, "connection refused", failed[0].ContextMap()["cause"])
+	assert.Zero(t, logs.FilterMessage("applying event").Len(), "should not log debug messages above the level")
+
+	// Test case 3: a single event is logged with the logger of the context
+	core, logs = observer.New(zapcore.DebugLevel)
+	ctx := ContextWithLogger(context.Background(), zap.New(core).Sugar())
+	event := routeEvents(1)[0]
+	assert.Nil(t, event.Apply(ctx, newFakeCluster()), "should not return error")
+	assert.Equal(t, 1, logs.FilterMessage("applying event").Len())
+	assert.Equal(t, 1, logs.FilterMessage("applied event").FilterField(zap.String("name", "route0")).Len())
+
+	// Test case 4: ApplyAllWithOptions logs every event once with the
+	// logger of the context
+	core, logs = observer.New(zapcore.DebugLevel)
+	ctx = ContextWithLogger(context.Background(), zap.New(core).Sugar())
+	assert.Nil(t, ApplyAllWithOptions(ctx, newFakeCluster(), routeEvents(2), ApplyOptions{}), "should not return error")
+	assert.Equal(t, 2, logs.FilterMessage("applied event").Len(), "should log every event once")
+}
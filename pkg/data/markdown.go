@@ -0,0 +1,99 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxSummaryFields is the maximum number of changed fields listed in a row
+// of MarkdownSummary.
+const maxSummaryFields = 3
+
+// MarkdownSummary returns the Markdown table of the planned changes, e.g.
+// for the comments of a CI bot on pull requests, with a row for each event
+// in the order they are applied, see SortEventsByKey. The diffs of updates
+// follow the table, collapsed in a details block each. Updates that change
+// nothing are omitted and sensitive fields are redacted like in Output.
+func MarkdownSummary(events []*Event) (string, error) {
+	var (
+		rows    strings.Builder
+		details strings.Builder
+		count   int
+	)
+	for _, event := range SortEventsByKey(events) {
+		noop, err := event.IsNoOp()
+		if err != nil {
+			return "", err
+		}
+		if noop {
+			continue
+		}
+		count++
+
+		changes, err := event.changeSummary()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&rows, "| %s | %s | %s | %s |\n",
+			event.ResourceType, markdownCell("`"+event.key()+"`"), event.Option, markdownCell(changes))
+
+		if event.resolvedOption() != UpdateOption {
+			continue
+		}
+		output, err := event.OutputWithOptions(&OutputOptions{DiffOnly: true})
+		if err != nil {
+			return "", err
+		}
+		title, diff, _ := strings.Cut(output, "\n")
+		fmt.Fprintf(&details, "\n<details>\n<summary>%s</summary>\n\n```diff\n%s\n```\n\n</details>\n",
+			strings.ReplaceAll(title, "\"", "&quot;"), strings.TrimSuffix(diff, "\n"))
+	}
+	if count == 0 {
+		return "No changes.\n", nil
+	}
+
+	var out strings.Builder
+	out.WriteString("| Resource type | Name | Action | Changes |\n")
+	out.WriteString("| --- | --- | --- | --- |\n")
+	out.WriteString(rows.String())
+	out.WriteString(details.String())
+	return out.String(), nil
+}
+
+// changeSummary returns the short summary of the change of the event, the
+// changed top-level fields for updates.
+func (e *Event) changeSummary() (string, error) {
+	switch e.resolvedOption() {
+	case CreateOption:
+		return "new resource", nil
+	case DeleteOption:
+		return "removed", nil
+	case UpdateOption:
+	default:
+		return "", nil
+	}
+
+	out, err := e.StructuredOutput()
+	if err != nil {
+		return "", err
+	}
+	var fields []string
+	seen := make(map[string]bool)
+	for _, change := range out.Changes {
+		field := strings.SplitN(strings.TrimPrefix(change.Path, "/"), "/", 2)[0]
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, "`"+field+"`")
+		}
+	}
+	summary := strings.Join(fields, ", ")
+	if len(fields) > maxSummaryFields {
+		summary = fmt.Sprintf("%s and %d more", strings.Join(fields[:maxSummaryFields], ", "), len(fields)-maxSummaryFields)
+	}
+	return "changed " + summary, nil
+}
+
+// markdownCell escapes the text for a cell of a Markdown table.
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", "\\|")
+}
@@ -0,0 +1,57 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestMarkdownSummary(t *testing.T) {
+	route1 := *route
+	route1.Description = "route1"
+	route1.Uris = []string{"/post"}
+	events := []*Event{
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: &route1},
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: ConsumerResourceType, Option: DeleteOption, OldValue: consumer},
+		{ResourceType: ServiceResourceType, Option: UpdateOption, OldValue: svc, Value: svc},
+	}
+
+	// Test case 1: a row for each change and the diffs of the updates
+	out, err := MarkdownSummary(events)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "| Resource type | Name | Action | Changes |\n"+
+		"| --- | --- | --- | --- |\n"+
+		"| service | `svc` | create | new resource |\n"+
+		"| route | `route` | update | changed `desc`, `uris` |\n"+
+		"| consumer | `jack` | delete | removed |\n"+
+		"\n<details>\n<summary>update route: &quot;route&quot;</summary>\n\n```diff\n"+
+		"--- remote\n"+
+		"+++ local\n"+
+		"@@ -5,8 +5,9 @@\n"+
+		" \t\t\"label1\": \"v1\",\n"+
+		" \t\t\"label2\": \"v2\"\n"+
+		" \t},\n"+
+		"+\t\"desc\": \"route1\",\n"+
+		" \t\"uris\": [\n"+
+		"-\t\t\"/get\"\n"+
+		"+\t\t\"/post\"\n"+
+		" \t],\n"+
+		" \t\"methods\": [\n"+
+		" \t\t\"GET\"\n"+
+		"```\n\n</details>\n", out)
+
+	// Test case 2: the cells are escaped and long summaries are shortened
+	route2 := &types.Route{ID: "a|b", Uris: []string{"/get"}}
+	route3 := &types.Route{ID: "a|b", Uris: []string{"/post"}, Description: "desc", Hosts: []string{"a.com"}, Methods: []string{"GET"}}
+	out, err = MarkdownSummary([]*Event{{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route2, Value: route3}})
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, out, "| route | `a\\|b` | update | changed `desc`, `hosts`, `methods` and 1 more |\n")
+
+	// Test case 3: no changes
+	out, err = MarkdownSummary(events[3:])
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "No changes.\n", out)
+}
@@ -0,0 +1,169 @@
+package data
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// DiffThreeWay is Diff respecting the changes made to the cluster out of
+// band, like kubectl apply. lastApplied are the resources of the last
+// applied configuration, e.g. stored with WriteConfig and loaded with
+// LoadFile. Only the fields managed by the configuration are changed: the
+// fields of the desired resources are set, and the fields of the last
+// applied resources missing in the desired ones are removed, objects are
+// merged field by field and the other values, arrays included, replaced.
+// The other fields of the current resources are kept. The current resources
+// missing in the desired ones are only deleted if they were last applied,
+// the ones created out of band are kept.
+func DiffThreeWay(lastApplied, desired, current []*Event) ([]*Event, error) {
+	lastByID, err := indexResources(lastApplied)
+	if err != nil {
+		return nil, errors.Wrap(err, "last applied")
+	}
+	desiredByID, err := indexResources(desired)
+	if err != nil {
+		return nil, err
+	}
+	currentByID, err := indexResources(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*Event
+	for _, event := range desired {
+		id := resourceID{typ: event.ResourceType, key: event.key()}
+		old, ok := currentByID[id]
+		if !ok {
+			events = append(events, &Event{
+				ResourceType: event.ResourceType,
+				Option:       CreateOption,
+				Value:        event.Value,
+			})
+			continue
+		}
+
+		var last interface{}
+		if lastEvent, ok := lastByID[id]; ok {
+			last = lastEvent.Value
+		}
+		value, changed, err := mergeThreeWay(event.ResourceType, last, event.Value, old.Value)
+		if err != nil {
+			return nil, &EventError{Event: event, Err: err}
+		}
+		if changed {
+			events = append(events, &Event{
+				ResourceType: event.ResourceType,
+				Option:       UpdateOption,
+				OldValue:     old.Value,
+				Value:        value,
+			})
+		}
+	}
+	for _, event := range current {
+		id := resourceID{typ: event.ResourceType, key: event.key()}
+		if _, ok := desiredByID[id]; ok {
+			continue
+		}
+		if _, ok := lastByID[id]; ok {
+			events = append(events, &Event{
+				ResourceType: event.ResourceType,
+				Option:       DeleteOption,
+				OldValue:     event.Value,
+			})
+		}
+	}
+	return SortEventsByKey(events), nil
+}
+
+// mergeThreeWay returns the current value with the changes from the last
+// applied value to the desired one, in the Go type of the resource type.
+// changed reports whether it differs from the current value, they are
+// compared before decoding, since decoding sets the APISIX defaults.
+func mergeThreeWay(typ ResourceType, last, desired, current interface{}) (value interface{}, changed bool, err error) {
+	newValue, ok := newValues[typ]
+	if !ok {
+		return nil, false, errors.Errorf("unsupported resource type %q", typ)
+	}
+	managedLast, err := managedValue(typ, last)
+	if err != nil {
+		return nil, false, err
+	}
+	managedDesired, err := managedValue(typ, desired)
+	if err != nil {
+		return nil, false, err
+	}
+	generic, err := patchValue(current, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	currentRaw, err := json.Marshal(generic)
+	if err != nil {
+		return nil, false, err
+	}
+	raw, err := json.Marshal(mergeValues(managedLast, managedDesired, generic))
+	if err != nil {
+		return nil, false, err
+	}
+	oldNode, node, err := canonicalPair(typ, currentRaw, raw)
+	if err != nil {
+		return nil, false, err
+	}
+	if oldNode.equal(node) {
+		return current, false, nil
+	}
+
+	value = newValue()
+	if err := json.Unmarshal(raw, value); err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// managedValue returns the generic JSON value of the fields set in the
+// configuration, that is without the fields set to their APISIX defaults by
+// decoding, see canonical. It is an empty object for a nil value.
+func managedValue(typ ResourceType, value interface{}) (interface{}, error) {
+	if isNil(value) {
+		return map[string]interface{}{}, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	node, err := parseNode(raw)
+	if err != nil {
+		return nil, err
+	}
+	node.canonical(typ)
+	return patchValue(json.RawMessage(node.marshalIndent()), nil)
+}
+
+// mergeValues merges the generic JSON values, see DiffThreeWay.
+func mergeValues(last, desired, current interface{}) interface{} {
+	desiredObj, ok := desired.(map[string]interface{})
+	if !ok {
+		return desired
+	}
+	currentObj, ok := current.(map[string]interface{})
+	if !ok {
+		return desired
+	}
+	lastObj, _ := last.(map[string]interface{})
+
+	merged := make(map[string]interface{}, len(currentObj))
+	for key, value := range currentObj {
+		if _, managed := lastObj[key]; managed {
+			if _, ok := desiredObj[key]; !ok {
+				// removed from the configuration
+				continue
+			}
+		}
+		merged[key] = value
+	}
+	for key, value := range desiredObj {
+		merged[key] = mergeValues(lastObj[key], value, currentObj[key])
+	}
+	return merged
+}
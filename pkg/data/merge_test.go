@@ -0,0 +1,82 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestDiffThreeWay(t *testing.T) {
+	last := &types.Route{ID: "route", Name: "route", Uris: []string{"/get"}, Description: "managed"}
+	current := &types.Route{ID: "route", Name: "route", Uris: []string{"/get"}, Description: "managed", Methods: []string{"GET"}}
+
+	// Test case 1: the fields changed out of band are kept
+	events, err := DiffThreeWay(
+		[]*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: last}},
+		[]*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: last}},
+		[]*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: current}},
+	)
+	assert.Nil(t, err, "should not return error")
+	assert.Empty(t, events, "should not revert the methods")
+
+	// Test case 2: the managed fields are updated, the others are kept
+	desired := &types.Route{ID: "route", Name: "route", Uris: []string{"/post"}}
+	events, err = DiffThreeWay(
+		[]*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: last}},
+		[]*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: desired}},
+		[]*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: current}},
+	)
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, events, 1)
+	assert.Equal(t, UpdateOption, events[0].Option)
+	assert.Equal(t, current, events[0].OldValue)
+	value := events[0].Value.(*types.Route)
+	assert.Equal(t, []string{"/post"}, value.Uris)
+	assert.Equal(t, []string{"GET"}, value.Methods, "should keep the field changed out of band")
+	assert.Empty(t, value.Description, "should remove the field removed from the configuration")
+
+	// Test case 3: without last applied resource, nothing is removed
+	events, err = DiffThreeWay(
+		nil,
+		[]*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: desired}},
+		[]*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: current}},
+	)
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, events, 1)
+	assert.Equal(t, "managed", events[0].Value.(*types.Route).Description)
+
+	// Test case 4: objects are merged field by field
+	events, err = DiffThreeWay(
+		nil,
+		[]*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "route", Plugins: types.Plugins{
+			"limit-count": map[string]interface{}{"count": 2},
+		}}}},
+		[]*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "route", Plugins: types.Plugins{
+			"limit-count": map[string]interface{}{"count": 1, "time_window": 60},
+			"cors":        map[string]interface{}{},
+		}}}},
+	)
+	assert.Nil(t, err, "should not return error")
+	assert.Len(t, events, 1)
+	plugins := events[0].Value.(*types.Route).Plugins
+	assert.Len(t, plugins, 2, "should keep the plugin enabled out of band")
+	assert.Equal(t, float64(2), plugins["limit-count"]["count"])
+	assert.Equal(t, float64(60), plugins["limit-count"]["time_window"], "should keep the field set out of band")
+
+	// Test case 5: only the resources last applied are deleted
+	events, err = DiffThreeWay(
+		[]*Event{{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc}},
+		[]*Event{{ResourceType: ConsumerResourceType, Option: CreateOption, Value: consumer}},
+		[]*Event{
+			{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+			{ResourceType: RouteResourceType, Option: CreateOption, Value: route},
+		},
+	)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []*Event{
+		{ResourceType: ConsumerResourceType, Option: CreateOption, Value: consumer},
+		{ResourceType: ServiceResourceType, Option: DeleteOption, OldValue: svc},
+	}, events, "should keep the route created out of band")
+}
@@ -0,0 +1,53 @@
+package data
+
+import (
+	"time"
+)
+
+// Metrics records the events applied by ApplyAllWithOptions, e.g. into
+// Prometheus counters and histograms labeled by resource type and option.
+// The calls may be concurrent.
+type Metrics interface {
+	// EventApplied is called after each event with the time it took to
+	// apply it, err is the error of applying the event, or nil on success.
+	// The option is "create", "update" or "delete": an upsert is recorded
+	// as the create or the update it amounts to, and a noop event as an
+	// update, see metricOption.
+	EventApplied(typ ResourceType, option string, duration time.Duration, err error)
+}
+
+// MetricsFunc is a function implementing Metrics.
+type MetricsFunc func(typ ResourceType, option string, duration time.Duration, err error)
+
+// EventApplied calls f.
+func (f MetricsFunc) EventApplied(typ ResourceType, option string, duration time.Duration, err error) {
+	f(typ, option, duration, err)
+}
+
+// startTimer returns the start time of applying an event, the clock is
+// only read if there are metrics to record or a logger.
+func (opts *ApplyOptions) startTimer() time.Time {
+	if opts.Metrics == nil && opts.Logger == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// observe records the event applied since start, if there are metrics.
+func (opts *ApplyOptions) observe(event *Event, start time.Time, err error) {
+	if opts.Metrics == nil {
+		return
+	}
+	opts.Metrics.EventApplied(event.ResourceType, metricOption(event), time.Since(start), err)
+}
+
+// metricOption returns the option of the event recorded by Metrics, so that
+// the options are always one of create, update and delete whatever the
+// options of ApplyAllWithOptions, e.g. UpsertCreates and Force.
+func metricOption(event *Event) string {
+	option := event.resolvedOption()
+	if option == NoOpOption {
+		option = UpdateOption
+	}
+	return option.String()
+}
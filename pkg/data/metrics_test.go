@@ -0,0 +1,103 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingMetrics counts the applied events like Prometheus counters.
+type recordingMetrics struct {
+	mu        sync.Mutex
+	applied   map[string]int
+	failures  map[string]int
+	durations map[ResourceType][]time.Duration
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{
+		applied:   map[string]int{},
+		failures:  map[string]int{},
+		durations: map[ResourceType][]time.Duration{},
+	}
+}
+
+func (m *recordingMetrics) EventApplied(typ ResourceType, option string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.applied[string(typ)+"/"+option]++
+	if err != nil {
+		m.failures[string(typ)+"/"+option]++
+	}
+	m.durations[typ] = append(m.durations[typ], duration)
+}
+
+func TestApplyAllMetrics(t *testing.T) {
+	// Test case 1: the events are recorded by type and option
+	cluster := newFakeCluster()
+	cluster.route.delay = 10 * time.Millisecond
+	cluster.service.items["svc"] = svc
+	metrics := newRecordingMetrics()
+	events := append(routeEvents(3), &Event{
+		ResourceType: ServiceResourceType,
+		Option:       UpdateOption,
+		OldValue:     svc,
+		Value:        svc,
+	})
+	err := ApplyAllWithOptions(context.Background(), cluster, events, ApplyOptions{
+		Concurrency: 2,
+		Metrics:     metrics,
+	})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, map[string]int{"route/create": 3, "service/update": 1}, metrics.applied)
+	assert.Empty(t, metrics.failures, "should not record failures")
+	assert.Len(t, metrics.durations[RouteResourceType], 3, "should record the duration of every route")
+	for _, duration := range metrics.durations[RouteResourceType] {
+		assert.GreaterOrEqual(t, duration, 10*time.Millisecond, "should measure the duration")
+	}
+
+	// Test case 2: the failures are recorded
+	cluster = newFakeCluster()
+	cluster.route.err = errors.New("unexpected status code 500")
+	metrics = newRecordingMetrics()
+	err = ApplyAllWithOptions(context.Background(), cluster, routeEvents(2), ApplyOptions{
+		ContinueOnError: true,
+		Metrics:         metrics,
+	})
+	assert.NotNil(t, err, "should return error")
+	assert.Equal(t, map[string]int{"route/create": 2}, metrics.failures)
+
+	// Test case 3: bulk requests record every event
+	bulk := &bulkCluster{fakeCluster: newFakeCluster()}
+	var applied int
+	err = ApplyAllWithOptions(context.Background(), bulk, routeEvents(4), ApplyOptions{
+		Bulk: true,
+		Metrics: MetricsFunc(func(typ ResourceType, option string, duration time.Duration, err error) {
+			assert.Equal(t, RouteResourceType, typ)
+			assert.Equal(t, "create", option)
+			applied++
+		}),
+	})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, 4, applied, "should record every event")
+
+	// Test case 4: upserts and noop events are recorded as creates or
+	// updates
+	cluster = newFakeCluster()
+	metrics = newRecordingMetrics()
+	events = []*Event{
+		{ResourceType: RouteResourceType, Option: UpsertOption, Value: route},
+		{ResourceType: ServiceResourceType, Option: UpsertOption, OldValue: svc, Value: svc},
+		{ResourceType: ServiceResourceType, Option: NoOpOption, OldValue: svc, Value: svc},
+	}
+	err = ApplyAllWithOptions(context.Background(), cluster, events, ApplyOptions{
+		Force:   true,
+		Metrics: metrics,
+	})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, map[string]int{"route/create": 1, "service/update": 2}, metrics.applied)
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"encoding/json"
+)
+
+// normalize converts the value to its generic JSON form, so that values
+// with the same JSON representation compare equal regardless of their Go
+// types, key order or omitted empty fields.
+func normalize(value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// canonicalRaw is the JSON of the value without the ignored top-level
+// fields, e.g. the ones managed by APISIX.
+func canonicalRaw(value interface{}, ignored []string) ([]byte, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return stripFields(raw, ignored)
+}
+
+// IsNoOp reports whether the event is a NoOpOption, or an update or an
+// upsert with the old value that changes nothing,
+// i.e. the old and new values are semantically identical: they are the
+// same in the canonical form of the resource type, regardless of the key
+// order, the empty fields, the default values and the order of unordered
+// arrays like hosts, see canonicalPair. DefaultIgnoredFields, managed by
+// APISIX, are ignored, and the id generated by APISIX too when the new
+// value doesn't set one.
+func (e *Event) IsNoOp() (bool, error) {
+	if e.Option == NoOpOption {
+		return true, nil
+	}
+	if e.resolvedOption() != UpdateOption {
+		return false, nil
+	}
+
+	ignored, err := e.ignoredFields(&OutputOptions{})
+	if err != nil {
+		return false, err
+	}
+	oldRaw, err := canonicalRaw(e.OldValue, ignored)
+	if err != nil {
+		return false, err
+	}
+	raw, err := canonicalRaw(e.Value, ignored)
+	if err != nil {
+		return false, err
+	}
+	oldValue, value, err := canonicalPair(e.ResourceType, oldRaw, raw)
+	if err != nil {
+		return false, err
+	}
+	return oldValue.equal(value), nil
+}
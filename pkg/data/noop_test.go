@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestEventIsNoOp(t *testing.T) {
+	// Test case 1: the same route
+	route1 := *route
+	event := &Event{
+		ResourceType: RouteResourceType,
+		Option:       UpdateOption,
+		OldValue:     route,
+		Value:        &route1,
+	}
+	noop, err := event.IsNoOp()
+	assert.Nil(t, err, "should not return error")
+	assert.True(t, noop, "should be a no-op")
+
+	// Test case 2: the remote value has a different key order
+	event = &Event{
+		ResourceType: ConsumerResourceType,
+		Option:       UpdateOption,
+		OldValue:     json.RawMessage(`{"plugins": {"key-auth": {"key": "auth-one"}}, "username": "jack"}`),
+		Value:        consumer,
+	}
+	noop, err = event.IsNoOp()
+	assert.Nil(t, err, "should not return error")
+	assert.True(t, noop, "JSON key order is not a change")
+
+	// Test case 3: nil and empty slices are both omitted
+	event = &Event{
+		ResourceType: ServiceResourceType,
+		Option:       UpdateOption,
+		OldValue:     &types.Service{ID: "svc", Name: "svc", Hosts: []string{}},
+		Value:        &types.Service{ID: "svc", Name: "svc"},
+	}
+	noop, err = event.IsNoOp()
+	assert.Nil(t, err, "should not return error")
+	assert.True(t, noop, "should be a no-op")
+
+	// Test case 4: the route is changed
+	route1.Uris = []string{"/post"}
+	event = &Event{
+		ResourceType: RouteResourceType,
+		Option:       UpdateOption,
+		OldValue:     route,
+		Value:        &route1,
+	}
+	noop, err = event.IsNoOp()
+	assert.Nil(t, err, "should not return error")
+	assert.False(t, noop, "should not be a no-op")
+
+	// Test case 5: create and delete are never no-op
+	noop, err = (&Event{ResourceType: RouteResourceType, Option: CreateOption, Value: route}).IsNoOp()
+	assert.Nil(t, err, "should not return error")
+	assert.False(t, noop, "create is not a no-op")
+}
+
+func TestApplySkipsNoOp(t *testing.T) {
+	cluster := newFakeCluster()
+	route1 := *route
+	event := &Event{
+		ResourceType: RouteResourceType,
+		Option:       UpdateOption,
+		OldValue:     route,
+		Value:        &route1,
+	}
+	assert.Nil(t, event.Apply(context.Background(), cluster), "should skip the event")
+	assert.Empty(t, cluster.route.calls, "should not call the admin API")
+}
+
+func TestEventIsNoOpCanonical(t *testing.T) {
+	// Test case 1: the fields managed by APISIX are ignored
+	event := &Event{
+		ResourceType: RouteResourceType,
+		Option:       UpdateOption,
+		OldValue:     json.RawMessage(`{"id": "route", "name": "route", "labels": {"label1": "v1", "label2": "v2"}, "methods": ["GET"], "uris": ["/get"], "service_id": "svc", "create_time": 1700000000, "update_time": 1700000001}`),
+		Value:        route,
+	}
+	noop, err := event.IsNoOp()
+	assert.Nil(t, err, "should not return error")
+	assert.True(t, noop, "the timestamps are not a change")
+
+	// Test case 2: empty and missing fields are the same
+	event = &Event{
+		ResourceType: ServiceResourceType,
+		Option:       UpdateOption,
+		OldValue:     json.RawMessage(`{"id": "svc", "name": "svc", "labels": {}, "hosts": [], "desc": "", "upstream": {"nodes": [{"host": "httpbin.org", "port": 80, "weight": 1}], "tls": null}}`),
+		Value: &types.Service{
+			ID:   "svc",
+			Name: "svc",
+			Upstream: &types.Upstream{
+				Nodes: types.UpstreamNodes{{Host: "httpbin.org", Port: 80, Weight: 1}},
+			},
+		},
+	}
+	noop, err = event.IsNoOp()
+	assert.Nil(t, err, "should not return error")
+	assert.True(t, noop, "empty fields are not a change")
+
+	// Test case 3: enabling a plugin without configuration is a change
+	event = &Event{
+		ResourceType: ServiceResourceType,
+		Option:       UpdateOption,
+		OldValue:     &types.Service{ID: "svc", Name: "svc"},
+		Value:        &types.Service{ID: "svc", Name: "svc", Plugins: types.Plugins{"prometheus": {}}},
+	}
+	noop, err = event.IsNoOp()
+	assert.Nil(t, err, "should not return error")
+	assert.False(t, noop, "should not be a no-op")
+}
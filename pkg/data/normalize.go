@@ -0,0 +1,356 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// defaultValues are the raw JSON values APISIX sets for some fields missing
+// in the resources of each type. They are the same as missing fields, so
+// they are removed before comparing resources.
+var defaultValues = map[ResourceType]map[string]string{
+	RouteResourceType: {
+		"priority": `0`,
+	},
+	SSLResourceType: {
+		"type": `"server"`,
+	},
+	UpstreamResourceType: upstreamDefaults,
+}
+
+// upstreamDefaults are the defaults of upstreams, including the ones inlined
+// in routes and services.
+var upstreamDefaults = map[string]string{
+	"type":      `"roundrobin"`,
+	"scheme":    `"http"`,
+	"pass_host": `"pass"`,
+	"hash_on":   `"vars"`,
+}
+
+// unorderedFields are the top-level arrays whose order doesn't matter to
+// APISIX, they are sorted before comparing resources.
+var unorderedFields = []string{
+	"hosts",
+	"methods",
+	"remote_addrs",
+	"snis",
+	"uris",
+}
+
+// jsonNode is a parsed JSON value keeping the order of the object keys.
+type jsonNode struct {
+	// keys and fields are set for objects
+	keys   []string
+	fields map[string]*jsonNode
+	// items are set for arrays
+	items []*jsonNode
+	array bool
+	// raw is set for the other values
+	raw json.RawMessage
+}
+
+func parseNode(raw []byte) (*jsonNode, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return &jsonNode{raw: json.RawMessage("null")}, nil
+	}
+
+	switch raw[0] {
+	case '{':
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, err
+		}
+		keys, err := objectKeys(raw)
+		if err != nil {
+			return nil, err
+		}
+		n := &jsonNode{keys: keys, fields: make(map[string]*jsonNode, len(keys))}
+		for _, key := range keys {
+			if n.fields[key], err = parseNode(obj[key]); err != nil {
+				return nil, err
+			}
+		}
+		return n, nil
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		n := &jsonNode{array: true, items: make([]*jsonNode, 0, len(items))}
+		for _, item := range items {
+			child, err := parseNode(item)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, child)
+		}
+		return n, nil
+	}
+	return &jsonNode{raw: raw}, nil
+}
+
+// objectKeys returns the keys of the raw JSON object in order, the
+// duplicated keys only once like json.Unmarshal.
+func objectKeys(raw []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	// consume the opening brace
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var keys []string
+	seen := make(map[string]bool)
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (n *jsonNode) isObject() bool {
+	return n.fields != nil
+}
+
+func (n *jsonNode) remove(key string) {
+	if _, ok := n.fields[key]; !ok {
+		return
+	}
+	delete(n.fields, key)
+	for i, k := range n.keys {
+		if k == key {
+			n.keys = append(n.keys[:i], n.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// isEmpty reports whether the value is null, an empty string, an empty
+// array or an empty object, which are the same as a missing field.
+func (n *jsonNode) isEmpty() bool {
+	switch {
+	case n.isObject():
+		return len(n.keys) == 0
+	case n.array:
+		return len(n.items) == 0
+	}
+	return string(n.raw) == "null" || string(n.raw) == `""`
+}
+
+// pruneEmpty removes the empty fields of the objects, recursively. The
+// fields of plugins objects are never removed, since enabling a plugin
+// without configuration is a change.
+func (n *jsonNode) pruneEmpty(keepFields bool) {
+	switch {
+	case n.isObject():
+		for _, key := range append([]string(nil), n.keys...) {
+			field := n.fields[key]
+			field.pruneEmpty(key == "plugins")
+			if !keepFields && field.isEmpty() {
+				n.remove(key)
+			}
+		}
+	case n.array:
+		for _, item := range n.items {
+			item.pruneEmpty(false)
+		}
+	}
+}
+
+// dropDefaults removes the fields of the object set to their defaults.
+func (n *jsonNode) dropDefaults(defaults map[string]string) {
+	for key, def := range defaults {
+		if field, ok := n.fields[key]; ok && field.raw != nil && scalarEqual(field.raw, json.RawMessage(def)) {
+			n.remove(key)
+		}
+	}
+}
+
+// sortStrings sorts the array if all its items are strings.
+func (n *jsonNode) sortStrings() {
+	values := make(map[*jsonNode]string, len(n.items))
+	for _, item := range n.items {
+		var s string
+		if item.raw == nil || json.Unmarshal(item.raw, &s) != nil {
+			return
+		}
+		values[item] = s
+	}
+	sort.SliceStable(n.items, func(i, j int) bool {
+		return values[n.items[i]] < values[n.items[j]]
+	})
+}
+
+// canonical rewrites the resource of the type, so that resources with the
+// same meaning are equal: the empty fields, see pruneEmpty, and the fields
+// set to their APISIX defaults are removed, the unordered arrays of strings
+// are sorted and the plugins are sorted by name, so that reordering them is
+// never shown as a change.
+func (n *jsonNode) canonical(typ ResourceType) {
+	n.pruneEmpty(false)
+	if !n.isObject() {
+		return
+	}
+
+	n.dropDefaults(defaultValues[typ])
+	if typ == RouteResourceType || typ == ServiceResourceType {
+		if upstream, ok := n.fields["upstream"]; ok && upstream.isObject() {
+			upstream.dropDefaults(upstreamDefaults)
+		}
+	}
+	for _, key := range unorderedFields {
+		if field, ok := n.fields[key]; ok && field.array {
+			field.sortStrings()
+		}
+	}
+	if plugins, ok := n.fields["plugins"]; ok && plugins.isObject() {
+		sort.Strings(plugins.keys)
+	}
+}
+
+// alignWith orders the keys of the objects like the keys of the other value,
+// recursively, so that the same fields are at the same place in both sides
+// of a diff. The keys missing in the other value are kept at the end, in
+// their order. The numbers equal to the other ones are written the same,
+// e.g. 2.0 and 2.
+func (n *jsonNode) alignWith(other *jsonNode) {
+	switch {
+	case n.isObject() && other.isObject():
+		keys := make([]string, 0, len(n.keys))
+		for _, key := range other.keys {
+			if field, ok := n.fields[key]; ok {
+				field.alignWith(other.fields[key])
+				keys = append(keys, key)
+			}
+		}
+		for _, key := range n.keys {
+			if _, ok := other.fields[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		n.keys = keys
+	case n.array && other.array:
+		for i := 0; i < len(n.items) && i < len(other.items); i++ {
+			n.items[i].alignWith(other.items[i])
+		}
+	case n.raw != nil && other.raw != nil && scalarEqual(n.raw, other.raw):
+		n.raw = other.raw
+	}
+}
+
+// equal reports whether the values are the same regardless of the order of
+// the object keys. Numbers are compared by value.
+func (n *jsonNode) equal(other *jsonNode) bool {
+	switch {
+	case n.isObject() || other.isObject():
+		if !n.isObject() || !other.isObject() || len(n.keys) != len(other.keys) {
+			return false
+		}
+		for key, field := range n.fields {
+			if otherField, ok := other.fields[key]; !ok || !field.equal(otherField) {
+				return false
+			}
+		}
+		return true
+	case n.array || other.array:
+		if !n.array || !other.array || len(n.items) != len(other.items) {
+			return false
+		}
+		for i := range n.items {
+			if !n.items[i].equal(other.items[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return scalarEqual(n.raw, other.raw)
+}
+
+func scalarEqual(a, b json.RawMessage) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	x, errX := strconv.ParseFloat(string(a), 64)
+	y, errY := strconv.ParseFloat(string(b), 64)
+	return errX == nil && errY == nil && x == y
+}
+
+// marshalIndent renders the value like json.MarshalIndent with tabs, in the
+// order of the keys.
+func (n *jsonNode) marshalIndent() []byte {
+	var out bytes.Buffer
+	n.write(&out, "")
+	return out.Bytes()
+}
+
+func (n *jsonNode) write(out *bytes.Buffer, indent string) {
+	switch {
+	case n.isObject():
+		if len(n.keys) == 0 {
+			out.WriteString("{}")
+			return
+		}
+		out.WriteString("{\n")
+		for i, key := range n.keys {
+			if i > 0 {
+				out.WriteString(",\n")
+			}
+			encodedKey, _ := json.Marshal(key)
+			out.WriteString(indent + "\t")
+			out.Write(encodedKey)
+			out.WriteString(": ")
+			n.fields[key].write(out, indent+"\t")
+		}
+		out.WriteString("\n" + indent + "}")
+	case n.array:
+		if len(n.items) == 0 {
+			out.WriteString("[]")
+			return
+		}
+		out.WriteString("[\n")
+		for i, item := range n.items {
+			if i > 0 {
+				out.WriteString(",\n")
+			}
+			out.WriteString(indent + "\t")
+			item.write(out, indent+"\t")
+		}
+		out.WriteString("\n" + indent + "]")
+	default:
+		out.Write(n.raw)
+	}
+}
+
+// canonicalPair parses both raw JSON values in the canonical form of the
+// resource type, with the old value aligned with the new one.
+// A missing old value stays nil.
+func canonicalPair(typ ResourceType, oldRaw, raw []byte) (oldValue, value *jsonNode, err error) {
+	value, err = parseNode(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	value.canonical(typ)
+	if oldRaw == nil {
+		return nil, value, nil
+	}
+
+	oldValue, err = parseNode(oldRaw)
+	if err != nil {
+		return nil, nil, err
+	}
+	oldValue.canonical(typ)
+	oldValue.alignWith(value)
+	return oldValue, value, nil
+}
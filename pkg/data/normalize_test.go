@@ -0,0 +1,111 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestOutputNormalized(t *testing.T) {
+	svc1 := &types.Service{
+		ID:    "svc",
+		Name:  "svc",
+		Hosts: []string{"a.example.com", "b.example.com"},
+		Plugins: types.Plugins{
+			"limit-count": {"count": 2, "time_window": 60},
+			"prometheus":  {},
+		},
+		Upstream: &types.Upstream{
+			Nodes: types.UpstreamNodes{{Host: "httpbin.org", Port: 80, Weight: 1}},
+		},
+	}
+
+	// Test case 1: the remote value only differs by the form
+	event := &Event{
+		ResourceType: ServiceResourceType,
+		Option:       UpdateOption,
+		OldValue: json.RawMessage(`{
+			"plugins": {"prometheus": {}, "limit-count": {"time_window": 60, "count": 2.0}},
+			"upstream": {"type": "roundrobin", "scheme": "http", "pass_host": "pass", "nodes": [{"weight": 1, "port": 80, "host": "httpbin.org"}]},
+			"labels": {},
+			"hosts": ["b.example.com", "a.example.com"],
+			"name": "svc",
+			"id": "svc",
+			"create_time": 1700000000
+		}`),
+		Value: svc1,
+	}
+	output, err := event.Output(false)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "updating service: \"svc\"\n", output, "should not show any change")
+	noop, err := event.IsNoOp()
+	assert.Nil(t, err, "should not return error")
+	assert.True(t, noop, "should be a no-op")
+
+	// Test case 2: only the real changes are shown
+	event.OldValue = json.RawMessage(`{"name": "svc", "id": "svc", "hosts": ["b.example.com", "a.example.com"], "plugins": {"limit-count": {"count": 1, "time_window": 60}, "prometheus": {}}, "upstream": {"nodes": [{"host": "httpbin.org", "port": 80, "weight": 1}]}}`)
+	output, err = event.OutputWithOptions(&OutputOptions{ContextLines: -1})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, `updating service: "svc"
+--- remote
++++ local
+@@ -10 +10 @@
+-			"count": 1,
++			"count": 2,
+`, output)
+
+	// Test case 3: the routes defaults
+	event = &Event{
+		ResourceType: RouteResourceType,
+		Option:       UpdateOption,
+		OldValue:     json.RawMessage(`{"id": "route", "name": "route", "priority": 0, "uris": ["/get"], "methods": ["POST", "GET"]}`),
+		Value:        &types.Route{ID: "route", Name: "route", Uris: []string{"/get"}, Methods: []string{"GET", "POST"}},
+	}
+	output, err = event.Output(false)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "updating route: \"route\"\n", output, "should not show any change")
+	event.OldValue = json.RawMessage(`{"id": "route", "name": "route", "priority": 10, "uris": ["/get"], "methods": ["POST", "GET"]}`)
+	output, err = event.OutputWithOptions(&OutputOptions{ContextLines: -1})
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, output, "-\t\"priority\": 10\n", "should show a priority which is not the default")
+}
+
+func TestOutputPluginOrder(t *testing.T) {
+	route1 := &types.Route{
+		ID:   "route",
+		Name: "route",
+		Uris: []string{"/get"},
+		Plugins: types.Plugins{
+			"cors":        {"allow_origins": "*"},
+			"limit-count": {"count": 2, "time_window": 60},
+			"prometheus":  {},
+		},
+	}
+	event := &Event{
+		ResourceType: RouteResourceType,
+		Option:       UpdateOption,
+		OldValue: json.RawMessage(`{
+			"id": "route", "name": "route", "uris": ["/get"],
+			"plugins": {"prometheus": {}, "limit-count": {"time_window": 60, "count": 2}, "cors": {"allow_origins": "*"}}
+		}`),
+		Value: route1,
+	}
+
+	// Test case 1: the reordered plugins are not a change
+	for _, opts := range []*OutputOptions{nil, {SplitPlugins: true}, {SideBySide: true}} {
+		output, err := event.OutputWithOptions(opts)
+		assert.Nil(t, err, "should not return error")
+		assert.Equal(t, "updating route: \"route\"\n", output, "should not show any change")
+	}
+
+	// Test case 2: the plugins are sorted by name on both sides
+	oldNode, node, err := canonicalPair(RouteResourceType,
+		[]byte(`{"plugins": {"prometheus": {}, "cors": {}}}`),
+		[]byte(`{"plugins": {"limit-count": {}, "cors": {}}}`))
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []string{"cors", "prometheus"}, oldNode.fields["plugins"].keys)
+	assert.Equal(t, []string{"cors", "limit-count"}, node.fields["plugins"].keys)
+}
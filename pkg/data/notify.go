@@ -0,0 +1,119 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// Notification is the report of a sync sent by WebhookNotifier.
+type Notification struct {
+	// Summary counts the applied events.
+	Summary Summary `json:"summary"`
+	// Failures are the failed events.
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// Failure is a failed event of a Notification.
+type Failure struct {
+	ResourceType ResourceType `json:"resource_type"`
+	Option       string       `json:"option"`
+	Name         string       `json:"name"`
+	Error        string       `json:"error"`
+}
+
+// NewNotification returns the notification of a sync, applied are the
+// events applied successfully, e.g. collected with ApplyOptions.Progress,
+// and err is the error of applying the others. Every EventError of err is
+// a failure, the other errors are failures without event.
+func NewNotification(applied []*Event, err error) *Notification {
+	n := &Notification{Summary: Summarize(applied)}
+	for _, err := range multierr.Errors(err) {
+		failure := Failure{Error: err.Error()}
+		var eventErr *EventError
+		if errors.As(err, &eventErr) {
+			failure.ResourceType = eventErr.Event.ResourceType
+			failure.Option = eventErr.Event.Option.String()
+			failure.Name = eventErr.Event.key()
+			failure.Error = eventErr.Err.Error()
+		}
+		n.Failures = append(n.Failures, failure)
+	}
+	return n
+}
+
+// WebhookNotifier posts the notifications to a webhook, e.g. of Slack or
+// Microsoft Teams.
+type WebhookNotifier struct {
+	// URL is the URL of the webhook.
+	URL string
+	// Template is the text/template of the request body, executed with the
+	// Notification. The "json" function encodes a value in JSON, e.g. for
+	// Slack:
+	//
+	//	{"text": {{ printf "adc synced: %s" .Summary.Counts | json }}}
+	//
+	// The body is the JSON of the notification if it is empty.
+	Template string
+	// Client sends the requests, a client with a 10s timeout if nil.
+	Client *http.Client
+}
+
+var notifyFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// body returns the request body of the notification.
+func (w *WebhookNotifier) body(n *Notification) ([]byte, error) {
+	if w.Template == "" {
+		return json.Marshal(n)
+	}
+	tmpl, err := template.New("webhook").Funcs(notifyFuncs).Parse(w.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, n); err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+	return body.Bytes(), nil
+}
+
+// Notify posts the notification to the webhook, an error is returned if it
+// doesn't respond with a 2xx status code. Callers should only warn about
+// the error, a failed notification doesn't fail the sync.
+func (w *WebhookNotifier) Notify(ctx context.Context, n *Notification) error {
+	body, err := w.body(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d from the webhook", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+)
+
+func TestNewNotification(t *testing.T) {
+	applied := routeEvents(2)
+	failed := &Event{ResourceType: ServiceResourceType, Option: DeleteOption, OldValue: svc}
+	n := NewNotification(applied, multierr.Combine(
+		&EventError{Event: failed, Err: errors.New("still in use")},
+		context.Canceled,
+	))
+	assert.Equal(t, Counts{Created: 2}, n.Summary.Counts)
+	assert.Equal(t, []Failure{
+		{ResourceType: ServiceResourceType, Option: "delete", Name: "svc", Error: "still in use"},
+		{Error: "context canceled"},
+	}, n.Failures)
+
+	// Test case 2: no failures
+	assert.Empty(t, NewNotification(applied, nil).Failures)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var (
+		body        string
+		contentType string
+		status      = http.StatusOK
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		contentType = r.Header.Get("Content-Type")
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	n := NewNotification(routeEvents(1), &EventError{
+		Event: &Event{ResourceType: ServiceResourceType, Option: DeleteOption, OldValue: svc},
+		Err:   errors.New(`still "in use"`),
+	})
+
+	// Test case 1: the notification is posted as JSON by default
+	notifier := &WebhookNotifier{URL: server.URL}
+	assert.Nil(t, notifier.Notify(context.Background(), n), "should not return error")
+	assert.Equal(t, "application/json", contentType)
+	assert.JSONEq(t, `{
+		"summary": {"created": 1, "updated": 0, "deleted": 0, "by_type": {"route": {"created": 1, "updated": 0, "deleted": 0}}},
+		"failures": [{"resource_type": "service", "option": "delete", "name": "svc", "error": "still \"in use\""}]
+	}`, body)
+
+	// Test case 2: templates
+	notifier.Template = `{"text": {{ printf "adc synced: %s" .Summary.Counts | json }}{{ range .Failures }}, "error": {{ json .Error }}{{ end }}}`
+	assert.Nil(t, notifier.Notify(context.Background(), n), "should not return error")
+	assert.Equal(t, `{"text": "adc synced: 1 created, 0 updated, 0 deleted", "error": "still \"in use\""}`, body)
+
+	notifier.Template = `{{ .Unknown }}`
+	assert.Contains(t, notifier.Notify(context.Background(), n).Error(), "invalid webhook template")
+
+	// Test case 3: the webhook fails
+	notifier.Template = ""
+	status = http.StatusForbidden
+	assert.EqualError(t, notifier.Notify(context.Background(), n), "unexpected status code 403 from the webhook")
+}
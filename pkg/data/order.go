@@ -0,0 +1,109 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+)
+
+var _orderIndex = -1
+
+func _order() int {
+	_orderIndex += 1
+	return _orderIndex
+}
+
+func _key(typ ResourceType, option Option) string {
+	return fmt.Sprintf("%s:%d", typ, option)
+}
+
+// order is the events order to ensure the data dependency. Higher takes priority
+// stream route requires: upstream, service.
+// service requires: upstream (shouldn't)
+// route requires: service, plugin config, consumer (soft require), upstream (shouldn't, use service instead), proto
+// consumer requires: consumer group
+// The dependent resources should be created/updated first but deleted later
+var order = map[string]int{
+	// secrets are referenced by plugins, so they are created first and deleted last
+	_key(SecretResourceType, DeleteOption): _order(),
+
+	// deletes are in the reverse order of creates
+	_key(ConsumerGroupResourceType, DeleteOption): _order(),
+	_key(ConsumerResourceType, DeleteOption):      _order(),
+	_key(PluginConfigResourceType, DeleteOption):  _order(),
+	_key(UpstreamResourceType, DeleteOption):      _order(),
+	_key(ServiceResourceType, DeleteOption):       _order(),
+	_key(StreamRouteResourceType, DeleteOption):   _order(),
+	_key(ProtoResourceType, DeleteOption):         _order(),
+	_key(RouteResourceType, DeleteOption):         _order(),
+
+	_key(RouteResourceType, UpdateOption):         _order(),
+	_key(ProtoResourceType, UpdateOption):         _order(),
+	_key(StreamRouteResourceType, UpdateOption):   _order(),
+	_key(ServiceResourceType, UpdateOption):       _order(),
+	_key(UpstreamResourceType, UpdateOption):      _order(),
+	_key(PluginConfigResourceType, UpdateOption):  _order(),
+	_key(ConsumerResourceType, UpdateOption):      _order(),
+	_key(ConsumerGroupResourceType, UpdateOption): _order(),
+
+	_key(RouteResourceType, CreateOption):         _order(),
+	_key(ProtoResourceType, CreateOption):         _order(),
+	_key(StreamRouteResourceType, CreateOption):   _order(),
+	_key(ServiceResourceType, CreateOption):       _order(),
+	_key(UpstreamResourceType, CreateOption):      _order(),
+	_key(PluginConfigResourceType, CreateOption):  _order(),
+	_key(ConsumerResourceType, CreateOption):      _order(),
+	_key(ConsumerGroupResourceType, CreateOption): _order(),
+
+	// no dependency
+	_key(SSLResourceType, DeleteOption):            _order(),
+	_key(SSLResourceType, CreateOption):            _order(),
+	_key(SSLResourceType, UpdateOption):            _order(),
+	_key(GlobalRuleResourceType, DeleteOption):     _order(),
+	_key(GlobalRuleResourceType, CreateOption):     _order(),
+	_key(GlobalRuleResourceType, UpdateOption):     _order(),
+	_key(PluginMetadataResourceType, DeleteOption): _order(),
+	_key(PluginMetadataResourceType, CreateOption): _order(),
+	_key(PluginMetadataResourceType, UpdateOption): _order(),
+
+	_key(SecretResourceType, UpdateOption): _order(),
+	_key(SecretResourceType, CreateOption): _order(),
+}
+
+// SortEvents returns the events sorted by their dependencies, higher priority
+// events will be executed first. Events of the same priority keep their
+// original order, so the result is deterministic.
+func SortEvents(events []*Event) []*Event {
+	sorted := make([]*Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].priority() > sorted[j].priority()
+	})
+	return sorted
+}
+
+// SortEventsByKey returns the events sorted like SortEvents, and events of
+// the same priority sorted by the unique key of their resources. The result
+// doesn't depend on the original order, so the printed plan is reproducible.
+func SortEventsByKey(events []*Event) []*Event {
+	sorted := make([]*Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		oi := sorted[i].priority()
+		oj := sorted[j].priority()
+		if oi != oj {
+			return oi > oj
+		}
+		return sorted[i].key() < sorted[j].key()
+	})
+	return sorted
+}
+
+// priority returns the priority of the event in order, upserts are applied
+// like creates since they may create the resources.
+func (e *Event) priority() int {
+	option := e.Option
+	if option == UpsertOption {
+		option = CreateOption
+	}
+	return order[_key(e.ResourceType, option)]
+}
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestSortEvents(t *testing.T) {
+	// Test case 1: dependencies are created first and deleted last
+	var events []*Event
+	for _, option := range []Option{DeleteOption, CreateOption} {
+		for _, typ := range []ResourceType{RouteResourceType, PluginConfigResourceType, ServiceResourceType, UpstreamResourceType} {
+			events = append(events, &Event{
+				ResourceType: typ,
+				Option:       option,
+			})
+		}
+	}
+
+	var got []string
+	for _, event := range SortEvents(events) {
+		got = append(got, _key(event.ResourceType, event.Option))
+	}
+	assert.Equal(t, []string{
+		_key(PluginConfigResourceType, CreateOption),
+		_key(UpstreamResourceType, CreateOption),
+		_key(ServiceResourceType, CreateOption),
+		_key(RouteResourceType, CreateOption),
+		_key(RouteResourceType, DeleteOption),
+		_key(ServiceResourceType, DeleteOption),
+		_key(UpstreamResourceType, DeleteOption),
+		_key(PluginConfigResourceType, DeleteOption),
+	}, got, "check the order of sorted events")
+	assert.Equal(t, RouteResourceType, events[0].ResourceType, "should not modify the given events")
+
+	// Test case 2: events of the same priority keep their order
+	events = []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "c"}},
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: &types.Service{ID: "svc"}},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "a"}},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "b"}},
+	}
+	sorted := SortEvents(events)
+	assert.Equal(t, []*Event{events[1], events[0], events[2], events[3]}, sorted, "should be stable")
+
+	// Test case 3: protos are created before the routes using them
+	sorted = SortEvents([]*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption},
+		{ResourceType: ProtoResourceType, Option: CreateOption},
+		{ResourceType: ProtoResourceType, Option: DeleteOption},
+		{ResourceType: RouteResourceType, Option: DeleteOption},
+	})
+	assert.Equal(t, []*Event{
+		{ResourceType: ProtoResourceType, Option: CreateOption},
+		{ResourceType: RouteResourceType, Option: CreateOption},
+		{ResourceType: RouteResourceType, Option: DeleteOption},
+		{ResourceType: ProtoResourceType, Option: DeleteOption},
+	}, sorted, "check the order of proto events")
+}
+
+func TestSortEventsDeletes(t *testing.T) {
+	dependent := []ResourceType{
+		RouteResourceType, ProtoResourceType, StreamRouteResourceType, ServiceResourceType,
+		UpstreamResourceType, PluginConfigResourceType, ConsumerResourceType, ConsumerGroupResourceType,
+		SecretResourceType,
+	}
+	// the creates and the deletes of a batch, interleaved
+	var events []*Event
+	for _, typ := range dependent {
+		events = append(events, &Event{ResourceType: typ, Option: DeleteOption}, &Event{ResourceType: typ, Option: CreateOption})
+	}
+
+	var creates, deletes []ResourceType
+	for _, event := range SortEvents(events) {
+		if event.Option == CreateOption {
+			assert.Empty(t, deletes, "should apply the creates before the deletes")
+			creates = append(creates, event.ResourceType)
+		} else {
+			deletes = append(deletes, event.ResourceType)
+		}
+	}
+
+	// Test case 1: dependencies are created first
+	assert.Equal(t, []ResourceType{
+		SecretResourceType, ConsumerGroupResourceType, ConsumerResourceType, PluginConfigResourceType,
+		UpstreamResourceType, ServiceResourceType, StreamRouteResourceType, ProtoResourceType,
+		RouteResourceType,
+	}, creates)
+
+	// Test case 2: and deleted in the reverse order
+	for i, typ := range creates {
+		assert.Equal(t, typ, deletes[len(deletes)-1-i], "should delete %s in the reverse order of creates", typ)
+	}
+}
+
+func TestSortEventsByKey(t *testing.T) {
+	events := []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "c"}},
+		{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: &types.Route{ID: "b"}},
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: &types.Service{ID: "svc"}},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "a"}},
+		{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: &types.Route{ID: "a"}},
+		{ResourceType: ConsumerResourceType, Option: CreateOption, Value: &types.Consumer{Username: "jack"}},
+	}
+	expected := []*Event{events[5], events[2], events[3], events[0], events[4], events[1]}
+	assert.Equal(t, expected, SortEventsByKey(events))
+
+	// Test case 2: the result doesn't depend on the original order
+	reversed := make([]*Event, len(events))
+	for i, event := range events {
+		reversed[len(events)-1-i] = event
+	}
+	assert.Equal(t, expected, SortEventsByKey(reversed))
+	assert.Equal(t, RouteResourceType, events[0].ResourceType, "should not modify the given events")
+}
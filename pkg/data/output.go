@@ -0,0 +1,241 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// OutputOptions are the options of Event.OutputWithOptions.
+type OutputOptions struct {
+	// DiffOnly prints the output of the diff command instead of the sync command.
+	DiffOnly bool
+	// Color wraps the added lines in green and the removed lines in red with
+	// ANSI escape codes. It should only be set when writing to a terminal,
+	// see ColorEnabled. It has no effect when NO_COLOR is set.
+	Color bool
+	// ContextLines is the number of unchanged lines around each change of
+	// update diffs. Zero means DefaultContextLines, negative means none.
+	ContextLines int
+	// RedactedFields are the sensitive fields masked in update diffs,
+	// nil means DefaultRedactedFields.
+	RedactedFields RedactedFields
+	// IgnoredFields are the top-level fields managed by APISIX that are
+	// removed from update diffs, nil means DefaultIgnoredFields. To extend
+	// the defaults, append to a copy of DefaultIgnoredFields.
+	IgnoredFields []string
+	// ShowBody prints the body of created resources as a diff against
+	// nothing below the title.
+	ShowBody bool
+	// SplitPlugins splits update diffs into the diff of the fields other
+	// than plugins, followed by a section for each changed plugin, so
+	// that a change isn't buried in the configuration of many plugins.
+	SplitPlugins bool
+	// SideBySide renders update diffs in two columns, the remote value on
+	// the left and the local one on the right, instead of unified diffs.
+	// The unified diff is still used if Width is too narrow.
+	SideBySide bool
+	// Width is the width of the terminal for side-by-side diffs, zero means
+	// DefaultWidth.
+	Width int
+}
+
+func (o *OutputOptions) ignoredFields() []string {
+	if o.IgnoredFields == nil {
+		return DefaultIgnoredFields
+	}
+	return o.IgnoredFields
+}
+
+func (o *OutputOptions) redactedFields() RedactedFields {
+	if o.RedactedFields == nil {
+		return DefaultRedactedFields
+	}
+	return o.RedactedFields
+}
+
+func (o *OutputOptions) contextLines() int {
+	switch {
+	case o.ContextLines == 0:
+		return DefaultContextLines
+	case o.ContextLines < 0:
+		return 0
+	}
+	return o.ContextLines
+}
+
+// ColorEnabled reports whether the output written to w should be colored,
+// that is w is a terminal and the NO_COLOR environment variable is not set.
+// See https://no-color.org.
+func ColorEnabled(w io.Writer) bool {
+	if noColor() {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func noColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
+)
+
+func paint(color, line string) string {
+	return color + line + ansiReset
+}
+
+// outputWriter writes the output of an event line by line, rendering the
+// changed sensitive values of the diffs with unmarkChanged and coloring the
+// lines like git if color is set. The first line is the title of the
+// event, the rest is the diff.
+type outputWriter struct {
+	w      io.Writer
+	option Option
+	color  bool
+	// line buffers the last line until its newline is written
+	line  []byte
+	title bool
+}
+
+func newOutputWriter(w io.Writer, option Option, color bool) *outputWriter {
+	return &outputWriter{w: w, option: option, color: color, title: true}
+}
+
+func (o *outputWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			o.line = append(o.line, p...)
+			break
+		}
+		o.line = append(o.line, p[:i]...)
+		if err := o.writeLine("\n"); err != nil {
+			return 0, err
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Flush writes the last line if it doesn't end with a newline.
+func (o *outputWriter) Flush() error {
+	if len(o.line) == 0 {
+		return nil
+	}
+	return o.writeLine("")
+}
+
+func (o *outputWriter) writeLine(end string) error {
+	line := unmarkChanged(string(o.line))
+	if o.color {
+		line = colorLine(o.option, o.title, line)
+	}
+	o.line, o.title = o.line[:0], false
+	_, err := io.WriteString(o.w, line+end)
+	return err
+}
+
+// colorLine colors the line of the output like git, the title of the event
+// or a line of the unified diff of updates.
+func colorLine(option Option, title bool, line string) string {
+	if line == "" {
+		return line
+	}
+	if title {
+		switch option {
+		case CreateOption:
+			return paint(ansiGreen, line)
+		case DeleteOption:
+			return paint(ansiRed, line)
+		}
+		return line
+	}
+
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		// the file headers of the diff
+	case strings.HasPrefix(line, "@@"):
+		return paint(ansiCyan, line)
+	case strings.HasPrefix(line, "+"):
+		return paint(ansiGreen, line)
+	case strings.HasPrefix(line, "-"):
+		return paint(ansiRed, line)
+	}
+	return line
+}
+
+// splitPluginsDiff returns the function writing the update diff of the
+// fields other than plugins, followed by a "plugin "name":" section with
+// the diff of each changed plugin, in the order of the plugins of the new
+// value, then the removed ones. The top-level diff is omitted if only
+// plugins changed.
+func splitPluginsDiff(typ ResourceType, oldValue, value interface{}, opts *OutputOptions, ignored []string) (func(w io.Writer) error, error) {
+	if _, ok := value.(*types.Proto); ok {
+		remote, local, err := marshalUpdate(typ, oldValue, value, opts.redactedFields(), ignored)
+		return func(w io.Writer) error {
+			return writeDiff(w, remote, local, opts)
+		}, err
+	}
+	oldNode, node, err := updateNodes(typ, oldValue, value, opts.redactedFields(), ignored)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPlugins, plugins := detachPlugins(oldNode), detachPlugins(node)
+	names := append([]string(nil), plugins.keys...)
+	for _, name := range oldPlugins.keys {
+		if _, ok := plugins.fields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	return func(w io.Writer) error {
+		if oldNode == nil || !oldNode.equal(node) {
+			remote, local := marshalNodes(oldNode, node)
+			if err := writeDiff(w, remote, local, opts); err != nil {
+				return err
+			}
+		}
+		for _, name := range names {
+			oldPlugin, plugin := oldPlugins.fields[name], plugins.fields[name]
+			if oldPlugin != nil && plugin != nil && oldPlugin.equal(plugin) {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "plugin \"%s\":\n", name); err != nil {
+				return err
+			}
+			remote, local := marshalNodes(oldPlugin, plugin)
+			if err := writeDiff(w, remote, local, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// detachPlugins removes the plugins from the node and returns them, an
+// empty object if there are none.
+func detachPlugins(node *jsonNode) *jsonNode {
+	empty := &jsonNode{fields: map[string]*jsonNode{}}
+	if node == nil || !node.isObject() {
+		return empty
+	}
+	plugins, ok := node.fields["plugins"]
+	node.remove("plugins")
+	if !ok || !plugins.isObject() {
+		return empty
+	}
+	return plugins
+}
@@ -0,0 +1,380 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestOutputWithOptions(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	svc1 := *svc
+	svc1.Hosts = []string{"svc1.example.com"}
+	update := &Event{
+		ResourceType: ServiceResourceType,
+		Option:       UpdateOption,
+		OldValue:     svc,
+		Value:        &svc1,
+	}
+
+	// Test case 1: plain output is the same as Output
+	plain, err := update.OutputWithOptions(nil)
+	assert.Nil(t, err, "should not return error")
+	output, err := update.Output(false)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, output, plain)
+	assert.NotContains(t, plain, "\x1b[", "should not contain ANSI codes")
+
+	// Test case 2: colored update diff
+	colored, err := update.OutputWithOptions(&OutputOptions{Color: true})
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, colored, "updating service: \"svc\"\n--- remote\n+++ local\n\x1b[36m@@", "should not color the title and headers")
+	assert.Contains(t, colored, "\x1b[31m-\t\t\"svc.example.com\"\x1b[0m\n", "should color the removed line in red")
+	assert.Contains(t, colored, "\x1b[32m+\t\t\"svc1.example.com\"\x1b[0m\n", "should color the added line in green")
+	assert.Contains(t, colored, "\n \t\"hosts\": [\n", "should not color the context lines")
+
+	// Test case 3: colored create and delete
+	colored, err = (&Event{ResourceType: RouteResourceType, Option: CreateOption, Value: route}).OutputWithOptions(&OutputOptions{DiffOnly: true, Color: true})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "\x1b[32m+++ route: \"route\"\x1b[0m", colored)
+	colored, err = (&Event{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: route}).OutputWithOptions(&OutputOptions{Color: true})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "\x1b[31mdeleting route: \"route\"\x1b[0m", colored)
+}
+
+func TestOutputContextLines(t *testing.T) {
+	route1 := *route
+	route1.Methods = []string{http.MethodPost}
+	route1.Uris = []string{"/post"}
+	route1.Labels = map[string]string{
+		"label1": "v1",
+		"label2": "v3",
+	}
+	route1.ServiceID = "svc1"
+	event := &Event{
+		ResourceType: RouteResourceType,
+		Option:       UpdateOption,
+		OldValue:     route,
+		Value:        &route1,
+	}
+
+	// Test case 1: the default is the same as gotextdiff.ToUnified
+	remote, _ := json.MarshalIndent(route, "", "\t")
+	local, _ := json.MarshalIndent(&route1, "", "\t")
+	remote, local = append(remote, '\n'), append(local, '\n')
+	edits := myers.ComputeEdits(span.URIFromPath("remote"), string(remote), string(local))
+	expected := fmt.Sprint(gotextdiff.ToUnified("remote", "local", string(remote), edits))
+	output, err := event.OutputWithOptions(&OutputOptions{})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "updating route: \"route\"\n"+expected, output)
+
+	// Test case 2: no context lines
+	output, err = event.OutputWithOptions(&OutputOptions{ContextLines: -1})
+	assert.Nil(t, err, "should not return error")
+	for _, line := range strings.Split(output, "\n")[3:] {
+		if line == "" || strings.HasPrefix(line, "@@") {
+			continue
+		}
+		assert.Regexp(t, `^[+-]`, line, "should only contain changed lines")
+	}
+
+	// Test case 3: more context lines merge the hunks
+	output, err = event.OutputWithOptions(&OutputOptions{ContextLines: 1})
+	assert.Nil(t, err, "should not return error")
+	short := strings.Count(output, "\n")
+	output, err = event.OutputWithOptions(&OutputOptions{ContextLines: 100})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, 1, strings.Count(output, "@@ "), "should be a single hunk")
+	assert.Greater(t, strings.Count(output, "\n"), short, "should contain more lines")
+}
+
+func TestOutputIgnoredFields(t *testing.T) {
+	// remoteRoute is a route dumped with the server-managed fields
+	type remoteRoute struct {
+		types.Route
+		CreateTime int64 `json:"create_time"`
+		UpdateTime int64 `json:"update_time"`
+		Status     int   `json:"status,omitempty"`
+	}
+	remote := &remoteRoute{
+		Route: types.Route{
+			ID:   "route",
+			Name: "route",
+			Uris: []string{"/get"},
+		},
+		CreateTime: 1700000000,
+		UpdateTime: 1700000001,
+		Status:     1,
+	}
+	event := &Event{
+		ResourceType: RouteResourceType,
+		Option:       UpdateOption,
+		OldValue:     remote,
+		Value: &types.Route{
+			Name: "route",
+			Uris: []string{"/get"},
+		},
+	}
+
+	// Test case 1: the server-managed fields and the generated id are ignored
+	output, err := event.OutputWithOptions(&OutputOptions{
+		// extend the defaults
+		IgnoredFields: append(append([]string{}, DefaultIgnoredFields...), "status"),
+	})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "updating route: \"\"\n", output)
+
+	// Test case 2: the id set by the user is kept
+	event.Value = &types.Route{
+		ID:   "route1",
+		Name: "route",
+		Uris: []string{"/get"},
+	}
+	output, err = event.OutputWithOptions(nil)
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, output, "-\t\"id\": \"route\",")
+	assert.Contains(t, output, "+\t\"id\": \"route1\",")
+	assert.Contains(t, output, "-\t\"status\": 1")
+	assert.NotContains(t, output, "create_time")
+
+	// Test case 3: an empty list ignores nothing
+	output, err = event.OutputWithOptions(&OutputOptions{IgnoredFields: []string{}})
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, output, "-\t\"create_time\": 1700000000,")
+}
+
+func TestOutputShowBody(t *testing.T) {
+	event := &Event{
+		ResourceType: ConsumerResourceType,
+		Option:       CreateOption,
+		Value:        consumer,
+	}
+
+	// Test case 1: terse by default
+	output, err := event.OutputWithOptions(nil)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "creating consumer: \"jack\"", output)
+
+	// Test case 2: the body is a diff against nothing
+	output, err = event.OutputWithOptions(&OutputOptions{ShowBody: true})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, `creating consumer: "jack"
+--- remote
++++ local
+@@ -1 +1,8 @@
++{
++	"username": "jack",
++	"plugins": {
++		"key-auth": {
++			"key": "***"
++		}
++	}
++}
+`, output)
+
+	// Test case 3: the diff command
+	output, err = event.OutputWithOptions(&OutputOptions{DiffOnly: true, ShowBody: true})
+	assert.Nil(t, err, "should not return error")
+	assert.True(t, strings.HasPrefix(output, "+++ consumer: \"jack\"\n--- remote\n"), output)
+}
+
+func TestOutputNoColor(t *testing.T) {
+	event := &Event{ResourceType: RouteResourceType, Option: CreateOption, Value: route}
+
+	// Test case 1: NO_COLOR disables the colors
+	t.Setenv("NO_COLOR", "1")
+	output, err := event.OutputWithOptions(&OutputOptions{Color: true})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "creating route: \"route\"", output)
+	assert.False(t, ColorEnabled(os.Stdout), "should be disabled by NO_COLOR")
+
+	// Test case 2: a buffer is not a terminal
+	t.Setenv("NO_COLOR", "")
+	assert.False(t, ColorEnabled(&bytes.Buffer{}), "should be disabled for non terminals")
+
+	// Test case 3: a regular file is not a terminal
+	f, err := os.CreateTemp(t.TempDir(), "output")
+	assert.Nil(t, err, "should not return error")
+	defer f.Close()
+	assert.False(t, ColorEnabled(f), "should be disabled for regular files")
+}
+
+func TestOutputNilOldValue(t *testing.T) {
+	for _, oldValue := range []interface{}{nil, (*types.Consumer)(nil)} {
+		event := &Event{
+			ResourceType: ConsumerResourceType,
+			Option:       UpdateOption,
+			OldValue:     oldValue,
+			Value:        consumer,
+		}
+		output, err := event.Output(false)
+		assert.Nil(t, err, "should not return error")
+		assert.Equal(t, `updating consumer: "jack"
+--- remote
++++ local
+@@ -1 +1,8 @@
++{
++	"username": "jack",
++	"plugins": {
++		"key-auth": {
++			"key": "***"
++		}
++	}
++}
+`, output)
+		assert.NotContains(t, output, "null")
+	}
+}
+
+func TestSplitPlugins(t *testing.T) {
+	route0 := *route
+	route0.Plugins = types.Plugins{
+		"cors":       types.Plugin{"allow_origins": "*"},
+		"proxy-mock": types.Plugin{"body": "old"},
+		"echo":       types.Plugin{"body": "echo"},
+	}
+	route1 := route0
+	route1.Description = "new"
+	route1.Plugins = types.Plugins{
+		"cors":         types.Plugin{"allow_origins": "*"},
+		"proxy-mock":   types.Plugin{"body": "new"},
+		"response-add": types.Plugin{"body": "added"},
+	}
+	event := &Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: &route0, Value: &route1}
+
+	// Test case 1: the top-level diff, then the changed plugins
+	output, err := event.OutputWithOptions(&OutputOptions{DiffOnly: true, SplitPlugins: true, ContextLines: -1})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, `update route: "route"
+--- remote
++++ local
+@@ -8 +8 @@
++	"desc": "new",
+plugin "proxy-mock":
+--- remote
++++ local
+@@ -2 +2 @@
+-	"body": "old"
++	"body": "new"
+plugin "response-add":
+--- remote
++++ local
+@@ -1 +1,3 @@
++{
++	"body": "added"
++}
+plugin "echo":
+--- remote
++++ local
+@@ -1,3 +1 @@
+-{
+-	"body": "echo"
+-}
+`, output)
+
+	// Test case 2: only plugins changed
+	route1.Description = ""
+	output, err = event.OutputWithOptions(&OutputOptions{DiffOnly: true, SplitPlugins: true})
+	assert.Nil(t, err, "should not return error")
+	assert.True(t, strings.HasPrefix(output, "update route: \"route\"\nplugin \"proxy-mock\":\n"), "should omit the top-level diff, got %s", output)
+	assert.NotContains(t, output, "cors", "should omit the unchanged plugins")
+}
+
+func TestOutputSideBySide(t *testing.T) {
+	route1 := *route
+	route1.Description = "new"
+	route1.Uris = []string{"/get", "/anything/with/a/path/longer/than/the/column"}
+	event := &Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: &route1}
+
+	// Test case 1: the added lines are on the right, long lines are truncated
+	output, err := event.OutputWithOptions(&OutputOptions{SideBySide: true, Width: 83, ContextLines: 1})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, `updating route: "route"
+remote                                     local
+@@ -7 +7 @@
+  },                                         },
+                                         >   "desc": "new",
+  "uris": [                                  "uris": [
+                                         >     "/anything/with/a/path/longer/than/…
+    "/get"                                     "/get"
+`, output)
+
+	// Test case 2: unified diffs in narrow terminals
+	output, err = event.OutputWithOptions(&OutputOptions{SideBySide: true, Width: 40})
+	assert.Nil(t, err, "should not return error")
+	unified, err := event.Output(false)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, unified, output)
+
+	// Test case 3: the changed lines
+	route2 := route1
+	route2.Description = "newer"
+	event = &Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: &route1, Value: &route2}
+	output, err = event.OutputWithOptions(&OutputOptions{SideBySide: true, Width: 83, ContextLines: -1})
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, output, "\n  \"desc\": \"new\",                         |   \"desc\": \"newer\",\n")
+}
+
+// errWriter fails every write.
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestWriteOutput(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	route1 := *route
+	route1.Uris = []string{"/anything"}
+	route1.Plugins = types.Plugins{"proxy-mock": map[string]interface{}{"delay": 1}}
+	events := []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: route},
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: &route1},
+		{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: route},
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: route},
+	}
+
+	// Test case 1: the same output as OutputWithOptions
+	for _, opts := range []*OutputOptions{
+		nil,
+		{DiffOnly: true, Color: true, ShowBody: true},
+		{SplitPlugins: true, Color: true},
+		{SideBySide: true, Width: 100},
+	} {
+		for _, event := range events {
+			output, err := event.OutputWithOptions(opts)
+			assert.Nil(t, err, "should not return error")
+			var buf bytes.Buffer
+			assert.Nil(t, event.WriteOutputWithOptions(&buf, opts), "should not return error")
+			assert.Equal(t, output, buf.String())
+		}
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, events[1].WriteOutput(&buf), "should not return error")
+	output, err := events[1].Output(false)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, output, buf.String())
+
+	// Test case 2: the errors of the writer
+	assert.EqualError(t, events[1].WriteOutput(errWriter{}), "broken pipe")
+	assert.EqualError(t, events[1].WriteOutputWithOptions(errWriter{}, &OutputOptions{SideBySide: true}), "broken pipe")
+	assert.EqualError(t, events[2].WriteOutput(errWriter{}), "broken pipe")
+
+	// Test case 3: nothing is written if the diff fails
+	buf.Reset()
+	invalid := &Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: make(chan int)}
+	assert.NotNil(t, invalid.WriteOutput(&buf), "should return error")
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,142 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// PatchOperation is an operation of a JSON Patch (RFC 6902).
+type PatchOperation struct {
+	// Op is "add", "remove" or "replace".
+	Op string `json:"op"`
+	// Path is the JSON pointer (RFC 6901) of the changed field.
+	Path string `json:"path"`
+	// Value is the new value, it is omitted for removes.
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch returns the JSON Patch (RFC 6902) turning the old value of the
+// event into the new one. Like FieldDiff, creates and deletes are compared
+// with an empty object and the values are not redacted, the patch of noops
+// is empty. The fields managed
+// by APISIX, see DefaultIgnoredFields, are left out of the patch, so that
+// it can be applied to a resource dumped from the cluster. The operations
+// are sorted by path, except the removes of array items which go from the
+// last item, so that the patch can be applied in order.
+func (e *Event) JSONPatch() ([]byte, error) {
+	ops, err := e.patch()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ops)
+}
+
+func (e *Event) patch() ([]PatchOperation, error) {
+	if e.Option == NoOpOption {
+		return []PatchOperation{}, nil
+	}
+	ignored, err := e.ignoredFields(&OutputOptions{})
+	if err != nil {
+		return nil, err
+	}
+	oldValue, err := patchValue(e.OldValue, ignored)
+	if err != nil {
+		return nil, err
+	}
+	value, err := patchValue(e.Value, ignored)
+	if err != nil {
+		return nil, err
+	}
+	return patchValues("", oldValue, value, []PatchOperation{})
+}
+
+// patchValue returns the generic JSON value without the ignored top-level
+// fields, an empty object for a nil value. The numbers are kept as is.
+func patchValue(value interface{}, ignored []string) (interface{}, error) {
+	if isNil(value) {
+		return map[string]interface{}{}, nil
+	}
+	raw, err := canonicalRaw(value, ignored)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// patchValues appends the operations turning the old generic JSON value
+// into the new one.
+func patchValues(path string, oldValue, value interface{}, ops []PatchOperation) ([]PatchOperation, error) {
+	var err error
+	switch {
+	case isObject(oldValue) && isObject(value):
+		oldObj, obj := oldValue.(map[string]interface{}), value.(map[string]interface{})
+		keys := make([]string, 0, len(oldObj)+len(obj))
+		for k := range oldObj {
+			keys = append(keys, k)
+		}
+		for k := range obj {
+			if _, ok := oldObj[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fieldPath := path + "/" + escapePointer(k)
+			o, inOld := oldObj[k]
+			n, inNew := obj[k]
+			switch {
+			case !inOld:
+				ops, err = appendPatch(ops, "add", fieldPath, n)
+			case !inNew:
+				ops, err = appendPatch(ops, "remove", fieldPath, nil)
+			default:
+				ops, err = patchValues(fieldPath, o, n, ops)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	case isArray(oldValue) && isArray(value):
+		oldArr, arr := oldValue.([]interface{}), value.([]interface{})
+		for i := 0; i < len(oldArr) && i < len(arr); i++ {
+			if ops, err = patchValues(path+"/"+strconv.Itoa(i), oldArr[i], arr[i], ops); err != nil {
+				return nil, err
+			}
+		}
+		for i := len(oldArr); i < len(arr); i++ {
+			if ops, err = appendPatch(ops, "add", path+"/"+strconv.Itoa(i), arr[i]); err != nil {
+				return nil, err
+			}
+		}
+		// remove from the last item, the indexes shift after each remove
+		for i := len(oldArr) - 1; i >= len(arr); i-- {
+			if ops, err = appendPatch(ops, "remove", path+"/"+strconv.Itoa(i), nil); err != nil {
+				return nil, err
+			}
+		}
+	case !reflect.DeepEqual(oldValue, value):
+		return appendPatch(ops, "replace", path, value)
+	}
+	return ops, nil
+}
+
+func appendPatch(ops []PatchOperation, op, path string, value interface{}) ([]PatchOperation, error) {
+	operation := PatchOperation{Op: op, Path: path}
+	if op != "remove" {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		operation.Value = raw
+	}
+	return append(ops, operation), nil
+}
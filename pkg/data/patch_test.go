@@ -0,0 +1,74 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestEventJSONPatch(t *testing.T) {
+	// Test case 1: add, remove and replace
+	event := &Event{
+		ResourceType: RouteResourceType,
+		Option:       UpdateOption,
+		OldValue: json.RawMessage(`{
+			"id": "route",
+			"name": "route",
+			"desc": "old",
+			"uris": ["/get", "/anything", "/status"],
+			"enable_websocket": true,
+			"plugins": {"key-auth": {}},
+			"create_time": 1700000000
+		}`),
+		Value: &types.Route{
+			ID:              "route",
+			Name:            "route",
+			Uris:            []string{"/get"},
+			EnableWebsocket: false,
+			Labels:          types.Labels{"team/name": "payments"},
+			Plugins:         types.Plugins{"key-auth": {"header": "apikey"}},
+		},
+	}
+	patch, err := event.JSONPatch()
+	assert.Nil(t, err, "should not return error")
+	assert.JSONEq(t, `[
+		{"op": "remove", "path": "/desc"},
+		{"op": "remove", "path": "/enable_websocket"},
+		{"op": "add", "path": "/labels", "value": {"team/name": "payments"}},
+		{"op": "add", "path": "/plugins/key-auth/header", "value": "apikey"},
+		{"op": "remove", "path": "/uris/2"},
+		{"op": "remove", "path": "/uris/1"}
+	]`, string(patch))
+
+	// Test case 2: values set to false, zero or null are kept
+	event = &Event{
+		ResourceType: ServiceResourceType,
+		Option:       UpdateOption,
+		OldValue:     json.RawMessage(`{"id": "svc", "name": "svc", "enable_websocket": true, "hosts": ["a.example.com"]}`),
+		Value:        json.RawMessage(`{"id": "svc", "name": "svc", "enable_websocket": false, "hosts": ["a.example.com", "b.example.com"], "desc": null, "priority": 0}`),
+	}
+	patch, err = event.JSONPatch()
+	assert.Nil(t, err, "should not return error")
+	assert.JSONEq(t, `[
+		{"op": "add", "path": "/desc", "value": null},
+		{"op": "replace", "path": "/enable_websocket", "value": false},
+		{"op": "add", "path": "/hosts/1", "value": "b.example.com"},
+		{"op": "add", "path": "/priority", "value": 0}
+	]`, string(patch))
+
+	// Test case 3: no changes
+	patch, err = (&Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: route}).JSONPatch()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "[]", string(patch))
+
+	// Test case 4: creates are compared with an empty object
+	patch, err = (&Event{ResourceType: ConsumerResourceType, Option: CreateOption, Value: consumer}).JSONPatch()
+	assert.Nil(t, err, "should not return error")
+	assert.JSONEq(t, `[
+		{"op": "add", "path": "/plugins", "value": {"key-auth": {"key": "auth-one"}}},
+		{"op": "add", "path": "/username", "value": "jack"}
+	]`, string(patch), "should not redact the values")
+}
@@ -0,0 +1,143 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PlanFormatVersion is the version of the plan document, it's increased on
+// incompatible changes of the format.
+const PlanFormatVersion = 1
+
+// PlanDocument is the machine-readable plan of a batch of events, e.g.
+//
+//	{
+//	  "format_version": 1,
+//	  "actions": [
+//	    {
+//	      "resource_type": "route",
+//	      "name": "route1",
+//	      "action": "update",
+//	      "before": {"id": "route1", "uris": ["/get"]},
+//	      "after": {"id": "route1", "uris": ["/anything"]}
+//	    }
+//	  ],
+//	  "summary": {"created": 0, "updated": 1, "deleted": 0, "by_type": {"route": {...}}}
+//	}
+type PlanDocument struct {
+	FormatVersion int `json:"format_version"`
+	// Actions are in the order they are applied, see SortEventsByKey.
+	Actions []PlanAction `json:"actions"`
+	Summary Summary      `json:"summary"`
+}
+
+// PlanAction is a planned change of a resource.
+type PlanAction struct {
+	ResourceType ResourceType `json:"resource_type"`
+	Name         string       `json:"name"`
+	// Action is one of "create", "update" and "delete".
+	Action string `json:"action"`
+	// Before is the resource in the cluster, null for creates.
+	Before interface{} `json:"before"`
+	// After is the resource in the configuration, null for deletes.
+	After interface{} `json:"after"`
+}
+
+// NewPlan returns the plan of the events. Updates that change nothing are
+// omitted and sensitive fields are redacted like in Output.
+func NewPlan(events []*Event) (*PlanDocument, error) {
+	plan := &PlanDocument{
+		FormatVersion: PlanFormatVersion,
+		Actions:       []PlanAction{},
+	}
+
+	var planned []*Event
+	for _, event := range SortEventsByKey(events) {
+		noop, err := event.IsNoOp()
+		if err != nil {
+			return nil, err
+		}
+		if noop {
+			continue
+		}
+
+		name, err := event.resourceKey()
+		if err != nil {
+			return nil, err
+		}
+		action := PlanAction{
+			ResourceType: event.ResourceType,
+			Name:         name,
+			Action:       event.Option.String(),
+		}
+		if event.Option != CreateOption && !isNil(event.OldValue) {
+			if action.Before, err = normalizeRedacted(event.ResourceType, event.OldValue); err != nil {
+				return nil, err
+			}
+		}
+		if event.Option != DeleteOption {
+			if action.After, err = normalizeRedacted(event.ResourceType, event.Value); err != nil {
+				return nil, err
+			}
+		}
+		plan.Actions = append(plan.Actions, action)
+		planned = append(planned, event)
+	}
+	plan.Summary = Summarize(planned)
+	return plan, nil
+}
+
+// Plan returns the JSON encoded plan of the events, see PlanDocument for
+// the format. The result is deterministic.
+func Plan(events []*Event) ([]byte, error) {
+	plan, err := NewPlan(events)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(plan, "", "  ")
+}
+
+// pluralName returns the plural of the resource type for humans, e.g.
+// "global rules" for global_rule.
+func pluralName(typ ResourceType) string {
+	name := strings.ReplaceAll(string(typ), "_", " ")
+	if typ == PluginMetadataResourceType {
+		return name
+	}
+	return name + "s"
+}
+
+// FormatPlan returns the outputs of the events in the order they are applied,
+// see SortEventsByKey, under a header for each phase, e.g.
+//
+//	# Phase 1: create services
+//	+++ service: "svc1"
+//	# Phase 2: delete routes
+//	--- route: "route1"
+//
+// The creates and updates of the dependencies come before the ones of their
+// dependents, e.g. a service before its routes, and the deletes come last in
+// the reverse order, e.g. a route before its service. A nil opts is the
+// output of the diff command, see OutputWithOptions.
+func FormatPlan(events []*Event, opts *OutputOptions) (string, error) {
+	if opts == nil {
+		opts = &OutputOptions{DiffOnly: true}
+	}
+
+	var out strings.Builder
+	for i, phase := range phases(SortEventsByKey(events)) {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "# Phase %d: %s %s", i+1, phase[0].Option, pluralName(phase[0].ResourceType))
+		for _, event := range phase {
+			output, err := event.OutputWithOptions(opts)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString("\n" + strings.TrimSuffix(output, "\n"))
+		}
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestPlan(t *testing.T) {
+	route1 := *route
+	route1.Uris = []string{"/anything"}
+	events := []*Event{
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: &route1},
+		{ResourceType: ConsumerResourceType, Option: DeleteOption, OldValue: consumer},
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		// no-op updates are omitted
+		{ResourceType: ServiceResourceType, Option: UpdateOption, OldValue: &types.Service{ID: "svc2"}, Value: &types.Service{ID: "svc2"}},
+	}
+
+	out, err := Plan(events)
+	assert.Nil(t, err, "should not return error")
+	var plan PlanDocument
+	assert.Nil(t, json.Unmarshal(out, &plan), "should be valid JSON")
+
+	assert.Equal(t, PlanFormatVersion, plan.FormatVersion)
+	var actions []string
+	for _, action := range plan.Actions {
+		actions = append(actions, action.Action+" "+string(action.ResourceType)+" "+action.Name)
+	}
+	assert.Equal(t, []string{"create service svc", "update route route", "delete consumer jack"}, actions)
+	assert.Equal(t, Counts{Created: 1, Updated: 1, Deleted: 1}, plan.Summary.Counts)
+
+	// Test case 2: before and after objects
+	assert.Nil(t, plan.Actions[0].Before, "should be null for creates")
+	assert.Equal(t, "svc", plan.Actions[0].After.(map[string]interface{})["id"])
+	assert.Equal(t, []interface{}{"/get"}, plan.Actions[1].Before.(map[string]interface{})["uris"])
+	assert.Equal(t, []interface{}{"/anything"}, plan.Actions[1].After.(map[string]interface{})["uris"])
+	assert.Nil(t, plan.Actions[2].After, "should be null for deletes")
+
+	// Test case 3: sensitive fields are redacted
+	assert.Equal(t, map[string]interface{}{
+		"key-auth": map[string]interface{}{"key": "***"},
+	}, plan.Actions[2].Before.(map[string]interface{})["plugins"])
+
+	// Test case 4: deterministic
+	again, err := Plan([]*Event{events[2], events[3], events[1], events[0]})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, string(out), string(again))
+
+	// Test case 5: empty plan
+	out, err = Plan(nil)
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, string(out), `"actions": []`)
+}
+
+func TestFormatPlan(t *testing.T) {
+	route1 := *route
+	route1.Uris = []string{"/post"}
+	events := []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "route2", Uris: []string{"/get"}, ServiceID: "svc"}},
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: &route1},
+		{ResourceType: ServiceResourceType, Option: DeleteOption, OldValue: &types.Service{ID: "svc0"}},
+		{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: &types.Route{ID: "route0"}},
+		{ResourceType: GlobalRuleResourceType, Option: CreateOption, Value: &types.GlobalRule{ID: "rule"}},
+	}
+
+	// Test case 1: the phases in the order they are applied
+	plan, err := FormatPlan(events, &OutputOptions{DiffOnly: true, ContextLines: -1})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, `# Phase 1: create global rules
++++ global_rule: "rule"
+# Phase 2: create services
++++ service: "svc"
+# Phase 3: create routes
++++ route: "route2"
+# Phase 4: update routes
+update route: "route"
+--- remote
++++ local
+@@ -9 +9 @@
+-		"/get"
++		"/post"
+# Phase 5: delete routes
+--- route: "route0"
+# Phase 6: delete services
+--- service: "svc0"`, plan)
+
+	// Test case 2: no events
+	plan, err = FormatPlan(nil, nil)
+	assert.Nil(t, err, "should not return error")
+	assert.Empty(t, plan)
+}
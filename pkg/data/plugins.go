@@ -0,0 +1,127 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// PluginValidator validates the plugins of the events against the plugin
+// schemas of the cluster. Every schema is fetched once and cached, so a
+// validator can be reused for many events. It is safe for concurrent use.
+type PluginValidator struct {
+	getter apisix.PluginSchemaGetter
+
+	mu      sync.Mutex
+	schemas map[string]*gojsonschema.Schema
+}
+
+// NewPluginValidator returns a validator of the plugin schemas of the
+// cluster.
+func NewPluginValidator(getter apisix.PluginSchemaGetter) *PluginValidator {
+	return &PluginValidator{
+		getter:  getter,
+		schemas: make(map[string]*gojsonschema.Schema),
+	}
+}
+
+// schema returns the cached schema of the plugin, fetching it from the
+// cluster on the first call.
+func (v *PluginValidator) schema(ctx context.Context, name, schemaType string) (*gojsonschema.Schema, error) {
+	key := schemaType + "/" + name
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if schema, ok := v.schemas[key]; ok {
+		return schema, nil
+	}
+
+	raw, err := v.getter.PluginSchema(ctx, name, schemaType)
+	if errors.Is(err, apisix.ErrNotFound) {
+		return nil, errors.New("unknown plugin")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the schema")
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(raw))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid schema")
+	}
+	v.schemas[key] = schema
+	return schema, nil
+}
+
+// Validate validates the plugins of all the events, the returned error
+// combines an EventError for every event with invalid plugins. Deletes
+// and stream routes are not validated.
+func (v *PluginValidator) Validate(ctx context.Context, events []*Event) error {
+	var errs error
+	for _, event := range events {
+		if err := v.validate(ctx, event); err != nil {
+			errs = multierr.Append(errs, &EventError{Event: event, Err: err})
+		}
+	}
+	return errs
+}
+
+func (v *PluginValidator) validate(ctx context.Context, event *Event) error {
+	if event.Option == DeleteOption {
+		return nil
+	}
+	plugins, schemaType := eventPlugins(event)
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs error
+	for _, name := range names {
+		schema, err := v.schema(ctx, name, schemaType)
+		if err == nil {
+			var result *gojsonschema.Result
+			result, err = schema.Validate(gojsonschema.NewGoLoader(map[string]interface{}(plugins[name])))
+			if err == nil {
+				err = resultError(result)
+			}
+		}
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("plugin %q: %w", name, err))
+		}
+	}
+	return errs
+}
+
+// eventPlugins returns the plugins of the value of the event, and the
+// type of their schemas. The plugins of stream routes are not returned,
+// the schemas of the stream subsystem are different.
+func eventPlugins(event *Event) (types.Plugins, string) {
+	switch value := event.Value.(type) {
+	case *types.Route:
+		return value.Plugins, apisix.PluginSchemaTypeRoute
+	case *types.Service:
+		return value.Plugins, apisix.PluginSchemaTypeRoute
+	case *types.Consumer:
+		return value.Plugins, apisix.PluginSchemaTypeConsumer
+	case *types.GlobalRule:
+		return value.Plugins, apisix.PluginSchemaTypeRoute
+	case *types.PluginConfig:
+		return value.Plugins, apisix.PluginSchemaTypeRoute
+	case *types.ConsumerGroup:
+		return value.Plugins, apisix.PluginSchemaTypeRoute
+	}
+	return nil, apisix.PluginSchemaTypeRoute
+}
+
+// ValidatePlugins validates the plugins of the events with a new
+// PluginValidator of the cluster.
+func ValidatePlugins(ctx context.Context, getter apisix.PluginSchemaGetter, events []*Event) error {
+	return NewPluginValidator(getter).Validate(ctx, events)
+}
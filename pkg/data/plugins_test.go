@@ -0,0 +1,71 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// fakePluginSchemas returns the schemas of limit-count and key-auth.
+type fakePluginSchemas struct {
+	calls []string
+}
+
+func (f *fakePluginSchemas) PluginSchema(_ context.Context, name string, schemaType string) (string, error) {
+	f.calls = append(f.calls, schemaType+"/"+name)
+	switch {
+	case name == "limit-count":
+		return `{"type":"object","required":["count","time_window"],"properties":{"count":{"type":"integer","exclusiveMinimum":0},"time_window":{"type":"integer","exclusiveMinimum":0}}}`, nil
+	case name == "key-auth" && schemaType == apisix.PluginSchemaTypeConsumer:
+		return `{"type":"object","required":["key"],"properties":{"key":{"type":"string"}}}`, nil
+	case name == "key-auth":
+		return `{"type":"object","properties":{"header":{"type":"string"}}}`, nil
+	}
+	return "", apisix.ErrNotFound
+}
+
+func TestPluginValidator(t *testing.T) {
+	limitCount := func(plugin types.Plugin) *Event {
+		return &Event{
+			ResourceType: RouteResourceType,
+			Option:       CreateOption,
+			Value:        &types.Route{ID: "route", Uris: []string{"/get"}, Plugins: types.Plugins{"limit-count": plugin}},
+		}
+	}
+
+	// Test case 1: valid plugins, the schemas are cached
+	getter := &fakePluginSchemas{}
+	validator := NewPluginValidator(getter)
+	events := []*Event{
+		limitCount(types.Plugin{"count": 2, "time_window": 60}),
+		limitCount(types.Plugin{"count": 10, "time_window": 1}),
+		{ResourceType: ConsumerResourceType, Option: CreateOption, Value: consumer},
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: &types.Service{ID: "svc", Plugins: types.Plugins{"key-auth": {}}}},
+		{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: &types.Route{ID: "route", Plugins: types.Plugins{"unknown": {}}}},
+		{ResourceType: StreamRouteResourceType, Option: CreateOption, Value: &types.StreamRoute{ID: "stream", Plugins: types.Plugins{"mqtt-proxy": {}}}},
+	}
+	assert.Nil(t, validator.Validate(context.Background(), events))
+	assert.Equal(t, []string{"/limit-count", "consumer/key-auth", "/key-auth"}, getter.calls, "should fetch every schema once")
+
+	// Test case 2: invalid plugins
+	err := validator.Validate(context.Background(), []*Event{limitCount(types.Plugin{"count": 2})})
+	assert.EqualError(t, err, `route "route": plugin "limit-count": schema violations: (root): time_window is required`)
+	err = validator.Validate(context.Background(), []*Event{{
+		ResourceType: ConsumerResourceType,
+		Option:       CreateOption,
+		Value:        &types.Consumer{Username: "jack", Plugins: types.Plugins{"key-auth": {}, "unknown": {}}},
+	}})
+	assert.EqualError(t, err, `consumer "jack": plugin "key-auth": schema violations: (root): key is required; plugin "unknown": unknown plugin`)
+
+	// Test case 3: nothing is applied if some plugins are invalid
+	cluster := newFakeCluster()
+	err = ApplyAllWithOptions(context.Background(), cluster, []*Event{limitCount(types.Plugin{"count": 0, "time_window": 60})}, ApplyOptions{
+		PluginValidator: validator,
+	})
+	assert.Contains(t, err.Error(), `plugin "limit-count": schema violations: count: Must be greater than 0`)
+	assert.Empty(t, cluster.route.calls, "should not apply any event")
+}
@@ -0,0 +1,231 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// RateLimits are the limits of writes to the admin API, in requests
+// per second. A limit <= 0 means unlimited.
+type RateLimits struct {
+	// Default is the limit shared by the resource types not in PerType.
+	Default float64
+	// PerType is the limit of each resource type.
+	PerType map[ResourceType]float64
+}
+
+// DefaultRateLimits is conservative, SSL writes are heavier since APISIX
+// has to parse the certificates.
+var DefaultRateLimits = RateLimits{
+	Default: 20,
+	PerType: map[ResourceType]float64{
+		SSLResourceType: 5,
+	},
+}
+
+// RateLimiter limits the writes to the admin API by resource type.
+// A nil RateLimiter doesn't limit anything.
+type RateLimiter struct {
+	fallback *rate.Limiter
+	limiters map[ResourceType]*rate.Limiter
+
+	// now and sleep are the clock of the limiter, replaced in tests
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newLimiter(limit float64) *rate.Limiter {
+	if limit <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	return rate.NewLimiter(rate.Limit(limit), 1)
+}
+
+// NewRateLimiter creates a RateLimiter with the given limits. It returns
+// nil, which doesn't limit anything, if all the limits are <= 0.
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	unlimited := limits.Default <= 0
+	for _, limit := range limits.PerType {
+		unlimited = unlimited && limit <= 0
+	}
+	if unlimited {
+		return nil
+	}
+
+	l := &RateLimiter{
+		fallback: newLimiter(limits.Default),
+		limiters: make(map[ResourceType]*rate.Limiter, len(limits.PerType)),
+		now:      time.Now,
+		sleep:    sleep,
+	}
+	for typ, limit := range limits.PerType {
+		l.limiters[typ] = newLimiter(limit)
+	}
+	return l
+}
+
+// sleep waits for the duration, or until the ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Wait blocks until a write of the resource type is allowed, or the ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, typ ResourceType) error {
+	if l == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	limiter, ok := l.limiters[typ]
+	if !ok {
+		limiter = l.fallback
+	}
+
+	now := l.now()
+	reservation := limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+	if err := l.sleep(ctx, delay); err != nil {
+		// give the reservation back to the next writes
+		reservation.CancelAt(l.now())
+		return err
+	}
+	return nil
+}
+
+type rateLimitedClient[T any] struct {
+	apisix.ResourceClient[T]
+
+	typ     ResourceType
+	limiter *RateLimiter
+}
+
+func (c *rateLimitedClient[T]) Create(ctx context.Context, obj *T) (*T, error) {
+	if err := c.limiter.Wait(ctx, c.typ); err != nil {
+		return nil, err
+	}
+	return c.ResourceClient.Create(ctx, obj)
+}
+
+func (c *rateLimitedClient[T]) Update(ctx context.Context, obj *T) (*T, error) {
+	if err := c.limiter.Wait(ctx, c.typ); err != nil {
+		return nil, err
+	}
+	return c.ResourceClient.Update(ctx, obj)
+}
+
+func (c *rateLimitedClient[T]) Delete(ctx context.Context, name string) error {
+	if err := c.limiter.Wait(ctx, c.typ); err != nil {
+		return err
+	}
+	return c.ResourceClient.Delete(ctx, name)
+}
+
+func limit[T any](client apisix.ResourceClient[T], typ ResourceType, limiter *RateLimiter) *rateLimitedClient[T] {
+	return &rateLimitedClient[T]{
+		ResourceClient: client,
+		typ:            typ,
+		limiter:        limiter,
+	}
+}
+
+type rateLimitedCluster struct {
+	apisix.Cluster
+
+	limiter *RateLimiter
+}
+
+// NewRateLimitedCluster wraps the cluster so that the writes of Apply and
+// ApplyAll respect the limiter, a bulk request of BulkWriter counts as a
+// single write. Reads and the transactions of Transactor are not limited.
+func NewRateLimitedCluster(cluster apisix.Cluster, limiter *RateLimiter) apisix.Cluster {
+	if limiter == nil {
+		return cluster
+	}
+	return &rateLimitedCluster{
+		Cluster: cluster,
+		limiter: limiter,
+	}
+}
+
+// Unwrap returns the wrapped cluster, see Unwrapper.
+func (c *rateLimitedCluster) Unwrap() apisix.Cluster {
+	return c.Cluster
+}
+
+// rateLimitedBulkWriter is a BulkWriter respecting the limiter.
+type rateLimitedBulkWriter struct {
+	BulkWriter
+
+	limiter *RateLimiter
+}
+
+func (w *rateLimitedBulkWriter) WriteBulk(ctx context.Context, typ ResourceType, values []interface{}) error {
+	if err := w.limiter.Wait(ctx, typ); err != nil {
+		return err
+	}
+	return w.BulkWriter.WriteBulk(ctx, typ, values)
+}
+
+func (c *rateLimitedCluster) Route() apisix.Route {
+	return limit[types.Route](c.Cluster.Route(), RouteResourceType, c.limiter)
+}
+
+func (c *rateLimitedCluster) Service() apisix.Service {
+	return limit[types.Service](c.Cluster.Service(), ServiceResourceType, c.limiter)
+}
+
+func (c *rateLimitedCluster) Consumer() apisix.Consumer {
+	return limit[types.Consumer](c.Cluster.Consumer(), ConsumerResourceType, c.limiter)
+}
+
+func (c *rateLimitedCluster) SSL() apisix.SSL {
+	return limit[types.SSL](c.Cluster.SSL(), SSLResourceType, c.limiter)
+}
+
+func (c *rateLimitedCluster) GlobalRule() apisix.GlobalRule {
+	return limit[types.GlobalRule](c.Cluster.GlobalRule(), GlobalRuleResourceType, c.limiter)
+}
+
+func (c *rateLimitedCluster) PluginConfig() apisix.PluginConfig {
+	return limit[types.PluginConfig](c.Cluster.PluginConfig(), PluginConfigResourceType, c.limiter)
+}
+
+func (c *rateLimitedCluster) ConsumerGroup() apisix.ConsumerGroup {
+	return limit[types.ConsumerGroup](c.Cluster.ConsumerGroup(), ConsumerGroupResourceType, c.limiter)
+}
+
+func (c *rateLimitedCluster) PluginMetadata() apisix.PluginMetadata {
+	return limit[types.PluginMetadata](c.Cluster.PluginMetadata(), PluginMetadataResourceType, c.limiter)
+}
+
+func (c *rateLimitedCluster) StreamRoute() apisix.StreamRoute {
+	return limit[types.StreamRoute](c.Cluster.StreamRoute(), StreamRouteResourceType, c.limiter)
+}
+
+func (c *rateLimitedCluster) Upstream() apisix.Upstream {
+	return limit[types.Upstream](c.Cluster.Upstream(), UpstreamResourceType, c.limiter)
+}
+
+func (c *rateLimitedCluster) Secret() apisix.Secret {
+	return limit[types.Secret](c.Cluster.Secret(), SecretResourceType, c.limiter)
+}
+
+func (c *rateLimitedCluster) Proto() apisix.Proto {
+	return limit[types.Proto](c.Cluster.Proto(), ProtoResourceType, c.limiter)
+}
@@ -0,0 +1,87 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// fakeClock is a clock whose time doesn't pass, it records the delays of
+// the limiter instead of sleeping.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	delays []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.delays = append(c.delays, d)
+	return ctx.Err()
+}
+
+func (c *fakeClock) sortedDelays() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delays := append([]time.Duration(nil), c.delays...)
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+	c.delays = nil
+	return delays
+}
+
+func TestRateLimitedCluster(t *testing.T) {
+	fake := newFakeCluster()
+	limiter := NewRateLimiter(RateLimits{
+		PerType: map[ResourceType]float64{
+			SSLResourceType: 50,
+		},
+	})
+	clock := &fakeClock{now: time.Now()}
+	limiter.now, limiter.sleep = clock.Now, clock.Sleep
+	cluster := NewRateLimitedCluster(fake, limiter)
+
+	var events []*Event
+	for i := 0; i < 6; i++ {
+		events = append(events, &Event{
+			ResourceType: SSLResourceType,
+			Option:       CreateOption,
+			Value:        &types.SSL{ID: fmt.Sprintf("ssl%d", i)},
+		})
+	}
+
+	// Test case 1: ssl writes are limited to 50 per second, the first one
+	// doesn't wait and each next one waits 20ms more
+	assert.Nil(t, ApplyAll(context.Background(), cluster, events, 6, false), "should apply all events")
+	assert.Equal(t, []time.Duration{
+		20 * time.Millisecond, 40 * time.Millisecond, 60 * time.Millisecond, 80 * time.Millisecond, 100 * time.Millisecond,
+	}, clock.sortedDelays(), "should wait for the limiter")
+	assert.Len(t, fake.ssl.calls, 6)
+
+	// Test case 2: other types are not limited
+	assert.Nil(t, ApplyAll(context.Background(), cluster, routeEvents(50), 1, false), "should apply all events")
+	assert.Empty(t, clock.sortedDelays(), "should not wait")
+
+	// Test case 3: the wait is cancelled with the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := ApplyAll(ctx, cluster, events, 1, false)
+	assert.ErrorIs(t, err, context.Canceled, "should return the context error")
+	assert.Len(t, fake.ssl.calls, 6, "should not apply any event")
+
+	// Test case 4: nil limiter disables the limits
+	assert.Equal(t, fake, NewRateLimitedCluster(fake, nil))
+	assert.Nil(t, (*RateLimiter)(nil).Wait(context.Background(), SSLResourceType))
+	assert.Nil(t, NewRateLimiter(RateLimits{PerType: map[ResourceType]float64{SSLResourceType: 0}}), "should not limit anything")
+}
@@ -0,0 +1,242 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// redactedValue replaces sensitive values in the output of events
+const redactedValue = "***"
+
+// changedRedactedValue replaces the sensitive values changed by updates in
+// the local side of the diffs, so that the diff shows the change. It's
+// rendered as redactedValue, see unmarkChanged.
+const changedRedactedValue = redactedValue + "\x00"
+
+// changedRedactedJSON is changedRedactedValue in the JSON of the diffs.
+const changedRedactedJSON = redactedValue + `\u0000`
+
+// unmarkChanged renders the changed sensitive values of the diff as
+// redactedValue, e.g. a changed password is shown as a removed and an
+// added "password": "***" line.
+func unmarkChanged(diff string) string {
+	return strings.ReplaceAll(diff, changedRedactedJSON, redactedValue)
+}
+
+// RedactedFields are the dotted JSON paths of the sensitive fields of each
+// resource type, e.g. "plugins.key-auth.key". A path can traverse arrays,
+// it applies to every element of them.
+type RedactedFields map[ResourceType][]string
+
+// DefaultRedactedFields are the credentials of the consumer auth plugins,
+// the private keys of SSL and of the client certificates of upstreams, the
+// SASL passwords of the Kafka upstreams, and the tokens of secret managers.
+var DefaultRedactedFields = RedactedFields{
+	ConsumerResourceType: {
+		"plugins.key-auth.key",
+		"plugins.basic-auth.password",
+		"plugins.jwt-auth.secret",
+		"plugins.jwt-auth.private_key",
+		"plugins.hmac-auth.secret_key",
+	},
+	SSLResourceType: {
+		"key",
+		"keys",
+	},
+	SecretResourceType: {
+		"token",
+		"secret_access_key",
+		"session_token",
+	},
+	UpstreamResourceType: {
+		"tls.client_key",
+	},
+	RouteResourceType: {
+		"upstream.tls.client_key",
+		"plugins.kafka-proxy.sasl.password",
+	},
+	ServiceResourceType: {
+		"upstream.tls.client_key",
+		"plugins.kafka-proxy.sasl.password",
+	},
+	StreamRouteResourceType: {
+		"upstream.tls.client_key",
+	},
+}
+
+// redactedJSON returns the compact JSON of the value with the sensitive
+// fields masked. The order of the fields is kept, so the result can be
+// indented to the same output of json.MarshalIndent.
+func redactedJSON(typ ResourceType, value interface{}, fields RedactedFields) ([]byte, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields[typ] {
+		raw, err = redactPath(raw, strings.Split(field, "."))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return raw, nil
+}
+
+// redactedPairJSON is redactedJSON of both sides of an update, the
+// sensitive values of value which differ from the ones of oldValue are
+// changedRedactedValue. oldRaw is nil if oldValue is.
+func redactedPairJSON(typ ResourceType, oldValue, value interface{}, fields RedactedFields) (oldRaw, raw []byte, err error) {
+	if isNil(oldValue) {
+		raw, err = redactedJSON(typ, value, fields)
+		return nil, raw, err
+	}
+	if oldRaw, err = json.Marshal(oldValue); err != nil {
+		return nil, nil, err
+	}
+	if raw, err = json.Marshal(value); err != nil {
+		return nil, nil, err
+	}
+
+	redacted, err := json.Marshal(redactedValue)
+	if err != nil {
+		return nil, nil, err
+	}
+	changed, err := json.Marshal(changedRedactedValue)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, field := range fields[typ] {
+		path := strings.Split(field, ".")
+		old := make(map[string]string)
+		oldRaw, err = maskPath(oldRaw, path, "", func(loc string, value json.RawMessage) json.RawMessage {
+			old[loc] = string(value)
+			return redacted
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		raw, err = maskPath(raw, path, "", func(loc string, value json.RawMessage) json.RawMessage {
+			if oldValue, ok := old[loc]; ok && oldValue != string(value) {
+				return changed
+			}
+			return redacted
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return oldRaw, raw, nil
+}
+
+// redactPath masks the value at the path of the raw JSON.
+func redactPath(raw json.RawMessage, path []string) (json.RawMessage, error) {
+	redacted, err := json.Marshal(redactedValue)
+	if err != nil {
+		return nil, err
+	}
+	return maskPath(raw, path, "", func(string, json.RawMessage) json.RawMessage {
+		return redacted
+	})
+}
+
+// maskPath replaces the value at the path of the raw JSON with the one
+// returned by mask, which is called with the location of the value, e.g.
+// "nodes.0.password" for the path "nodes.password". Empty values are kept.
+func maskPath(raw json.RawMessage, path []string, loc string, mask func(loc string, value json.RawMessage) json.RawMessage) (json.RawMessage, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return raw, nil
+	}
+
+	switch raw[0] {
+	case '[':
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			return nil, err
+		}
+		for i := range elems {
+			elem, err := maskPath(elems[i], path, joinLocation(loc, strconv.Itoa(i)), mask)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = elem
+		}
+		return json.Marshal(elems)
+	case '{':
+		if len(path) == 0 {
+			return mask(loc, raw), nil
+		}
+		return maskObject(raw, path, loc, mask)
+	}
+
+	if len(path) > 0 || string(raw) == "null" || string(raw) == `""` {
+		return raw, nil
+	}
+	return mask(loc, raw), nil
+}
+
+func joinLocation(loc, key string) string {
+	if loc == "" {
+		return key
+	}
+	return loc + "." + key
+}
+
+// maskObject masks the value at the path of the raw JSON object like
+// maskPath, the order of the keys is kept.
+func maskObject(raw json.RawMessage, path []string, loc string, mask func(loc string, value json.RawMessage) json.RawMessage) (json.RawMessage, error) {
+	return walkObject(raw, func(key string, value json.RawMessage) (json.RawMessage, error) {
+		if key != path[0] {
+			return value, nil
+		}
+		return maskPath(value, path[1:], joinLocation(loc, key), mask)
+	})
+}
+
+// walkObject rewrites each field of the raw JSON object with the value
+// returned by fn, the field is removed if it returns nil. The order of the
+// keys is kept.
+func walkObject(raw json.RawMessage, fn func(key string, value json.RawMessage) (json.RawMessage, error)) (json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	// consume the opening brace
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for written := 0; dec.More(); {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		value, err = fn(key, value)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			continue
+		}
+
+		if written > 0 {
+			out.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(encodedKey)
+		out.WriteByte(':')
+		out.Write(value)
+		written++
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
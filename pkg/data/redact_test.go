@@ -0,0 +1,129 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestRedactedFields(t *testing.T) {
+	consumer1 := &types.Consumer{
+		Username: "jack",
+		Plugins: types.Plugins{
+			"key-auth": types.Plugin{
+				"key": "auth-two",
+			},
+			"basic-auth": types.Plugin{
+				"username": "jack",
+				"password": "p@ssw0rd",
+			},
+		},
+	}
+	event := &Event{
+		ResourceType: ConsumerResourceType,
+		Option:       UpdateOption,
+		OldValue:     consumer,
+		Value:        consumer1,
+	}
+
+	// Test case 1: the default fields
+	output, err := event.Output(false)
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, output, "+\t\t\"basic-auth\": {\n+\t\t\t\"password\": \"***\",\n+\t\t\t\"username\": \"jack\"\n+\t\t},", "should redact the password")
+	assert.Contains(t, output, "\t\t\t\"key\": \"***\"", "should redact the key")
+	assert.NotContains(t, output, "auth-one", "should not leak the old key")
+	assert.NotContains(t, output, "auth-two", "should not leak the new key")
+	assert.NotContains(t, output, "p@ssw0rd", "should not leak the password")
+
+	// Test case 2: override the fields
+	output, err = event.OutputWithOptions(&OutputOptions{
+		RedactedFields: RedactedFields{
+			ConsumerResourceType: {"plugins.basic-auth.username"},
+		},
+	})
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, output, "+\t\t\t\"password\": \"p@ssw0rd\",\n+\t\t\t\"username\": \"***\"\n", "should only redact the given fields")
+	assert.Contains(t, output, "-\t\t\t\"key\": \"auth-one\"\n+\t\t\t\"key\": \"auth-two\"\n", "should not redact the key")
+
+	// Test case 3: the structured output uses the defaults
+	structured, err := event.StructuredOutput()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []Change{
+		{Path: "/plugins/basic-auth", New: map[string]interface{}{"username": "jack", "password": "***"}},
+	}, structured.Changes)
+}
+
+func TestRedactPath(t *testing.T) {
+	raw := []byte(`{"name":"svc","nodes":[{"host":"a","password":"1"},{"host":"b"}],"empty":"","keys":["k1","k2"],"obj":{"a":1}}`)
+
+	// Test case 1: traverse arrays and keep the order of keys
+	out, err := redactPath(raw, []string{"nodes", "password"})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, `{"name":"svc","nodes":[{"host":"a","password":"***"},{"host":"b"}],"empty":"","keys":["k1","k2"],"obj":{"a":1}}`, string(out))
+
+	// Test case 2: arrays of values and objects
+	out, err = redactPath(raw, []string{"keys"})
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, string(out), `"keys":["***","***"]`)
+	out, err = redactPath(raw, []string{"obj"})
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, string(out), `"obj":"***"`)
+
+	// Test case 3: empty and missing values are kept
+	out, err = redactPath(raw, []string{"empty"})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, string(raw), string(out))
+	out, err = redactPath(raw, []string{"name", "missing"})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, string(raw), string(out))
+}
+
+func TestRedactedChanges(t *testing.T) {
+	upstream := &types.Upstream{
+		ID:    "kafka",
+		Name:  "kafka",
+		Nodes: []types.UpstreamNode{{Host: "127.0.0.1", Port: 9092, Weight: 1}},
+		TLS:   &types.ClientTLS{Cert: "cert", Key: "old-key"},
+	}
+	upstream1 := *upstream
+	upstream1.TLS = &types.ClientTLS{Cert: "cert", Key: "new-key"}
+
+	// Test case 1: a changed client key of an upstream
+	event := &Event{ResourceType: UpstreamResourceType, Option: UpdateOption, OldValue: upstream, Value: &upstream1}
+	output, err := event.Output(false)
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, output, "-\t\t\"client_key\": \"***\"\n+\t\t\"client_key\": \"***\"\n", "should show the change")
+	assert.NotContains(t, output, "old-key", "should not leak the old key")
+	assert.NotContains(t, output, "new-key", "should not leak the new key")
+	assert.NotContains(t, output, `\u0000`, "should not contain the mark of the change")
+
+	// Test case 2: a changed SASL password in the plugins of a route
+	kafka := func(password string) *types.Route {
+		return &types.Route{
+			ID:         "kafka",
+			Name:       "kafka",
+			Uri:        "/kafka",
+			UpstreamID: "kafka",
+			Plugins: types.Plugins{
+				"kafka-proxy": types.Plugin{
+					"sasl": map[string]interface{}{"username": "user", "password": password},
+				},
+			},
+		}
+	}
+	event = &Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: kafka("p@ssw0rd"), Value: kafka("s3cret")}
+	output, err = event.Output(true)
+	assert.Nil(t, err, "should not return error")
+	assert.Contains(t, output, "-\t\t\t\t\"password\": \"***\",\n+\t\t\t\t\"password\": \"***\",\n", "should show the change")
+	assert.NotContains(t, output, "p@ssw0rd", "should not leak the old password")
+	assert.NotContains(t, output, "s3cret", "should not leak the new password")
+
+	// Test case 3: unchanged values are unchanged in the diff
+	event = &Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: kafka("p@ssw0rd"), Value: kafka("p@ssw0rd")}
+	output, err = event.Output(true)
+	assert.Nil(t, err, "should not return error")
+	assert.NotContains(t, output, "-\t", "should not show changes")
+	assert.NotContains(t, output, "+\t", "should not show changes")
+}
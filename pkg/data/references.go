@@ -0,0 +1,53 @@
+package data
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// ValidateReferences checks the services referenced by the routes of the
+// create and update events exist after applying the events, i.e. they are
+// created or updated by the events, or they exist in the cluster and are
+// not deleted by the events. The cluster isn't checked if it's nil.
+// The returned error combines an EventError for every dangling reference.
+func ValidateReferences(ctx context.Context, cluster apisix.Cluster, events []*Event) error {
+	services := make(map[string]bool)
+	for _, event := range events {
+		if event.ResourceType == ServiceResourceType {
+			services[event.key()] = event.Option != DeleteOption
+		}
+	}
+
+	var errs error
+	for _, event := range events {
+		if event.ResourceType != RouteResourceType || event.Option == DeleteOption {
+			continue
+		}
+		route, ok := event.Value.(*types.Route)
+		if !ok || route == nil || route.ServiceID == "" {
+			continue
+		}
+
+		exists, ok := services[route.ServiceID]
+		if !ok && cluster != nil {
+			_, err := cluster.Service().Get(ctx, route.ServiceID)
+			if err != nil && !errors.Is(err, apisix.ErrNotFound) {
+				return errors.Wrapf(err, "failed to get service \"%s\"", route.ServiceID)
+			}
+			exists = err == nil
+			services[route.ServiceID] = exists
+		}
+		if !exists {
+			errs = multierr.Append(errs, &EventError{
+				Event: event,
+				Err:   errors.Errorf("service \"%s\" referenced by route \"%s\" doesn't exist", route.ServiceID, route.Name),
+			})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,56 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestValidateReferences(t *testing.T) {
+	orphan := &types.Route{ID: "orphan", Name: "orphan", Uris: []string{"/orphan"}, ServiceID: "missing"}
+	remote := &types.Route{ID: "remote", Name: "remote", Uris: []string{"/remote"}, ServiceID: "remote-svc"}
+
+	// Test case 1: the service is created by the events
+	events := []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: route},
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+	}
+	assert.Nil(t, ValidateReferences(context.Background(), nil, events), "should not return error")
+
+	// Test case 2: dangling references
+	events = append(events, &Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: orphan, Value: orphan})
+	err := ValidateReferences(context.Background(), nil, events)
+	assert.EqualError(t, err, "route \"orphan\": service \"missing\" referenced by route \"orphan\" doesn't exist")
+
+	// Test case 3: the service exists in the cluster
+	cluster := newFakeCluster()
+	cluster.service.items["remote-svc"] = &types.Service{ID: "remote-svc"}
+	events = []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: remote},
+	}
+	assert.Nil(t, ValidateReferences(context.Background(), cluster, events), "should not return error")
+	assert.NotNil(t, ValidateReferences(context.Background(), nil, events), "should not check the cluster")
+
+	// Test case 4: the service is deleted by the events
+	events = append(events, &Event{ResourceType: ServiceResourceType, Option: DeleteOption, OldValue: &types.Service{ID: "remote-svc"}})
+	err = ValidateReferences(context.Background(), cluster, events)
+	assert.EqualError(t, err, "route \"remote\": service \"remote-svc\" referenced by route \"remote\" doesn't exist")
+
+	// Test case 5: deleted routes are ignored
+	events = []*Event{
+		{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: orphan},
+	}
+	assert.Nil(t, ValidateReferences(context.Background(), cluster, events), "should not return error")
+
+	// Test case 6: DryRun reports them
+	_, err = DryRun(context.Background(), cluster, []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: orphan},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: remote},
+	})
+	assert.Len(t, multierr.Errors(err), 1)
+	assert.Contains(t, err.Error(), "service \"missing\" referenced by route \"orphan\" doesn't exist")
+}
@@ -0,0 +1,144 @@
+package data
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix"
+)
+
+// ApplyStatus is the outcome of applying an event, see ApplyResult.
+type ApplyStatus string
+
+const (
+	// AppliedStatus is the status of the events applied to the cluster.
+	AppliedStatus ApplyStatus = "applied"
+	// SkippedStatus is the status of the events that change nothing, which
+	// are not sent to the cluster unless forced, see Event.IsNoOp and
+	// ApplyOptions.Force.
+	SkippedStatus ApplyStatus = "skipped"
+	// FailedStatus is the status of the events that failed, including the
+	// ones aborted by ApplyOptions.BeforeApply.
+	FailedStatus ApplyStatus = "failed"
+	// PendingStatus is the status of the events that were not applied
+	// because an earlier event failed or the context was canceled, or
+	// because the validation of the events failed.
+	PendingStatus ApplyStatus = "pending"
+)
+
+// ApplyResult is the outcome of applying an event, Err is the error of the
+// failed events and nil otherwise.
+type ApplyResult struct {
+	Event  *Event
+	Status ApplyStatus
+	Err    error
+	// Applied is the event applied instead of Event when the options
+	// change it, e.g. the update disabling the resource of a delete with
+	// DisableDelete, nil otherwise.
+	Applied *Event
+}
+
+// ApplyResults are the results of ApplyAllWithResults.
+type ApplyResults []ApplyResult
+
+// Err combines an EventError for every failed event, the same errors as
+// ApplyAllWithOptions without the errors of the context and of validation.
+func (r ApplyResults) Err() error {
+	var errs error
+	for _, result := range r {
+		if result.Status == FailedStatus {
+			errs = multierr.Append(errs, &EventError{Event: result.Event, Err: result.Err})
+		}
+	}
+	return errs
+}
+
+// Events returns the events with the statuses in the order of the results,
+// e.g. the failed and pending ones to retry them.
+func (r ApplyResults) Events(statuses ...ApplyStatus) []*Event {
+	var events []*Event
+	for _, result := range r {
+		for _, status := range statuses {
+			if result.Status == status {
+				events = append(events, result.Event)
+				break
+			}
+		}
+	}
+	return events
+}
+
+// ApplyAllWithResults is ApplyAllWithOptions that also returns the result of
+// every event, in the order they are applied, see SortEvents. The events
+// dropped by ApplyOptions.Filters have no result. The error is the same as
+// ApplyAllWithOptions, all the events are pending if the validation fails.
+// When it aborts after a failure or a cancellation, the events completed
+// before, those still running in the other workers included, are applied
+// or failed and the rest is pending, so that the results are the partial
+// state of the cluster, e.g. to Rollback.
+func ApplyAllWithResults(ctx context.Context, cluster apisix.Cluster, events []*Event, opts ApplyOptions) (ApplyResults, error) {
+	results := &resultRecorder{}
+	err := applyAll(ctx, cluster, events, opts, results)
+	return results.results(), err
+}
+
+// resultRecorder records the errors of the applied events, it's safe for
+// concurrent use. The methods of a nil recorder do nothing.
+type resultRecorder struct {
+	mu      sync.Mutex
+	events  []*Event
+	applied map[*Event]error
+	targets map[*Event]*Event
+	// force is ApplyOptions.Force, the events changing nothing are applied
+	force bool
+}
+
+// plan sets the events to apply, in order, and whether they are forced.
+func (r *resultRecorder) plan(events []*Event, force bool) {
+	if r == nil {
+		return
+	}
+	r.events, r.force = events, force
+}
+
+// record records the error of applying the event, nil on success, and the
+// event applied instead of it, see ApplyOptions.target.
+func (r *resultRecorder) record(event, target *Event, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.applied == nil {
+		r.applied = make(map[*Event]error)
+		r.targets = make(map[*Event]*Event)
+	}
+	r.applied[event] = err
+	if target != event {
+		r.targets[event] = target
+	}
+}
+
+func (r *resultRecorder) results() ApplyResults {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	results := make(ApplyResults, 0, len(r.events))
+	for _, event := range r.events {
+		result := ApplyResult{Event: event, Status: PendingStatus}
+		if err, ok := r.applied[event]; ok {
+			result.Err, result.Applied = err, r.targets[event]
+			switch noop, _ := event.IsNoOp(); {
+			case err != nil:
+				result.Status = FailedStatus
+			case noop && !r.force:
+				result.Status = SkippedStatus
+			default:
+				result.Status = AppliedStatus
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
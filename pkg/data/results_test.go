@@ -0,0 +1,62 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+)
+
+func TestApplyAllWithResults(t *testing.T) {
+	fake := newFakeCluster()
+	fake.route.err = errors.New("unexpected status code 400; invalid route")
+	create := &Event{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc}
+	noop := &Event{ResourceType: ConsumerResourceType, Option: UpdateOption, OldValue: consumer, Value: consumer}
+	// the deletes of routes are applied last
+	routes := routeEvents(3)
+	for _, event := range routes {
+		event.Option, event.OldValue, event.Value = DeleteOption, event.Value, nil
+	}
+	events := append([]*Event{create, noop}, routes...)
+
+	// Test case 1: stops at the first failure
+	results, err := ApplyAllWithResults(context.Background(), fake, events, ApplyOptions{Concurrency: 1})
+	assert.Equal(t, "route \"route0\": failed to apply route: unexpected status code 400; invalid route", err.Error())
+	statuses := make(map[*Event]ApplyStatus)
+	for _, result := range results {
+		statuses[result.Event] = result.Status
+	}
+	assert.Equal(t, map[*Event]ApplyStatus{
+		create:    AppliedStatus,
+		noop:      SkippedStatus,
+		routes[0]: FailedStatus,
+		routes[1]: PendingStatus,
+		routes[2]: PendingStatus,
+	}, statuses)
+	assert.Equal(t, err.Error(), results.Err().Error(), "should derive the same error")
+	assert.Equal(t, routes, results.Events(FailedStatus, PendingStatus), "should return the events to retry")
+
+	// Test case 2: continue on error
+	fake = newFakeCluster()
+	fake.route.err = errors.New("unexpected status code 400; invalid route")
+	results, err = ApplyAllWithResults(context.Background(), fake, events, ApplyOptions{ContinueOnError: true})
+	assert.Len(t, multierr.Errors(err), 3)
+	assert.Equal(t, routes, results.Events(FailedStatus))
+	assert.Empty(t, results.Events(PendingStatus))
+	for _, result := range results {
+		if result.Status == FailedStatus {
+			assert.EqualError(t, result.Err, "failed to apply route: unexpected status code 400; invalid route")
+		} else {
+			assert.Nil(t, result.Err)
+		}
+	}
+
+	// Test case 3: nothing is applied if the validation fails
+	invalid := &Event{ResourceType: RouteResourceType, Option: CreateOption, Value: route}
+	results, err = ApplyAllWithResults(context.Background(), newFakeCluster(), []*Event{create, invalid, invalid}, ApplyOptions{Validate: true})
+	assert.NotNil(t, err, "should return error")
+	assert.Len(t, results.Events(PendingStatus), 3)
+	assert.Nil(t, results.Err())
+}
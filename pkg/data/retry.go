@@ -0,0 +1,94 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/api7/adc/pkg/api/apisix"
+)
+
+// RetryPolicy is the policy of retrying a failed event.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponential growth of the delay.
+	MaxBackoff time.Duration
+	// Jitter is the fraction of the delay that is randomized, in [0, 1].
+	Jitter float64
+}
+
+// DefaultRetryPolicy retries a failed event twice.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Jitter:         0.2,
+}
+
+// IsRetryable reports whether the error is transient, i.e. a network error
+// or a 5xx response of the admin API. A 4xx response won't succeed by
+// retrying, so it is not retryable.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var statusErr *apisix.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// backoff returns the delay before the given retry, starting from 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			delay = p.MaxBackoff
+			break
+		}
+	}
+
+	if p.Jitter > 0 {
+		delta := float64(delay) * p.Jitter
+		delay += time.Duration(delta * (2*rand.Float64() - 1))
+	}
+	return delay
+}
+
+// apply applies the event, with ApplyWithRetry if there is a retry policy.
+func (opts *ApplyOptions) apply(ctx context.Context, cluster apisix.Cluster, event *Event) error {
+	if opts.Retry == nil {
+		return event.Apply(ctx, cluster)
+	}
+	return event.ApplyWithRetry(ctx, cluster, *opts.Retry)
+}
+
+// ApplyWithRetry applies the event, and retries it with exponential
+// backoff when it fails with a retryable error.
+func (e *Event) ApplyWithRetry(ctx context.Context, cluster apisix.Cluster, policy RetryPolicy) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = e.Apply(ctx, cluster)
+		if err == nil || attempt >= policy.MaxAttempts || !IsRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
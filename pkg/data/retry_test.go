@@ -0,0 +1,108 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     4 * time.Millisecond,
+	Jitter:         0.5,
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.False(t, IsRetryable(nil), "nil is not retryable")
+	assert.True(t, IsRetryable(&apisix.StatusError{StatusCode: 503, Message: "service unavailable"}), "5xx is retryable")
+	assert.False(t, IsRetryable(&apisix.StatusError{StatusCode: 400, Message: "invalid configuration"}), "4xx is not retryable")
+	assert.True(t, IsRetryable(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}), "network error is retryable")
+	assert.True(t, IsRetryable(fmt.Errorf("failed to apply route: %w", &apisix.StatusError{StatusCode: 502})), "wrapped 5xx is retryable")
+	assert.False(t, IsRetryable(context.Canceled), "canceled context is not retryable")
+	assert.False(t, IsRetryable(errors.New("unknown")), "unknown error is not retryable")
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.backoff(2))
+	assert.Equal(t, 400*time.Millisecond, policy.backoff(3))
+	assert.Equal(t, time.Second, policy.backoff(10), "should be capped by the max backoff")
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.backoff(2)
+		assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		assert.LessOrEqual(t, delay, 300*time.Millisecond)
+	}
+}
+
+func TestApplyWithRetry(t *testing.T) {
+	event := &Event{
+		ResourceType: RouteResourceType,
+		Option:       CreateOption,
+		Value:        route,
+	}
+
+	// Test case 1: fails twice and then succeeds
+	cluster := newFakeCluster()
+	cluster.route.err = &apisix.StatusError{StatusCode: 503, Message: "service unavailable"}
+	cluster.route.failures = 2
+	assert.Nil(t, event.ApplyWithRetry(context.Background(), cluster, testRetryPolicy), "should succeed after retrying")
+	assert.Equal(t, []string{"create:route", "create:route", "create:route"}, cluster.route.calls)
+
+	// Test case 2: gives up after the max attempts
+	cluster = newFakeCluster()
+	cluster.route.err = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	err := event.ApplyWithRetry(context.Background(), cluster, testRetryPolicy)
+	assert.NotNil(t, err, "should return error")
+	assert.Len(t, cluster.route.calls, 3, "should stop after the max attempts")
+
+	// Test case 3: validation errors fail immediately
+	cluster = newFakeCluster()
+	cluster.route.err = &apisix.StatusError{StatusCode: 400, Message: "invalid configuration"}
+	err = event.ApplyWithRetry(context.Background(), cluster, testRetryPolicy)
+	assert.Equal(t, "failed to apply route: unexpected status code 400; invalid configuration", err.Error())
+	assert.Len(t, cluster.route.calls, 1, "should not retry")
+
+	// Test case 4: stops retrying when the context is done
+	cluster = newFakeCluster()
+	cluster.route.err = &apisix.StatusError{StatusCode: 503, Message: "service unavailable"}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = event.ApplyWithRetry(ctx, cluster, RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: time.Second,
+	})
+	assert.Equal(t, "failed to apply route: unexpected status code 503; service unavailable", err.Error())
+	assert.Len(t, cluster.route.calls, 1, "should not retry after the context is done")
+}
+
+func TestApplyAllRetry(t *testing.T) {
+	// Test case 1: the events are retried with the policy
+	cluster := newFakeCluster()
+	cluster.route.err = &apisix.StatusError{StatusCode: 503, Message: "service unavailable"}
+	cluster.route.failures = 2
+	err := ApplyAllWithOptions(context.Background(), cluster, routeEvents(1), ApplyOptions{Retry: &testRetryPolicy})
+	assert.Nil(t, err, "should succeed after retrying")
+	assert.Len(t, cluster.route.calls, 3, "should retry the event")
+
+	// Test case 2: the events are not retried without a policy
+	cluster = newFakeCluster()
+	cluster.route.err = &apisix.StatusError{StatusCode: 503, Message: "service unavailable"}
+	cluster.route.failures = 2
+	err = ApplyAllWithOptions(context.Background(), cluster, routeEvents(1), ApplyOptions{})
+	assert.NotNil(t, err, "should return error")
+	assert.Len(t, cluster.route.calls, 1, "should not retry the event")
+}
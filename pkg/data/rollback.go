@@ -0,0 +1,101 @@
+package data
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix"
+)
+
+// Inverse returns the event reverting the event: the inverse of a create
+// is a delete of the value, the inverse of a delete is a create of the old
+// value, and the inverse of an update swaps the old and new values. The
+// inverse of an upsert is an update restoring the old value. An error is
+// returned if the event lacks the value to restore, e.g. an update without
+// old value, or an upsert without old value since it is unknown whether it
+// created the resource. A noop is its own inverse.
+func (e *Event) Inverse() (Event, error) {
+	if err := e.checkValues(); err != nil {
+		return Event{}, err
+	}
+
+	inverse := Event{ResourceType: e.ResourceType}
+	switch e.Option {
+	case CreateOption:
+		inverse.Option = DeleteOption
+		inverse.OldValue = e.Value
+	case DeleteOption:
+		inverse.Option = CreateOption
+		inverse.Value = e.OldValue
+	case UpdateOption, UpsertOption:
+		if isNil(e.OldValue) {
+			return Event{}, errors.Errorf("invalid %s event: old value is required", e.ResourceType)
+		}
+		inverse.Option = UpdateOption
+		inverse.OldValue = e.Value
+		inverse.Value = e.OldValue
+	case NoOpOption:
+		inverse = *e
+	}
+	return inverse, nil
+}
+
+// ApplyWithRollback applies the events one by one in the order of SortEvents.
+// When an event fails, the applied events are reverted in reverse order, so
+// that the cluster is not left in a half-applied state. It returns the error
+// of the failed event and the error of the rollback, if any.
+func ApplyWithRollback(ctx context.Context, cluster apisix.Cluster, events []*Event) (applyErr error, rollbackErr error) {
+	var applied []*Event
+	for _, event := range SortEvents(events) {
+		if applyErr = ctx.Err(); applyErr != nil {
+			break
+		}
+		if applyErr = event.Apply(ctx, cluster); applyErr != nil {
+			break
+		}
+		applied = append(applied, event)
+	}
+	if applyErr == nil {
+		return nil, nil
+	}
+
+	return applyErr, revert(applied, cluster)
+}
+
+// Rollback reverts the applied events of the results in reverse order, e.g.
+// the partial state left by ApplyAllWithResults when it aborts. Like
+// ApplyWithRollback, it keeps going on failure and returns the combined
+// errors. The skipped, failed and pending events are not reverted, and the
+// events applied instead of others are reverted, see ApplyResult.Applied.
+func Rollback(cluster apisix.Cluster, results ApplyResults) error {
+	var applied []*Event
+	for _, result := range results {
+		if result.Status != AppliedStatus {
+			continue
+		}
+		if result.Applied != nil {
+			applied = append(applied, result.Applied)
+		} else {
+			applied = append(applied, result.Event)
+		}
+	}
+	return revert(applied, cluster)
+}
+
+// revert applies the inverses of the applied events in reverse order.
+func revert(applied []*Event, cluster apisix.Cluster) (errs error) {
+	// the rollback must not be cancelled with ctx, otherwise the cluster
+	// is left in the half-applied state
+	ctx := context.Background()
+	for i := len(applied) - 1; i >= 0; i-- {
+		// keep going, rollback as much as possible
+		inverse, err := applied[i].Inverse()
+		if err == nil {
+			err = inverse.Apply(ctx, cluster)
+		}
+		errs = multierr.Append(errs, err)
+	}
+	return errs
+}
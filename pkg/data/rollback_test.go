@@ -0,0 +1,171 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestApplyWithRollback(t *testing.T) {
+	upstream := &types.Upstream{
+		ID:    "httpbin",
+		Name:  "httpbin",
+		Nodes: types.UpstreamNodes{{Host: "httpbin.org", Port: 80, Weight: 1}},
+	}
+	upstream1 := &types.Upstream{
+		ID:    "httpbin",
+		Name:  "httpbin",
+		Nodes: types.UpstreamNodes{{Host: "httpbin.org", Port: 80, Weight: 10}},
+	}
+	ssl := &types.SSL{
+		ID:   "ssl",
+		SNIs: []string{"example.com"},
+	}
+
+	cluster := newFakeCluster()
+	cluster.upstream.items["httpbin"] = upstream
+	cluster.ssl.items["ssl"] = ssl
+	route1 := *route
+	route1.Methods = []string{"POST"}
+	events := []*Event{
+		{
+			ResourceType: RouteResourceType,
+			Option:       UpdateOption,
+			OldValue:     route,
+			Value:        &route1,
+		},
+		{
+			ResourceType: ServiceResourceType,
+			Option:       CreateOption,
+			Value:        svc,
+		},
+		{
+			ResourceType: UpstreamResourceType,
+			Option:       UpdateOption,
+			OldValue:     upstream,
+			Value:        upstream1,
+		},
+		{
+			ResourceType: SSLResourceType,
+			Option:       DeleteOption,
+			OldValue:     ssl,
+		},
+	}
+
+	// Test case 1: the route fails, everything applied before is reverted
+	cluster.route.err = errors.New("unexpected status code 400; invalid route")
+	applyErr, rollbackErr := ApplyWithRollback(context.Background(), cluster, events)
+	assert.Equal(t, "failed to apply route: unexpected status code 400; invalid route", applyErr.Error())
+	assert.Nil(t, rollbackErr, "should roll back successfully")
+	assert.Equal(t, []string{"update:httpbin", "update:httpbin"}, cluster.upstream.calls)
+	assert.Equal(t, []string{"create:svc", "delete:svc"}, cluster.service.calls)
+	assert.Equal(t, upstream, cluster.upstream.items["httpbin"], "should restore the old upstream")
+	assert.Empty(t, cluster.service.items, "should delete the created service")
+	assert.Equal(t, ssl, cluster.ssl.items["ssl"], "should recreate the deleted ssl")
+
+	// Test case 2: the service fails, only the deleted ssl is reverted
+	cluster.service.err = errors.New("unexpected status code 503; unavailable")
+	applyErr, rollbackErr = ApplyWithRollback(context.Background(), cluster, events)
+	assert.NotNil(t, applyErr, "should return the apply error")
+	assert.Equal(t, "failed to apply service: unexpected status code 503; unavailable", applyErr.Error())
+	assert.Nil(t, rollbackErr, "should roll back successfully")
+	assert.Equal(t, ssl, cluster.ssl.items["ssl"], "should recreate the deleted ssl")
+
+	// Test case 3: succeeds without rollback
+	cluster = newFakeCluster()
+	cluster.upstream.items["httpbin"] = upstream
+	cluster.ssl.items["ssl"] = ssl
+	cluster.route.items["route"] = route
+	applyErr, rollbackErr = ApplyWithRollback(context.Background(), cluster, events)
+	assert.Nil(t, applyErr)
+	assert.Nil(t, rollbackErr)
+	assert.Equal(t, upstream1, cluster.upstream.items["httpbin"])
+	assert.Empty(t, cluster.ssl.items)
+	assert.Equal(t, &route1, cluster.route.items["route"])
+}
+
+func TestEventInverse(t *testing.T) {
+	route1 := *route
+	route1.Methods = []string{"POST"}
+
+	// Test case 1: the inverse of each option
+	inverse, err := (&Event{ResourceType: RouteResourceType, Option: CreateOption, Value: route}).Inverse()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, Event{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: route}, inverse)
+	inverse, err = (&Event{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: route}).Inverse()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, Event{ResourceType: RouteResourceType, Option: CreateOption, Value: route}, inverse)
+	update := &Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: &route1}
+	inverse, err = update.Inverse()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: &route1, Value: route}, inverse)
+
+	// Test case 2: the inverse of the inverse is the event
+	twice, err := inverse.Inverse()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, *update, twice)
+
+	// Test case 3: invalid events
+	_, err = (&Event{ResourceType: RouteResourceType, Option: UpdateOption, Value: route}).Inverse()
+	assert.EqualError(t, err, "invalid route event: old value is required")
+	_, err = (&Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: (*types.Route)(nil), Value: route}).Inverse()
+	assert.EqualError(t, err, "invalid route event: old value is required")
+	_, err = (&Event{ResourceType: RouteResourceType, Option: CreateOption}).Inverse()
+	assert.EqualError(t, err, "invalid route event: value is required")
+	_, err = (&Event{ResourceType: RouteResourceType, Option: 10, Value: route}).Inverse()
+	assert.EqualError(t, err, "invalid route event: unknown option 10")
+	_, err = (&Event{ResourceType: RouteResourceType, Option: UpsertOption, Value: route}).Inverse()
+	assert.EqualError(t, err, "invalid route event: old value is required", "should not guess whether the upsert created the route")
+
+	// Test case 4: the inverse of an upsert restores the old value
+	inverse, err = (&Event{ResourceType: ServiceResourceType, Option: UpsertOption, OldValue: svc, Value: &types.Service{ID: "svc"}}).Inverse()
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, Event{ResourceType: ServiceResourceType, Option: UpdateOption, OldValue: &types.Service{ID: "svc"}, Value: svc}, inverse)
+}
+
+func TestApplyWithRollbackWithoutOldValue(t *testing.T) {
+	cluster := newFakeCluster()
+	cluster.route.err = errors.New("unexpected status code 400; invalid route")
+	route1 := *route
+	route1.Methods = []string{"POST"}
+	events := []*Event{
+		{ResourceType: ServiceResourceType, Option: UpdateOption, Value: svc},
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: &route1},
+	}
+	applyErr, rollbackErr := ApplyWithRollback(context.Background(), cluster, events)
+	assert.NotNil(t, applyErr, "should return the apply error")
+	assert.EqualError(t, rollbackErr, "invalid service event: old value is required", "should not revert the update")
+	assert.Equal(t, []string{"update:svc"}, cluster.service.calls)
+}
+
+func TestRollback(t *testing.T) {
+	cluster := newFakeCluster()
+	cluster.route.err = errors.New("unexpected status code 400; invalid route")
+	cluster.route.failures = 1
+	route1 := *route
+	route1.ID, route1.Name = "route1", "route1"
+	events := []*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: ConsumerResourceType, Option: UpdateOption, OldValue: consumer, Value: consumer},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: route},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &route1},
+	}
+
+	// Test case 1: the partial state of the aborted apply
+	results, err := ApplyAllWithResults(context.Background(), cluster, events, ApplyOptions{Concurrency: 1})
+	assert.NotNil(t, err, "should return the apply error")
+	assert.Equal(t, []*Event{events[0]}, results.Events(AppliedStatus), "should report the applied service")
+	assert.Equal(t, []*Event{events[3], events[1]}, results.Events(PendingStatus), "should report the remaining events")
+	assert.Contains(t, cluster.service.items, "svc")
+
+	// Test case 2: only the applied events are reverted
+	assert.Nil(t, Rollback(cluster, results), "should roll back successfully")
+	assert.Empty(t, cluster.service.items, "should delete the created service")
+	assert.Equal(t, []string{"create:svc", "delete:svc"}, cluster.service.calls)
+	assert.Empty(t, cluster.consumer.calls, "should not revert the pending consumer")
+	assert.Equal(t, []string{"create:route"}, cluster.route.calls, "should not revert the failed route")
+}
@@ -0,0 +1,105 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// _hostRegex is the host pattern of the route schema of APISIX, a host may
+// start with a wildcard, e.g. "*.example.com".
+var _hostRegex = regexp.MustCompile(`^\*?[0-9a-zA-Z-._\[\]:]+$`)
+
+// validateRoute checks the uris, hosts and vars of the route, the errors
+// point at the bad field.
+func validateRoute(route *types.Route) error {
+	if route.Uri == "" && len(route.Uris) == 0 {
+		return errors.New("uri or uris is required")
+	}
+	if route.Uri != "" {
+		if err := validateURI(route.Uri); err != nil {
+			return errors.Wrap(err, "uri")
+		}
+	}
+	for i, uri := range route.Uris {
+		if err := validateURI(uri); err != nil {
+			return errors.Wrapf(err, "uris[%d]", i)
+		}
+	}
+
+	if route.Host != "" && !_hostRegex.MatchString(route.Host) {
+		return errors.Errorf("host: invalid host \"%s\"", route.Host)
+	}
+	for i, host := range route.Hosts {
+		if !_hostRegex.MatchString(host) {
+			return errors.Errorf("hosts[%d]: invalid host \"%s\"", i, host)
+		}
+	}
+
+	for i, expr := range route.Vars {
+		if err := validateVar(expr); err != nil {
+			return errors.Wrapf(err, "vars[%d]", i)
+		}
+	}
+	return nil
+}
+
+func validateURI(uri string) error {
+	if uri == "" {
+		return errors.New("must not be empty")
+	}
+	if !strings.HasPrefix(uri, "/") {
+		return errors.Errorf("\"%s\" must start with \"/\"", uri)
+	}
+	return nil
+}
+
+// validateVar checks the regex of the expression [var, operator, value] or
+// [var, "!", operator, value] compiles.
+func validateVar(expr []types.StringOrSlice) error {
+	if len(expr) > 0 {
+		switch expr[0].StrVal {
+		case "AND", "OR", "!AND", "!OR":
+			// the nested expressions of logical operators are not checked
+			return nil
+		}
+	}
+	if len(expr) < 2 {
+		return errors.Errorf("expression must have at least 2 elements, got %d", len(expr))
+	}
+
+	op, rest := expr[1].StrVal, expr[2:]
+	if op == "!" && len(rest) > 0 {
+		op, rest = rest[0].StrVal, rest[1:]
+	}
+	if op != "~~" && op != "~*" {
+		return nil
+	}
+	if len(rest) != 1 {
+		return errors.Errorf("operator \"%s\" requires a regex", op)
+	}
+	if err := validateRegex(rest[0].StrVal); err != nil {
+		return errors.Wrapf(err, "invalid regex \"%s\"", rest[0].StrVal)
+	}
+	return nil
+}
+
+// validateRegex checks the regex can be compiled. APISIX uses PCRE, so the
+// PCRE features RE2 doesn't support, such as lookarounds, are accepted.
+func validateRegex(expr string) error {
+	_, err := syntax.Parse(expr, syntax.Perl)
+	var syntaxErr *syntax.Error
+	if errors.As(err, &syntaxErr) {
+		switch syntaxErr.Code {
+		case syntax.ErrInvalidPerlOp, syntax.ErrInvalidEscape:
+			return nil
+		}
+		return fmt.Errorf("%s: %s", syntaxErr.Code, syntaxErr.Expr)
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func vars(exprs ...[]string) types.Vars {
+	var result types.Vars
+	for _, expr := range exprs {
+		var elems []types.StringOrSlice
+		for _, elem := range expr {
+			elems = append(elems, types.StringOrSlice{StrVal: elem})
+		}
+		result = append(result, elems)
+	}
+	return result
+}
+
+func TestRouteValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		route *types.Route
+		err   string
+	}{
+		{
+			name: "valid",
+			route: &types.Route{
+				ID:    "route",
+				Uris:  []string{"/get", "/anything/*"},
+				Hosts: []string{"example.com", "*.example.com", "127.0.0.1:9080"},
+				Vars: vars(
+					[]string{"arg_name", "==", "json"},
+					[]string{"uri", "~~", "^/api/v[0-9]+/"},
+					[]string{"http_user_agent", "!", "~*", "curl"},
+					// lookarounds are supported by PCRE
+					[]string{"uri", "~~", "^/(?!internal)"},
+				),
+			},
+		},
+		{
+			name:  "empty uri",
+			route: &types.Route{ID: "route", Uris: []string{"/get", ""}},
+			err:   "invalid route event: uris[1]: must not be empty",
+		},
+		{
+			name:  "relative uri",
+			route: &types.Route{ID: "route", Uri: "get"},
+			err:   "invalid route event: uri: \"get\" must start with \"/\"",
+		},
+		{
+			name:  "invalid host",
+			route: &types.Route{ID: "route", Uri: "/get", Hosts: []string{"example.com", "ex ample.com"}},
+			err:   "invalid route event: hosts[1]: invalid host \"ex ample.com\"",
+		},
+		{
+			name:  "wildcard in the middle",
+			route: &types.Route{ID: "route", Uri: "/get", Host: "api.*.example.com"},
+			err:   "invalid route event: host: invalid host \"api.*.example.com\"",
+		},
+		{
+			name:  "invalid regex",
+			route: &types.Route{ID: "route", Uri: "/get", Vars: vars([]string{"arg_name", "==", "json"}, []string{"uri", "~~", "^/api/(v1"})},
+			err:   "invalid route event: vars[1]: invalid regex \"^/api/(v1\": missing closing ): ^/api/(v1",
+		},
+		{
+			name:  "negated invalid regex",
+			route: &types.Route{ID: "route", Uri: "/get", Vars: vars([]string{"uri", "!", "~*", "*.json"})},
+			err:   "invalid route event: vars[0]: invalid regex \"*.json\": missing argument to repetition operator: *",
+		},
+		{
+			name:  "missing regex",
+			route: &types.Route{ID: "route", Uri: "/get", Vars: vars([]string{"uri", "~~"})},
+			err:   "invalid route event: vars[0]: operator \"~~\" requires a regex",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Event{ResourceType: RouteResourceType, Option: CreateOption, Value: tt.route}).Validate()
+			if tt.err == "" {
+				assert.Nil(t, err, "should not return error")
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
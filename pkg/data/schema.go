@@ -0,0 +1,152 @@
+package data
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+	"go.uber.org/multierr"
+)
+
+//go:embed schemas/apisix.json
+var _schemas []byte
+
+var (
+	schemasOnce sync.Once
+	schemas     map[ResourceType]*gojsonschema.Schema
+	schemasErr  error
+)
+
+// loadSchemas compiles the embedded schema of every resource type, the
+// resource types share the definitions of the same document.
+func loadSchemas() (map[ResourceType]*gojsonschema.Schema, error) {
+	schemasOnce.Do(func() {
+		var doc map[string]interface{}
+		if schemasErr = json.Unmarshal(_schemas, &doc); schemasErr != nil {
+			return
+		}
+		schemas = make(map[ResourceType]*gojsonschema.Schema, len(identifiers))
+		for typ := range identifiers {
+			root := map[string]interface{}{
+				"definitions": doc["definitions"],
+				"$ref":        "#/definitions/" + string(typ),
+			}
+			schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(root))
+			if err != nil {
+				schemasErr = errors.Wrapf(err, "invalid schema of %s", typ)
+				return
+			}
+			schemas[typ] = schema
+		}
+	})
+	return schemas, schemasErr
+}
+
+// SchemaViolation is a field of a value violating the schema.
+type SchemaViolation struct {
+	// Field is the path of the field, e.g. "upstream.nodes.0.port", or
+	// "(root)" for the value itself.
+	Field   string
+	Message string
+}
+
+// SchemaError is the error of a value violating the schema of its
+// resource type.
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", v.Field, v.Message))
+	}
+	return "schema violations: " + strings.Join(msgs, "; ")
+}
+
+// ValidateSchema validates the value of the event against the embedded
+// schema of its resource type, a subset of the APISIX schemas. It catches
+// the errors the types can't, e.g. an out-of-range port or an unknown HTTP
+// method. Deletes are not validated. The returned error wraps a
+// SchemaError listing every violation.
+func (e *Event) ValidateSchema() error {
+	if err := e.validateSchema(); err != nil {
+		return errors.Wrapf(err, "invalid %s event", e.ResourceType)
+	}
+	return nil
+}
+
+func (e *Event) validateSchema() error {
+	if e.Option == DeleteOption {
+		return nil
+	}
+	all, err := loadSchemas()
+	if err != nil {
+		return err
+	}
+	schema, ok := all[e.ResourceType]
+	if !ok {
+		return fmt.Errorf("unsupported resource type %q", e.ResourceType)
+	}
+	value, err := normalize(e.Value)
+	if err != nil {
+		return err
+	}
+	result, err := schema.Validate(gojsonschema.NewGoLoader(omitEmpty(value)))
+	if err != nil {
+		return err
+	}
+	return resultError(result)
+}
+
+// resultError returns the SchemaError of the result, or nil if it is valid.
+func resultError(result *gojsonschema.Result) error {
+	if result.Valid() {
+		return nil
+	}
+	schemaErr := &SchemaError{}
+	for _, re := range result.Errors() {
+		schemaErr.Violations = append(schemaErr.Violations, SchemaViolation{
+			Field:   re.Field(),
+			Message: re.Description(),
+		})
+	}
+	return schemaErr
+}
+
+// omitEmpty removes the null and empty string fields of the objects,
+// nested ones included, the types marshal the unset fields without
+// omitempty like that.
+func omitEmpty(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if field == nil || field == "" {
+				delete(v, k)
+				continue
+			}
+			v[k] = omitEmpty(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = omitEmpty(item)
+		}
+	}
+	return value
+}
+
+// ValidateSchemas validates all the events with Event.ValidateSchema, the
+// returned error combines an EventError for every invalid event.
+func ValidateSchemas(events []*Event) error {
+	var errs error
+	for _, event := range events {
+		if err := event.validateSchema(); err != nil {
+			errs = multierr.Append(errs, &EventError{Event: event, Err: err})
+		}
+	}
+	return errs
+}
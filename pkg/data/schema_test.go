@@ -0,0 +1,74 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestValidateSchema(t *testing.T) {
+	// Test case 1: valid resources of every type
+	valid := []*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: route},
+		{ResourceType: ConsumerResourceType, Option: CreateOption, Value: consumer},
+		{ResourceType: SSLResourceType, Option: CreateOption, Value: &types.SSL{ID: "ssl", SNIs: []string{"*.example.com"}, Cert: "cert", Key: "key"}},
+		{ResourceType: GlobalRuleResourceType, Option: CreateOption, Value: &types.GlobalRule{ID: "prometheus", Plugins: types.Plugins{"prometheus": {}}}},
+		{ResourceType: PluginConfigResourceType, Option: CreateOption, Value: &types.PluginConfig{ID: "cors", Plugins: types.Plugins{"cors": {}}}},
+		{ResourceType: ConsumerGroupResourceType, Option: CreateOption, Value: &types.ConsumerGroup{ID: "group", Plugins: types.Plugins{}}},
+		{ResourceType: PluginMetadataResourceType, Option: CreateOption, Value: &types.PluginMetadata{ID: "http-logger"}},
+		{ResourceType: StreamRouteResourceType, Option: CreateOption, Value: &types.StreamRoute{ID: "stream", ServerPort: 9100}},
+		{ResourceType: UpstreamResourceType, Option: CreateOption, Value: &types.Upstream{ID: "ups", Nodes: types.UpstreamNodes{{Host: "httpbin.org", Port: 80, Weight: 1}}}},
+		{ResourceType: SecretResourceType, Option: CreateOption, Value: &types.Secret{ID: "vault/1"}},
+		{ResourceType: ProtoResourceType, Option: CreateOption, Value: &types.Proto{ID: "proto", Content: "syntax = \"proto3\";"}},
+		{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: &types.Route{ID: "invalid id"}},
+	}
+	for _, event := range valid {
+		assert.Nil(t, event.ValidateSchema(), "%s should be valid", event.ResourceType)
+	}
+	assert.Nil(t, ValidateSchemas(valid))
+
+	// Test case 2: the violations of each field are reported
+	invalid := &Event{
+		ResourceType: RouteResourceType,
+		Option:       CreateOption,
+		Value: &types.Route{
+			ID:      "users route",
+			Uris:    []string{"/get", ""},
+			Methods: []string{"GET", "FETCH"},
+			Upstream: &types.Upstream{
+				Nodes:    types.UpstreamNodes{{Host: "httpbin.org", Port: 65536}},
+				PassHost: "proxy",
+			},
+		},
+	}
+	err := invalid.ValidateSchema()
+	var schemaErr *SchemaError
+	assert.True(t, errors.As(err, &schemaErr), "should be a schema error")
+	fields := map[string]string{}
+	for _, v := range schemaErr.Violations {
+		fields[v.Field] = v.Message
+	}
+	assert.Contains(t, fields, "id")
+	assert.Contains(t, fields, "uris.1")
+	assert.Contains(t, fields, "methods.1")
+	assert.Contains(t, fields, "upstream.nodes.0.port")
+	assert.Contains(t, fields, "upstream.pass_host")
+	assert.Len(t, fields, 5, "should not report the unset fields")
+	assert.Contains(t, err.Error(), "invalid route event: schema violations: ")
+	assert.Contains(t, err.Error(), "upstream.nodes.0.port: Must be less than or equal to 65535")
+
+	// Test case 3: required fields
+	err = (&Event{ResourceType: ProtoResourceType, Option: CreateOption, Value: &types.Proto{ID: "proto"}}).ValidateSchema()
+	assert.EqualError(t, err, "invalid proto event: schema violations: (root): content is required")
+
+	// Test case 4: nothing is applied if some events are invalid
+	cluster := newFakeCluster()
+	err = ApplyAllWithOptions(context.Background(), cluster, append(routeEvents(2), invalid), ApplyOptions{ValidateSchema: true})
+	assert.Contains(t, err.Error(), "route \"users route\": schema violations: ")
+	assert.Empty(t, cluster.route.calls, "should not apply any event")
+}
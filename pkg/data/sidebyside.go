@@ -0,0 +1,137 @@
+package data
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+)
+
+// DefaultWidth is the width of side-by-side diffs if OutputOptions.Width
+// is not set, e.g. when the output is not a terminal.
+const DefaultWidth = 160
+
+// minColumnWidth is the narrowest column of side-by-side diffs, the unified
+// diff is printed instead in narrower widths.
+const minColumnWidth = 30
+
+// The markers between the columns of side-by-side diffs, like sdiff.
+const (
+	markerEqual    = ' '
+	markerChanged  = '|'
+	markerDeleted  = '<'
+	markerInserted = '>'
+)
+
+// writeDiff writes the diff of both sides of an update, side by side if the
+// options ask for it and the width allows it, unified otherwise.
+func writeDiff(w io.Writer, remote, local []byte, opts *OutputOptions) error {
+	if opts.SideBySide {
+		if ok, err := writeSideBySideDiff(w, remote, local, opts); ok || err != nil {
+			return err
+		}
+	}
+	return writeUnifiedDiff(w, remote, local, opts)
+}
+
+// writeSideBySideDiff writes the diff of both sides of an update in two
+// columns, the remote value on the left and the local one on the right
+// under a header, with the changed lines on the same rows. Every hunk
+// starts with the line numbers of both sides. The lines longer than the
+// columns are truncated. It returns false without writing anything if the
+// width is too narrow for two columns.
+func writeSideBySideDiff(w io.Writer, remote, local []byte, opts *OutputOptions) (bool, error) {
+	width := opts.Width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	// the marker and the spaces around it separate the columns
+	column := (width - 3) / 2
+	if column < minColumnWidth {
+		return false, nil
+	}
+
+	edits := myers.ComputeEdits(span.URIFromPath("remote"), string(remote), string(local))
+	unified := toUnified("remote", "local", string(remote), edits, opts.contextLines())
+	color := opts.Color && !noColor()
+
+	row := func(left, right string, marker rune) error {
+		l, r := fitColumn(left, column), strings.TrimRight(fitColumn(right, column), " ")
+		if color {
+			if marker == markerChanged || marker == markerDeleted {
+				l = paint(ansiRed, l)
+			}
+			if (marker == markerChanged || marker == markerInserted) && r != "" {
+				r = paint(ansiGreen, r)
+			}
+		}
+		_, err := fmt.Fprintln(w, strings.TrimRight(fmt.Sprintf("%s %c %s", l, marker, r), " "))
+		return err
+	}
+	if len(unified.Hunks) > 0 {
+		if _, err := fmt.Fprintln(w, fitColumn("remote", column)+"   local"); err != nil {
+			return true, err
+		}
+	}
+	for _, hunk := range unified.Hunks {
+		if _, err := fmt.Fprintf(w, "@@ -%d +%d @@\n", hunk.FromLine, hunk.ToLine); err != nil {
+			return true, err
+		}
+		var deleted, inserted []string
+		flush := func() error {
+			defer func() { deleted, inserted = nil, nil }()
+			for i := 0; i < len(deleted) || i < len(inserted); i++ {
+				var err error
+				switch {
+				case i >= len(inserted):
+					err = row(deleted[i], "", markerDeleted)
+				case i >= len(deleted):
+					err = row("", inserted[i], markerInserted)
+				default:
+					err = row(deleted[i], inserted[i], markerChanged)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for _, line := range hunk.Lines {
+			// unmarked before fitting the columns, see unmarkChanged
+			content := unmarkChanged(strings.TrimSuffix(line.Content, "\n"))
+			switch line.Kind {
+			case gotextdiff.Delete:
+				deleted = append(deleted, content)
+			case gotextdiff.Insert:
+				inserted = append(inserted, content)
+			default:
+				if err := flush(); err != nil {
+					return true, err
+				}
+				if err := row(content, content, markerEqual); err != nil {
+					return true, err
+				}
+			}
+		}
+		if err := flush(); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// fitColumn pads or truncates the line to the width of the column, with
+// the tabs of the indentation expanded.
+func fitColumn(line string, width int) string {
+	line = strings.ReplaceAll(line, "\t", "  ")
+	n := utf8.RuneCountInString(line)
+	if n <= width {
+		return line + strings.Repeat(" ", width-n)
+	}
+	runes := []rune(line)
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// SSLExpiryWarning is how long before the expiry of a certificate
+// Event.Warnings starts to warn about it.
+var SSLExpiryWarning = 30 * 24 * time.Hour
+
+// keyPairs returns the cert and key pairs of the SSL.
+func keyPairs(ssl *types.SSL) ([][2]string, error) {
+	if len(ssl.Certs) != len(ssl.Keys) {
+		return nil, errors.Errorf("certs and keys must have the same length, got %d certs and %d keys", len(ssl.Certs), len(ssl.Keys))
+	}
+	pairs := [][2]string{{ssl.Cert, ssl.Key}}
+	for i := range ssl.Certs {
+		pairs = append(pairs, [2]string{ssl.Certs[i], ssl.Keys[i]})
+	}
+	return pairs, nil
+}
+
+// isSecretRef reports whether the value refers to a secret or an
+// environment variable, which is resolved by APISIX.
+func isSecretRef(value string) bool {
+	return strings.HasPrefix(value, "$secret://") || strings.HasPrefix(value, "$env://")
+}
+
+// validateSSL checks every cert of the SSL matches its key and isn't expired.
+func validateSSL(ssl *types.SSL) error {
+	pairs, err := keyPairs(ssl)
+	if err != nil {
+		return err
+	}
+	for i, pair := range pairs {
+		field := "cert"
+		if i > 0 {
+			field = fmt.Sprintf("certs[%d]", i-1)
+		}
+		leaf, err := parseKeyPair(pair[0], pair[1])
+		if err != nil {
+			return errors.Wrap(err, field)
+		}
+		if leaf != nil && time.Now().After(leaf.NotAfter) {
+			return errors.Errorf("%s: certificate expired at %s", field, leaf.NotAfter.UTC().Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// parseKeyPair checks the PEM encoded cert matches the key and returns the
+// certificate. The pair isn't checked if either of them is a secret
+// reference, nil is returned then.
+func parseKeyPair(cert, key string) (*x509.Certificate, error) {
+	if isSecretRef(cert) || isSecretRef(key) {
+		return nil, nil
+	}
+
+	block, _ := pem.Decode([]byte(cert))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("invalid PEM encoded certificate")
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid certificate")
+	}
+	if _, err := tls.X509KeyPair([]byte(cert), []byte(key)); err != nil {
+		return nil, errors.Wrap(err, "invalid key pair")
+	}
+	return leaf, nil
+}
+
+// Warnings returns the problems of the event that APISIX doesn't reject,
+// i.e. a certificate expired or expiring in SSLExpiryWarning.
+func (e *Event) Warnings() []string {
+	ssl, ok := e.Value.(*types.SSL)
+	if !ok || ssl == nil || e.Option == DeleteOption {
+		return nil
+	}
+	pairs, err := keyPairs(ssl)
+	if err != nil {
+		return nil
+	}
+
+	var warnings []string
+	now := time.Now()
+	for _, pair := range pairs {
+		leaf, err := parseKeyPair(pair[0], pair[1])
+		if err != nil || leaf == nil {
+			continue
+		}
+		expiry := leaf.NotAfter.UTC().Format(time.RFC3339)
+		switch left := leaf.NotAfter.Sub(now); {
+		case left < 0:
+			warnings = append(warnings, fmt.Sprintf("ssl \"%s\": certificate of %s expired at %s", ssl.ID, leaf.Subject.CommonName, expiry))
+		case left < SSLExpiryWarning:
+			warnings = append(warnings, fmt.Sprintf("ssl \"%s\": certificate of %s expires in %d days, at %s", ssl.ID, leaf.Subject.CommonName, int(left.Hours()/24), expiry))
+		}
+	}
+	return warnings
+}
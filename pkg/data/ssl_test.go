@@ -0,0 +1,121 @@
+package data
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// generateKeyPair returns a PEM encoded self-signed certificate of the
+// common name valid until notAfter, and its private key.
+func generateKeyPair(t *testing.T, commonName string, notAfter time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "should generate key")
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err, "should create certificate")
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err, "should marshal key")
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return string(cert), string(keyPEM)
+}
+
+func TestSSLValidate(t *testing.T) {
+	year := time.Now().Add(365 * 24 * time.Hour)
+	cert, key := generateKeyPair(t, "example.com", year)
+	otherCert, otherKey := generateKeyPair(t, "other.example.com", year)
+	expiredCert, expiredKey := generateKeyPair(t, "expired.example.com", time.Now().Add(-time.Hour))
+
+	tests := []struct {
+		name string
+		ssl  *types.SSL
+		err  string
+	}{
+		{
+			name: "valid",
+			ssl:  &types.SSL{ID: "ssl", SNIs: []string{"example.com"}, Cert: cert, Key: key},
+		},
+		{
+			name: "valid certs",
+			ssl:  &types.SSL{ID: "ssl", Cert: cert, Key: key, Certs: []string{otherCert}, Keys: []string{otherKey}},
+		},
+		{
+			name: "secret references",
+			ssl:  &types.SSL{ID: "ssl", Cert: "$secret://vault/1/ssl/cert", Key: "$env://SSL_KEY"},
+		},
+		{
+			name: "mismatched pair",
+			ssl:  &types.SSL{ID: "ssl", Cert: cert, Key: otherKey},
+			err:  "invalid ssl event: cert: invalid key pair: tls: private key does not match public key",
+		},
+		{
+			name: "mismatched certs",
+			ssl:  &types.SSL{ID: "ssl", Cert: cert, Key: key, Certs: []string{otherCert}, Keys: []string{key}},
+			err:  "invalid ssl event: certs[0]: invalid key pair: tls: private key does not match public key",
+		},
+		{
+			name: "certs without keys",
+			ssl:  &types.SSL{ID: "ssl", Cert: cert, Key: key, Certs: []string{otherCert}},
+			err:  "invalid ssl event: certs and keys must have the same length, got 1 certs and 0 keys",
+		},
+		{
+			name: "invalid cert",
+			ssl:  &types.SSL{ID: "ssl", Cert: "not a certificate", Key: key},
+			err:  "invalid ssl event: cert: invalid PEM encoded certificate",
+		},
+		{
+			name: "expired",
+			ssl:  &types.SSL{ID: "ssl", Cert: expiredCert, Key: expiredKey},
+			err:  "invalid ssl event: cert: certificate expired at ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Event{ResourceType: SSLResourceType, Option: CreateOption, Value: tt.ssl}).Validate()
+			if tt.err == "" {
+				assert.Nil(t, err, "should not return error")
+			} else {
+				assert.NotNil(t, err, "should return error")
+				assert.Contains(t, err.Error(), tt.err)
+			}
+		})
+	}
+}
+
+func TestSSLWarnings(t *testing.T) {
+	cert, key := generateKeyPair(t, "example.com", time.Now().Add(365*24*time.Hour))
+	soonCert, soonKey := generateKeyPair(t, "soon.example.com", time.Now().Add(10*24*time.Hour+time.Hour))
+	expiredCert, expiredKey := generateKeyPair(t, "expired.example.com", time.Now().Add(-time.Hour))
+
+	// Test case 1: no warnings
+	event := &Event{ResourceType: SSLResourceType, Option: CreateOption, Value: &types.SSL{ID: "ssl", Cert: cert, Key: key}}
+	assert.Nil(t, event.Warnings())
+
+	// Test case 2: expiring and expired certificates
+	event.Value = &types.SSL{ID: "ssl", Cert: soonCert, Key: soonKey, Certs: []string{expiredCert}, Keys: []string{expiredKey}}
+	warnings := event.Warnings()
+	assert.Len(t, warnings, 2)
+	assert.Contains(t, warnings[0], "ssl \"ssl\": certificate of soon.example.com expires in 10 days, at ")
+	assert.Contains(t, warnings[1], "ssl \"ssl\": certificate of expired.example.com expired at ")
+
+	// Test case 3: other resources
+	assert.Nil(t, (&Event{ResourceType: RouteResourceType, Option: CreateOption, Value: route}).Warnings())
+}
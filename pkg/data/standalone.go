@@ -0,0 +1,67 @@
+package data
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// standaloneEnd is the marker APISIX requires at the end of the apisix.yaml
+// of the standalone mode, so that it doesn't load a partially written file.
+const standaloneEnd = "#END\n"
+
+// standaloneConfig is the apisix.yaml of APISIX in the standalone mode, the
+// flat lists of the resources by type.
+type standaloneConfig struct {
+	Routes         []*types.Route          `json:"routes,omitempty"`
+	Services       []*types.Service        `json:"services,omitempty"`
+	Upstreams      []*types.Upstream       `json:"upstreams,omitempty"`
+	Consumers      []*types.Consumer       `json:"consumers,omitempty"`
+	ConsumerGroups []*types.ConsumerGroup  `json:"consumer_groups,omitempty"`
+	SSLs           []*types.SSL            `json:"ssls,omitempty"`
+	GlobalRules    []*types.GlobalRule     `json:"global_rules,omitempty"`
+	PluginConfigs  []*types.PluginConfig   `json:"plugin_configs,omitempty"`
+	PluginMetadata []*types.PluginMetadata `json:"plugin_metadata,omitempty"`
+	StreamRoutes   []*types.StreamRoute    `json:"stream_routes,omitempty"`
+	Secrets        []*types.Secret         `json:"secrets,omitempty"`
+	Protos         []*types.Proto          `json:"protos,omitempty"`
+}
+
+// WriteStandaloneConfig writes the apisix.yaml of APISIX in the standalone
+// mode with the resources of the events to w, instead of applying them with
+// the admin API. Like ToConfiguration, the values of the events are written
+// and deletes are omitted, so the events must describe all the resources of
+// the cluster, e.g. the ones of LoadConfiguration.
+func WriteStandaloneConfig(w io.Writer, events []*Event) error {
+	conf, err := ToConfiguration(events)
+	if err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(&standaloneConfig{
+		Routes:         conf.Routes,
+		Services:       conf.Services,
+		Upstreams:      conf.Upstreams,
+		Consumers:      conf.Consumers,
+		ConsumerGroups: conf.ConsumerGroups,
+		SSLs:           conf.SSLs,
+		GlobalRules:    conf.GlobalRules,
+		PluginConfigs:  conf.PluginConfigs,
+		PluginMetadata: conf.PluginMetadatas,
+		StreamRoutes:   conf.StreamRoutes,
+		Secrets:        conf.Secrets,
+		Protos:         conf.Protos,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the standalone configuration")
+	}
+	if string(out) == "{}\n" {
+		out = nil
+	}
+
+	_, err = w.Write(append(out, standaloneEnd...))
+	return err
+}
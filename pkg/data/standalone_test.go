@@ -0,0 +1,48 @@
+package data
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestWriteStandaloneConfig(t *testing.T) {
+	events := []*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: &types.Route{ID: "route"}, Value: route},
+		{ResourceType: ConsumerResourceType, Option: DeleteOption, OldValue: consumer},
+		{ResourceType: PluginMetadataResourceType, Option: CreateOption, Value: &types.PluginMetadata{ID: "http-logger", Config: map[string]interface{}{"log_format": map[string]interface{}{"host": "$host"}}}},
+	}
+
+	// Test case 1: the flat lists end with the marker, deletes are omitted
+	var buf bytes.Buffer
+	err := WriteStandaloneConfig(&buf, events)
+	assert.Nil(t, err, "should not return error")
+	out := buf.String()
+	assert.True(t, strings.HasSuffix(out, "\n#END\n"), "should end with the marker")
+	assert.NotContains(t, out, "jack", "should not contain deleted resources")
+	assert.NotContains(t, out, "version:", "should not contain the fields of the declarative configuration")
+
+	var conf map[string][]map[string]interface{}
+	assert.Nil(t, yaml.Unmarshal(buf.Bytes(), &conf), "should be valid YAML")
+	assert.Len(t, conf, 3)
+	assert.Equal(t, "svc", conf["services"][0]["id"])
+	assert.Equal(t, "route", conf["routes"][0]["id"])
+	assert.Equal(t, "svc", conf["routes"][0]["service_id"])
+	assert.Equal(t, "http-logger", conf["plugin_metadata"][0]["id"])
+
+	// Test case 2: no resources
+	buf.Reset()
+	err = WriteStandaloneConfig(&buf, []*Event{{ResourceType: ConsumerResourceType, Option: DeleteOption, OldValue: consumer}})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, "#END\n", buf.String())
+
+	// Test case 3: invalid events
+	err = WriteStandaloneConfig(&buf, []*Event{{ResourceType: RouteResourceType, Option: CreateOption, Value: svc}})
+	assert.EqualError(t, err, "invalid route event: unexpected value *types.Service")
+}
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"fmt"
+)
+
+// Counts are the numbers of events by option.
+type Counts struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Deleted int `json:"deleted"`
+}
+
+func (c *Counts) add(option Option) {
+	switch option {
+	case CreateOption:
+		c.Created++
+	case UpdateOption:
+		c.Updated++
+	case DeleteOption:
+		c.Deleted++
+	}
+}
+
+// String returns the counts like "3 created, 5 updated, 1 deleted".
+func (c Counts) String() string {
+	return fmt.Sprintf("%d created, %d updated, %d deleted", c.Created, c.Updated, c.Deleted)
+}
+
+// Summary is the summary of a batch of events.
+type Summary struct {
+	// Counts are the totals of all resource types.
+	Counts
+	// ByType are the counts of each resource type.
+	ByType map[ResourceType]Counts `json:"by_type"`
+}
+
+// Summarize counts the events by resource type and option, upserts are
+// counted as updates if their old value is set and as creates otherwise.
+func Summarize(events []*Event) Summary {
+	summary := Summary{
+		ByType: make(map[ResourceType]Counts),
+	}
+	for _, event := range events {
+		summary.Counts.add(event.resolvedOption())
+		counts := summary.ByType[event.ResourceType]
+		counts.add(event.resolvedOption())
+		summary.ByType[event.ResourceType] = counts
+	}
+	return summary
+}
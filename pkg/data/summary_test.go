@@ -0,0 +1,33 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	events := append(routeEvents(3), []*Event{
+		{ResourceType: ServiceResourceType, Option: UpdateOption, OldValue: svc, Value: svc},
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: route},
+		{ResourceType: ConsumerResourceType, Option: DeleteOption, OldValue: consumer},
+	}...)
+
+	summary := Summarize(events)
+	assert.Equal(t, Counts{Created: 3, Updated: 2, Deleted: 1}, summary.Counts)
+	assert.Equal(t, map[ResourceType]Counts{
+		RouteResourceType:    {Created: 3, Updated: 1},
+		ServiceResourceType:  {Updated: 1},
+		ConsumerResourceType: {Deleted: 1},
+	}, summary.ByType)
+	assert.Equal(t, "3 created, 2 updated, 1 deleted", summary.String())
+	assert.Equal(t, "3 created, 1 updated, 0 deleted", summary.ByType[RouteResourceType].String())
+
+	out, err := json.Marshal(summary)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, `{"created":3,"updated":2,"deleted":1,"by_type":{"consumer":{"created":0,"updated":0,"deleted":1},"route":{"created":3,"updated":1,"deleted":0},"service":{"created":0,"updated":1,"deleted":0}}}`, string(out))
+
+	// empty batch
+	assert.Equal(t, "0 created, 0 updated, 0 deleted", Summarize(nil).String())
+}
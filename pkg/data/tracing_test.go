@@ -0,0 +1,74 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/tracing"
+)
+
+// fakeSpan records the attributes of a span and its parent.
+type fakeSpan struct {
+	name   string
+	attrs  map[string]string
+	err    error
+	parent *fakeSpan
+}
+
+func (s *fakeSpan) SetAttributes(attrs ...tracing.Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+func (s *fakeSpan) RecordError(err error) { s.err = err }
+func (s *fakeSpan) End()                  {}
+
+type spanKey struct{}
+
+type fakeTracer struct {
+	mu    sync.Mutex
+	spans []*fakeSpan
+}
+
+func (t *fakeTracer) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parent, _ := ctx.Value(spanKey{}).(*fakeSpan)
+	span := &fakeSpan{name: name, attrs: map[string]string{}, parent: parent}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func TestApplyAllTracing(t *testing.T) {
+	tracer := &fakeTracer{}
+	ctx := tracing.ContextWithTracer(context.Background(), tracer)
+	cluster := newFakeCluster()
+	cluster.route.err = errors.New("unexpected status code 400")
+	cluster.route.failures = 1
+
+	err := ApplyAllWithOptions(ctx, cluster, routeEvents(2), ApplyOptions{ContinueOnError: true})
+	assert.NotNil(t, err, "should return error")
+
+	assert.Len(t, tracer.spans, 3, "should start a span per event")
+	root := tracer.spans[0]
+	assert.Equal(t, "adc.apply_all", root.name)
+	assert.Equal(t, "2", root.attrs["adc.events"])
+	assert.NotNil(t, root.err, "should record the error")
+
+	var failed int
+	for _, span := range tracer.spans[1:] {
+		assert.Equal(t, "adc.apply", span.name)
+		assert.Equal(t, root, span.parent, "should be a child of the apply_all span")
+		assert.Equal(t, "route", span.attrs["adc.resource_type"])
+		assert.Equal(t, "create", span.attrs["adc.option"])
+		assert.Contains(t, []string{"route0", "route1"}, span.attrs["adc.name"])
+		if span.err != nil {
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed, "should record the error of the failed event")
+}
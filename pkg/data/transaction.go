@@ -0,0 +1,69 @@
+package data
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix"
+)
+
+// Transactor is implemented by the clusters able to apply many events in a
+// single transaction, APISIX itself doesn't support it.
+type Transactor interface {
+	// ApplyTransaction applies all the events in order, or none of them if
+	// it returns an error.
+	ApplyTransaction(ctx context.Context, events []*Event) error
+}
+
+// ApplyAtomic applies the events sorted with SortEvents all or nothing. When
+// the cluster implements Transactor, or wraps one that does, they are
+// applied in a single transaction after being validated, see Event.Validate,
+// and checked against the version of the cluster, and no-op updates are
+// left out. Other clusters apply them with ApplyWithRollback, which
+// reverts the applied events on failure on a best-effort basis, rollbackErr
+// is only returned in this case.
+func ApplyAtomic(ctx context.Context, cluster apisix.Cluster, events []*Event) (applyErr error, rollbackErr error) {
+	transactor, ok := findCluster[Transactor](cluster)
+	if !ok {
+		return ApplyWithRollback(ctx, cluster, events)
+	}
+	return applyTransaction(ctx, cluster, transactor, events), nil
+}
+
+func applyTransaction(ctx context.Context, cluster apisix.Cluster, transactor Transactor, events []*Event) error {
+	var (
+		errs    error
+		applied []*Event
+	)
+	for _, event := range SortEvents(events) {
+		if err := event.Validate(); err != nil {
+			errs = multierr.Append(errs, &EventError{Event: event, Err: err})
+			continue
+		}
+		if err := event.checkVersion(cluster); err != nil {
+			errs = multierr.Append(errs, &EventError{Event: event, Err: errors.Wrapf(err, "failed to apply %s", event.ResourceType)})
+			continue
+		}
+		noop, err := event.IsNoOp()
+		if err != nil {
+			errs = multierr.Append(errs, &EventError{Event: event, Err: err})
+			continue
+		}
+		if !noop {
+			applied = append(applied, event)
+		}
+	}
+	if errs != nil || len(applied) == 0 {
+		return errs
+	}
+
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "cancelled before applying the transaction")
+	}
+	if err := transactor.ApplyTransaction(ctx, applied); err != nil {
+		return errors.Wrap(err, "failed to apply the transaction")
+	}
+	return nil
+}
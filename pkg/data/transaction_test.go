@@ -0,0 +1,78 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// transactionCluster is a fake cluster applying the events in transactions.
+type transactionCluster struct {
+	*fakeCluster
+	transactions [][]*Event
+	err          error
+}
+
+func (c *transactionCluster) ApplyTransaction(ctx context.Context, events []*Event) error {
+	c.transactions = append(c.transactions, events)
+	return c.err
+}
+
+func TestApplyAtomic(t *testing.T) {
+	route1 := *route
+	events := []*Event{
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "route1", Uris: []string{"/get"}, ServiceID: "svc"}},
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: &route1},
+	}
+
+	// Test case 1: the events are applied in a single transaction
+	cluster := &transactionCluster{fakeCluster: newFakeCluster()}
+	applyErr, rollbackErr := ApplyAtomic(context.Background(), cluster, events)
+	assert.Nil(t, applyErr, "should not return error")
+	assert.Nil(t, rollbackErr, "should not roll back")
+	assert.Len(t, cluster.transactions, 1, "should apply a single transaction")
+	assert.Equal(t, []*Event{events[1], events[0]}, cluster.transactions[0], "should sort the events and skip the no-ops")
+	assert.Empty(t, cluster.route.calls, "should not apply the events one by one")
+
+	// Test case 2: the error of the transaction
+	cluster = &transactionCluster{fakeCluster: newFakeCluster(), err: errors.New("unexpected status code 409")}
+	applyErr, rollbackErr = ApplyAtomic(context.Background(), cluster, events)
+	assert.EqualError(t, applyErr, "failed to apply the transaction: unexpected status code 409")
+	assert.Nil(t, rollbackErr, "should not roll back")
+
+	// Test case 3: nothing is applied if some events are invalid
+	cluster = &transactionCluster{fakeCluster: newFakeCluster()}
+	applyErr, _ = ApplyAtomic(context.Background(), cluster, append(events,
+		&Event{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{Uris: []string{"/get"}}},
+		&Event{ResourceType: UpstreamResourceType, Option: CreateOption, Value: &types.Upstream{ID: "httpbin"}},
+	))
+	assert.Len(t, multierr.Errors(applyErr), 2, "should return an error for each invalid event")
+	assert.Empty(t, cluster.transactions, "should not apply any transaction")
+
+	// Test case 4: the other clusters are rolled back
+	fake := newFakeCluster()
+	fake.route.err = errors.New("unexpected status code 400; invalid route")
+	applyErr, rollbackErr = ApplyAtomic(context.Background(), fake, events[:2])
+	assert.NotNil(t, applyErr, "should return the apply error")
+	assert.Nil(t, rollbackErr, "should roll back successfully")
+	assert.Equal(t, []string{"create:svc", "delete:svc"}, fake.service.calls)
+
+	// Test case 5: the transactor is found through the wrappers, which
+	// check the version of the cluster
+	cluster = &transactionCluster{fakeCluster: newFakeCluster()}
+	wrapped := NewRateLimitedCluster(NewVersionedCluster(cluster, Version{Major: 3}), NewRateLimiter(RateLimits{Default: 1000}))
+	applyErr, _ = ApplyAtomic(context.Background(), wrapped, events)
+	assert.Nil(t, applyErr, "should not return error")
+	assert.Len(t, cluster.transactions, 1, "should apply a single transaction")
+
+	vault := &types.Secret{ID: "vault/1", URI: "http://127.0.0.1:8200", Prefix: "kv/apisix", Token: "token"}
+	applyErr, _ = ApplyAtomic(context.Background(), wrapped, []*Event{{ResourceType: SecretResourceType, Option: CreateOption, Value: vault}})
+	assert.EqualError(t, applyErr, "secret \"vault/1\": failed to apply secret: secrets are not supported by APISIX 3.0.0, they require APISIX 3.1.0 or later")
+	assert.Len(t, cluster.transactions, 1, "should not apply the transaction")
+}
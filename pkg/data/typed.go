@@ -0,0 +1,142 @@
+package data
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// Resource is the constraint of the Go types of the resources.
+type Resource interface {
+	types.Service | types.Route | types.Consumer | types.SSL | types.GlobalRule |
+		types.PluginConfig | types.ConsumerGroup | types.PluginMetadata |
+		types.StreamRoute | types.Upstream | types.Secret | types.Proto
+}
+
+// resourceTypeOf returns the resource type of the Go type.
+func resourceTypeOf[T Resource]() ResourceType {
+	switch interface{}((*T)(nil)).(type) {
+	case *types.Service:
+		return ServiceResourceType
+	case *types.Route:
+		return RouteResourceType
+	case *types.Consumer:
+		return ConsumerResourceType
+	case *types.SSL:
+		return SSLResourceType
+	case *types.GlobalRule:
+		return GlobalRuleResourceType
+	case *types.PluginConfig:
+		return PluginConfigResourceType
+	case *types.ConsumerGroup:
+		return ConsumerGroupResourceType
+	case *types.PluginMetadata:
+		return PluginMetadataResourceType
+	case *types.StreamRoute:
+		return StreamRouteResourceType
+	case *types.Upstream:
+		return UpstreamResourceType
+	case *types.Secret:
+		return SecretResourceType
+	}
+	return ProtoResourceType
+}
+
+// TypedEvent is an Event whose values have the Go type of the resource,
+// e.g. TypedEvent[types.Route] for routes, so that building and reading
+// events is checked at compile time. Convert it with Event to apply it.
+type TypedEvent[T Resource] struct {
+	Option   Option
+	OldValue *T
+	Value    *T
+}
+
+// NewCreateEvent returns the event creating the value.
+func NewCreateEvent[T Resource](value *T) *TypedEvent[T] {
+	return &TypedEvent[T]{Option: CreateOption, Value: value}
+}
+
+// NewUpdateEvent returns the event updating the old value to the value.
+func NewUpdateEvent[T Resource](oldValue, value *T) *TypedEvent[T] {
+	return &TypedEvent[T]{Option: UpdateOption, OldValue: oldValue, Value: value}
+}
+
+// NewUpsertEvent returns the event creating or updating the value, the old
+// value is the remote resource if known and may be nil.
+func NewUpsertEvent[T Resource](oldValue, value *T) *TypedEvent[T] {
+	return &TypedEvent[T]{Option: UpsertOption, OldValue: oldValue, Value: value}
+}
+
+// NewDeleteEvent returns the event deleting the old value.
+func NewDeleteEvent[T Resource](oldValue *T) *TypedEvent[T] {
+	return &TypedEvent[T]{Option: DeleteOption, OldValue: oldValue}
+}
+
+// ResourceType returns the resource type of T.
+func (e *TypedEvent[T]) ResourceType() ResourceType {
+	return resourceTypeOf[T]()
+}
+
+// Event returns the untyped event, the nil values are nil interfaces like
+// in the events of the differ.
+func (e *TypedEvent[T]) Event() *Event {
+	event := &Event{
+		ResourceType: e.ResourceType(),
+		Option:       e.Option,
+	}
+	if e.OldValue != nil {
+		event.OldValue = e.OldValue
+	}
+	if e.Value != nil {
+		event.Value = e.Value
+	}
+	return event
+}
+
+// ToTypedEvent returns the typed event of the untyped one. An error is
+// returned if the event is of another resource type, or if its values are
+// neither nil nor of type *T.
+func ToTypedEvent[T Resource](event *Event) (*TypedEvent[T], error) {
+	typed := &TypedEvent[T]{Option: event.Option}
+	if event.ResourceType != typed.ResourceType() {
+		return nil, errors.Errorf("invalid %s event: resource type must be %s", event.ResourceType, typed.ResourceType())
+	}
+
+	var err error
+	if typed.OldValue, err = typedValue[T](event.OldValue); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s event: old value", event.ResourceType)
+	}
+	if typed.Value, err = typedValue[T](event.Value); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s event", event.ResourceType)
+	}
+	return typed, nil
+}
+
+func typedValue[T Resource](value interface{}) (*T, error) {
+	if value == nil {
+		return nil, nil
+	}
+	v, ok := value.(*T)
+	if !ok {
+		return nil, errors.Errorf("value must be %T, got %T", v, value)
+	}
+	return v, nil
+}
+
+// TypedEvents returns the events of the resource type of T as typed events,
+// in the same order. The events of other resource types are skipped.
+func TypedEvents[T Resource](events []*Event) ([]*TypedEvent[T], error) {
+	typ := resourceTypeOf[T]()
+	var typed []*TypedEvent[T]
+	for _, event := range events {
+		if event.ResourceType != typ {
+			continue
+		}
+		e, err := ToTypedEvent[T](event)
+		if err != nil {
+			return nil, err
+		}
+		typed = append(typed, e)
+	}
+	return typed, nil
+}
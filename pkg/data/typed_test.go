@@ -0,0 +1,49 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestTypedEvent(t *testing.T) {
+	// Test case 1: typed events are applied as untyped ones
+	cluster := newFakeCluster()
+	events := []*Event{
+		NewCreateEvent(svc).Event(),
+		NewCreateEvent(route).Event(),
+		NewDeleteEvent(&types.Upstream{ID: "httpbin"}).Event(),
+	}
+	assert.Equal(t, &Event{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc}, events[0], "should leave the old value nil")
+	assert.Equal(t, &Event{ResourceType: UpstreamResourceType, Option: DeleteOption, OldValue: &types.Upstream{ID: "httpbin"}}, events[2])
+	cluster.upstream.items["httpbin"] = &types.Upstream{ID: "httpbin"}
+	assert.Nil(t, ApplyAll(context.Background(), cluster, events, 1, false), "should not return error")
+	assert.Equal(t, route, cluster.route.items["route"])
+
+	// Test case 2: untyped events are converted back
+	route1 := *route
+	update := NewUpdateEvent(route, &route1)
+	typed, err := ToTypedEvent[types.Route](update.Event())
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, update, typed)
+	routes, err := TypedEvents[types.Route](events)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []*TypedEvent[types.Route]{NewCreateEvent(route)}, routes, "should only convert the routes")
+
+	// Test case 3: mismatched events
+	_, err = ToTypedEvent[types.Service](events[1])
+	assert.EqualError(t, err, "invalid route event: resource type must be service")
+	_, err = ToTypedEvent[types.Route](&Event{ResourceType: RouteResourceType, Option: CreateOption, Value: types.Route{ID: "route"}})
+	assert.EqualError(t, err, "invalid route event: value must be *types.Route, got types.Route")
+	_, err = ToTypedEvent[types.Route](&Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: svc, Value: route})
+	assert.EqualError(t, err, "invalid route event: old value: value must be *types.Route, got *types.Service")
+}
+
+func TestResourceTypeOf(t *testing.T) {
+	assert.Equal(t, SSLResourceType, resourceTypeOf[types.SSL]())
+	assert.Equal(t, ProtoResourceType, resourceTypeOf[types.Proto]())
+	assert.Equal(t, PluginMetadataResourceType, resourceTypeOf[types.PluginMetadata]())
+}
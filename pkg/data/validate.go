@@ -0,0 +1,183 @@
+package data
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+// validator checks the value of an event has the type of the resource.
+// With required, it also checks the required fields of the resource.
+type validator func(value interface{}, required bool) error
+
+func validatorOf[T any](check func(*T) error) validator {
+	return func(value interface{}, required bool) error {
+		v, ok := value.(*T)
+		if !ok || v == nil {
+			return errors.Errorf("value must be %T, got %T", v, value)
+		}
+		if required && check != nil {
+			return check(v)
+		}
+		return nil
+	}
+}
+
+var validators = map[ResourceType]validator{
+	ServiceResourceType:  validatorOf[types.Service](nil),
+	RouteResourceType:    validatorOf(validateRoute),
+	ConsumerResourceType: validatorOf[types.Consumer](nil),
+	SSLResourceType: validatorOf(func(ssl *types.SSL) error {
+		if ssl.Cert == "" || ssl.Key == "" {
+			return errors.New("cert and key are required")
+		}
+		return validateSSL(ssl)
+	}),
+	GlobalRuleResourceType: validatorOf(func(rule *types.GlobalRule) error {
+		if len(rule.Plugins) == 0 {
+			return errors.New("plugins is required")
+		}
+		return nil
+	}),
+	PluginConfigResourceType: validatorOf(func(conf *types.PluginConfig) error {
+		if conf.Plugins == nil {
+			return errors.New("plugins is required")
+		}
+		return nil
+	}),
+	ConsumerGroupResourceType: validatorOf(func(group *types.ConsumerGroup) error {
+		if group.Plugins == nil {
+			return errors.New("plugins is required")
+		}
+		return nil
+	}),
+	PluginMetadataResourceType: validatorOf[types.PluginMetadata](nil),
+	StreamRouteResourceType:    validatorOf[types.StreamRoute](nil),
+	UpstreamResourceType: validatorOf(func(ups *types.Upstream) error {
+		if len(ups.Nodes) == 0 && ups.ServiceName == "" {
+			return errors.New("nodes or service_name is required")
+		}
+		return nil
+	}),
+	SecretResourceType: validatorOf[types.Secret](nil),
+	ProtoResourceType: validatorOf(func(proto *types.Proto) error {
+		if proto.Content == "" {
+			return errors.New("content is required")
+		}
+		return nil
+	}),
+}
+
+// Validate checks the event can be applied without calling the admin API:
+// the option is known, the values have the type of the resource type,
+// the resource has a unique key and the required fields are set.
+func (e *Event) Validate() error {
+	if err := e.validate(); err != nil {
+		return errors.Wrapf(err, "invalid %s event", e.ResourceType)
+	}
+	return nil
+}
+
+func (e *Event) validate() error {
+	switch e.Option {
+	case CreateOption, UpdateOption, DeleteOption, UpsertOption, NoOpOption:
+	default:
+		return errors.Errorf("unknown option %d", e.Option)
+	}
+
+	validate, ok := validators[e.ResourceType]
+	if !ok {
+		return errors.Errorf("unsupported resource type %q", e.ResourceType)
+	}
+	if e.Option == DeleteOption || e.Option != CreateOption && !isNil(e.OldValue) {
+		if err := validate(e.OldValue, false); err != nil {
+			return errors.Wrap(err, "old value")
+		}
+	}
+	if e.Option != DeleteOption {
+		if err := validate(e.Value, true); err != nil {
+			return err
+		}
+	}
+
+	key, err := e.resourceKey()
+	if err != nil {
+		return errors.Cause(err)
+	}
+	if key == "" {
+		return missingKeyError(e.ResourceType)
+	}
+	return nil
+}
+
+// checkValues checks the event has the values of its option: the value for
+// creates, upserts and noops, the old value for deletes, and both for updates.
+func (e *Event) checkValues() error {
+	switch e.Option {
+	case CreateOption, UpdateOption, DeleteOption, UpsertOption, NoOpOption:
+	default:
+		return errors.Errorf("invalid %s event: unknown option %d", e.ResourceType, e.Option)
+	}
+	if (e.Option == UpdateOption || e.Option == DeleteOption) && isNil(e.OldValue) {
+		return errors.Errorf("invalid %s event: old value is required", e.ResourceType)
+	}
+	if e.Option != DeleteOption && isNil(e.Value) {
+		return errors.Errorf("invalid %s event: value is required", e.ResourceType)
+	}
+	return nil
+}
+
+// missingKeyError is the error of a resource without identifier.
+func missingKeyError(typ ResourceType) error {
+	if identifiers[typ] == "Username" {
+		return errors.New("username is required")
+	}
+	return errors.New("id is required")
+}
+
+// ValidateEvents validates all the events with Event.Validate, the returned
+// error combines an EventError for every invalid event.
+func ValidateEvents(events []*Event) error {
+	var errs error
+	for _, event := range events {
+		if err := event.validate(); err != nil {
+			errs = multierr.Append(errs, &EventError{Event: event, Err: err})
+		}
+	}
+	return errs
+}
+
+// ValidateUnique checks no resource is changed by more than one event, e.g.
+// two services with the same id, where the last one silently wins. The
+// returned error combines an EventError for every duplicated resource.
+func ValidateUnique(events []*Event) error {
+	type resource struct {
+		typ ResourceType
+		key string
+	}
+	var (
+		first  = make(map[resource]*Event)
+		counts = make(map[resource]int)
+		dups   []resource
+	)
+	for _, event := range events {
+		r := resource{typ: event.ResourceType, key: event.key()}
+		counts[r]++
+		switch counts[r] {
+		case 1:
+			first[r] = event
+		case 2:
+			dups = append(dups, r)
+		}
+	}
+
+	var errs error
+	for _, r := range dups {
+		errs = multierr.Append(errs, &EventError{
+			Event: first[r],
+			Err:   errors.Errorf("defined %d times", counts[r]),
+		})
+	}
+	return errs
+}
@@ -0,0 +1,171 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+
+	"github.com/api7/adc/pkg/api/apisix/types"
+)
+
+func TestEventValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *Event
+		err   string
+	}{
+		{
+			name:  "valid create",
+			event: &Event{ResourceType: RouteResourceType, Option: CreateOption, Value: route},
+		},
+		{
+			name:  "valid update without old value",
+			event: &Event{ResourceType: ServiceResourceType, Option: UpdateOption, Value: svc},
+		},
+		{
+			name:  "valid delete",
+			event: &Event{ResourceType: ConsumerResourceType, Option: DeleteOption, OldValue: consumer},
+		},
+		{
+			name:  "unknown option",
+			event: &Event{ResourceType: RouteResourceType, Option: 10, Value: route},
+			err:   "invalid route event: unknown option 10",
+		},
+		{
+			name:  "unknown resource type",
+			event: &Event{ResourceType: "rout", Option: CreateOption, Value: route},
+			err:   "invalid rout event: unsupported resource type \"rout\"",
+		},
+		{
+			name:  "type mismatch",
+			event: &Event{ResourceType: RouteResourceType, Option: CreateOption, Value: svc},
+			err:   "invalid route event: value must be *types.Route, got *types.Service",
+		},
+		{
+			name:  "old value type mismatch",
+			event: &Event{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: svc},
+			err:   "invalid route event: old value: value must be *types.Route, got *types.Service",
+		},
+		{
+			name:  "missing value",
+			event: &Event{ResourceType: ServiceResourceType, Option: CreateOption},
+			err:   "invalid service event: value must be *types.Service, got <nil>",
+		},
+		{
+			name:  "route without uri",
+			event: &Event{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "route"}},
+			err:   "invalid route event: uri or uris is required",
+		},
+		{
+			name:  "upstream without nodes",
+			event: &Event{ResourceType: UpstreamResourceType, Option: UpdateOption, Value: &types.Upstream{ID: "ups"}},
+			err:   "invalid upstream event: nodes or service_name is required",
+		},
+		{
+			name:  "missing username",
+			event: &Event{ResourceType: ConsumerResourceType, Option: CreateOption, Value: &types.Consumer{}},
+			err:   "invalid consumer event: username is required",
+		},
+		{
+			name:  "missing id",
+			event: &Event{ResourceType: ServiceResourceType, Option: CreateOption, Value: &types.Service{Name: "svc"}},
+			err:   "invalid service event: id is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.event.Validate()
+			if tt.err == "" {
+				assert.Nil(t, err, "should not return error")
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestApplyAllValidate(t *testing.T) {
+	events := []*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "route1"}},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: svc},
+	}
+
+	// Test case 1: all the errors are collected and nothing is applied
+	cluster := newFakeCluster()
+	err := ApplyAllWithOptions(context.Background(), cluster, events, ApplyOptions{Validate: true})
+	errs := multierr.Errors(err)
+	assert.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], "route \"route1\": uri or uris is required")
+	assert.EqualError(t, errs[1], "route \"svc\": value must be *types.Route, got *types.Service")
+	assert.Nil(t, cluster.service.calls, "should not apply any event")
+
+	// Test case 2: valid events are applied
+	err = ApplyAllWithOptions(context.Background(), cluster, events[:1], ApplyOptions{Validate: true})
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, []string{"create:svc"}, cluster.service.calls)
+}
+
+func TestValidateUnique(t *testing.T) {
+	users1 := &types.Service{ID: "users-api", Name: "users-api", Hosts: []string{"a.example.com"}}
+	users2 := &types.Service{ID: "users-api", Name: "users-api", Hosts: []string{"b.example.com"}}
+	events := []*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: users1},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: route},
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: users2},
+	}
+
+	// Test case 1: different resource types don't conflict
+	assert.Nil(t, ValidateUnique(events[:2]), "should not return error")
+	assert.Nil(t, ValidateUnique([]*Event{
+		{ResourceType: ServiceResourceType, Option: CreateOption, Value: svc},
+		{ResourceType: RouteResourceType, Option: CreateOption, Value: &types.Route{ID: "svc", Uris: []string{"/"}}},
+	}), "should not return error")
+
+	// Test case 2: duplicates are listed
+	events = append(events,
+		&Event{ResourceType: ServiceResourceType, Option: UpdateOption, Value: users1},
+		&Event{ResourceType: RouteResourceType, Option: DeleteOption, OldValue: route},
+	)
+	err := ValidateUnique(events)
+	errs := multierr.Errors(err)
+	assert.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], "service \"users-api\": defined 3 times")
+	assert.EqualError(t, errs[1], "route \"route\": defined 2 times")
+
+	// Test case 3: ApplyAll rejects them
+	cluster := newFakeCluster()
+	err = ApplyAllWithOptions(context.Background(), cluster, events[:3], ApplyOptions{Validate: true})
+	assert.EqualError(t, multierr.Errors(err)[0], "service \"users-api\": defined 2 times")
+	assert.Nil(t, cluster.service.calls, "should not apply any event")
+}
+
+func TestNewEvents(t *testing.T) {
+	// Test case 1: valid events
+	event, err := NewCreate(RouteResourceType, route)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, &Event{ResourceType: RouteResourceType, Option: CreateOption, Value: route}, event)
+	route1 := *route
+	event, err = NewUpdate(RouteResourceType, route, &route1)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, &Event{ResourceType: RouteResourceType, Option: UpdateOption, OldValue: route, Value: &route1}, event)
+	event, err = NewDelete(ServiceResourceType, svc)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, &Event{ResourceType: ServiceResourceType, Option: DeleteOption, OldValue: svc}, event)
+
+	// Test case 2: missing values
+	_, err = NewUpdate(RouteResourceType, nil, route)
+	assert.EqualError(t, err, "invalid route event: old value is required")
+	_, err = NewDelete(RouteResourceType, (*types.Route)(nil))
+	assert.EqualError(t, err, "invalid route event: old value is required")
+	_, err = NewCreate(ConsumerResourceType, nil)
+	assert.EqualError(t, err, "invalid consumer event: value is required")
+
+	// Test case 3: invalid values
+	_, err = NewCreate(RouteResourceType, svc)
+	assert.EqualError(t, err, "invalid route event: value must be *types.Route, got *types.Service")
+	_, err = NewCreate(RouteResourceType, &types.Route{ID: "route"})
+	assert.NotNil(t, err, "should check the required fields")
+}
@@ -0,0 +1,122 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/api7/adc/pkg/api/apisix"
+)
+
+// Version is the version of APISIX.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Less reports whether v is older than other.
+func (v Version) Less(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+// ParseVersion parses versions like "3.8.0", the missing minor and patch
+// numbers are 0 and the suffixes of pre-releases like "3.0.0-beta" are
+// ignored.
+func ParseVersion(s string) (Version, error) {
+	core, _, _ := strings.Cut(s, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return Version{}, errors.Errorf("invalid version %q", s)
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, errors.Errorf("invalid version %q", s)
+		}
+		numbers[i] = n
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// MinVersion is the oldest version of APISIX supported by adc, the admin
+// API of APISIX 2.x is used for the versions older than 3.0, see
+// apisix.AdminAPISetter.
+var MinVersion = Version{Major: 2}
+
+// minVersions are the versions of APISIX adding the resource types newer
+// than MinVersion.
+var minVersions = map[ResourceType]Version{
+	ConsumerGroupResourceType: {Major: 3},
+	SecretResourceType:        {Major: 3, Minor: 1},
+}
+
+// DetectVersion returns the version of APISIX running the cluster, which
+// must implement apisix.VersionGetter, or wrap one that does.
+func DetectVersion(ctx context.Context, cluster apisix.Cluster) (Version, error) {
+	getter, ok := findCluster[apisix.VersionGetter](cluster)
+	if !ok {
+		return Version{}, errors.New("the cluster doesn't report its version")
+	}
+	s, err := getter.Version(ctx)
+	if err != nil {
+		return Version{}, errors.Wrap(err, "failed to detect the version of APISIX")
+	}
+	return ParseVersion(s)
+}
+
+// CheckResourceType returns an error if the resource type is not supported
+// by the version of APISIX.
+func (v Version) CheckResourceType(typ ResourceType) error {
+	if v.Less(MinVersion) {
+		return errors.Errorf("APISIX %s is not supported, adc requires APISIX %s or later", v, MinVersion)
+	}
+	if min, ok := minVersions[typ]; ok && v.Less(min) {
+		return errors.Errorf("%s are not supported by APISIX %s, they require APISIX %s or later", pluralName(typ), v, min)
+	}
+	return nil
+}
+
+// versionedCluster is a cluster of a known version of APISIX.
+type versionedCluster struct {
+	apisix.Cluster
+
+	version Version
+}
+
+// NewVersionedCluster wraps the cluster of the version of APISIX, e.g. the
+// one of DetectVersion, so that Apply and ApplyAll refuse to apply the
+// resource types this version doesn't support, instead of failing with a
+// confusing 404.
+func NewVersionedCluster(cluster apisix.Cluster, version Version) apisix.Cluster {
+	return &versionedCluster{Cluster: cluster, version: version}
+}
+
+// Unwrap returns the wrapped cluster, see Unwrapper.
+func (c *versionedCluster) Unwrap() apisix.Cluster {
+	return c.Cluster
+}
+
+// checkVersion returns an error if the cluster, or a cluster it wraps, is of
+// a version of APISIX which doesn't support the resource type of the event.
+func (e *Event) checkVersion(cluster apisix.Cluster) error {
+	versioned, ok := findCluster[*versionedCluster](cluster)
+	if !ok {
+		return nil
+	}
+	return versioned.version.CheckResourceType(e.ResourceType)
+}
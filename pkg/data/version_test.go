@@ -0,0 +1,163 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/adc/pkg/api/apisix"
+	"github.com/api7/adc/pkg/api/apisix/types"
+	"github.com/api7/adc/pkg/config"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    Version
+		err     string
+	}{
+		{version: "3.8.0", want: Version{Major: 3, Minor: 8}},
+		{version: "3.1", want: Version{Major: 3, Minor: 1}},
+		{version: "3.0.0-beta", want: Version{Major: 3}},
+		{version: "2.15.3", want: Version{Major: 2, Minor: 15, Patch: 3}},
+		{version: "3.x", err: `invalid version "3.x"`},
+		{version: "", err: `invalid version ""`},
+		{version: "1.2.3.4", err: `invalid version "1.2.3.4"`},
+	}
+	for _, tc := range tests {
+		got, err := ParseVersion(tc.version)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err)
+			continue
+		}
+		assert.Nil(t, err, "should not return error")
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestCheckResourceType(t *testing.T) {
+	// Test case 1: the resource types added later
+	v := Version{Major: 3, Minor: 0, Patch: 2}
+	assert.Nil(t, v.CheckResourceType(RouteResourceType))
+	assert.EqualError(t, v.CheckResourceType(SecretResourceType), "secrets are not supported by APISIX 3.0.2, they require APISIX 3.1.0 or later")
+	assert.Nil(t, Version{Major: 3, Minor: 1}.CheckResourceType(SecretResourceType))
+
+	// Test case 2: APISIX 2.x
+	v = Version{Major: 2, Minor: 15}
+	assert.Nil(t, v.CheckResourceType(RouteResourceType))
+	assert.EqualError(t, v.CheckResourceType(ConsumerGroupResourceType), "consumer groups are not supported by APISIX 2.15.0, they require APISIX 3.0.0 or later")
+	assert.EqualError(t, Version{Major: 1, Minor: 5}.CheckResourceType(RouteResourceType), "APISIX 1.5.0 is not supported, adc requires APISIX 2.0.0 or later")
+}
+
+type versionCluster struct {
+	*fakeCluster
+	version string
+}
+
+func (c *versionCluster) Version(context.Context) (string, error) {
+	return c.version, nil
+}
+
+func TestVersionedCluster(t *testing.T) {
+	// Test case 1: detect the version
+	fake := &versionCluster{fakeCluster: newFakeCluster(), version: "3.0.0"}
+	version, err := DetectVersion(context.Background(), fake)
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, Version{Major: 3}, version)
+	_, err = DetectVersion(context.Background(), newFakeCluster())
+	assert.EqualError(t, err, "the cluster doesn't report its version")
+
+	// Test case 2: the unsupported resource types are refused
+	cluster := NewVersionedCluster(fake, version)
+	vault := &types.Secret{ID: "vault/1", URI: "http://127.0.0.1:8200", Prefix: "kv/apisix", Token: "token"}
+	secret := &Event{ResourceType: SecretResourceType, Option: CreateOption, Value: vault}
+	err = secret.Apply(context.Background(), cluster)
+	assert.EqualError(t, err, "failed to apply secret: secrets are not supported by APISIX 3.0.0, they require APISIX 3.1.0 or later")
+	assert.Empty(t, fake.secret.calls, "should not apply the secret")
+
+	// Test case 3: the others are applied
+	assert.Nil(t, ApplyAll(context.Background(), cluster, routeEvents(1), 1, false), "should not return error")
+	assert.Len(t, fake.route.items, 1)
+
+	// Test case 4: the version is found through the other wrappers
+	limiter := NewRateLimiter(RateLimits{Default: 1000})
+	version, err = DetectVersion(context.Background(), NewRateLimitedCluster(fake, limiter))
+	assert.Nil(t, err, "should not return error")
+	assert.Equal(t, Version{Major: 3}, version)
+	wrapped := NewRateLimitedCluster(NewVersionedCluster(fake, version), limiter)
+	err = secret.Apply(context.Background(), wrapped)
+	assert.EqualError(t, err, "failed to apply secret: secrets are not supported by APISIX 3.0.0, they require APISIX 3.1.0 or later")
+	assert.Empty(t, fake.secret.calls, "should not apply the secret")
+}
+
+// newAdminAPIServer returns a mock of the admin API of the major version of
+// APISIX, recording the requests.
+func newAdminAPIServer(major int, requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Server", fmt.Sprintf("APISIX/%d.0.0", major))
+		key := strings.TrimPrefix(r.URL.Path, "/apisix/admin")
+		switch r.Method {
+		case http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Key not found"}`)
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			if major == 2 {
+				fmt.Fprintf(w, `{"action":"set","node":{"key":"/apisix%s","value":%s}}`, key, body)
+			} else {
+				fmt.Fprintf(w, `{"key":"/apisix%s","value":%s}`, key, body)
+			}
+		}
+	}))
+}
+
+func TestApplyAdminAPIVersions(t *testing.T) {
+	events := []*Event{
+		routeEvents(1)[0],
+		{ResourceType: RouteResourceType, Option: UpsertOption, Value: route},
+		{ResourceType: ProtoResourceType, Option: CreateOption, Value: &types.Proto{ID: "proto", Content: "syntax = \"proto3\";"}},
+	}
+	for _, tc := range []struct {
+		version string
+		want    []string
+	}{
+		{
+			version: "2.15.3",
+			want: []string{
+				"PUT /apisix/admin/routes/route0",
+				"GET /apisix/admin/routes/route",
+				"PUT /apisix/admin/routes/route",
+				"PUT /apisix/admin/proto/proto",
+			},
+		},
+		{
+			version: "3.8.0",
+			want: []string{
+				"PUT /apisix/admin/routes/route0",
+				"GET /apisix/admin/routes/route",
+				"PUT /apisix/admin/routes/route",
+				"PUT /apisix/admin/protos/proto",
+			},
+		},
+	} {
+		version, err := ParseVersion(tc.version)
+		assert.Nil(t, err, "should not return error")
+		var requests []string
+		server := newAdminAPIServer(version.Major, &requests)
+
+		cluster, err := apisix.NewCluster(context.Background(), config.ClientConfig{Server: server.URL, Version: tc.version})
+		assert.Nil(t, err, "should not return error")
+		for _, event := range events {
+			assert.Nil(t, event.Apply(context.Background(), NewVersionedCluster(cluster, version)), "should apply %s against APISIX %s", event.ResourceType, tc.version)
+		}
+		assert.Equal(t, tc.want, requests, "APISIX %s", tc.version)
+		server.Close()
+	}
+}
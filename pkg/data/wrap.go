@@ -0,0 +1,31 @@
+package data
+
+import (
+	"github.com/api7/adc/pkg/api/apisix"
+)
+
+// Unwrapper is implemented by the clusters wrapping another one, like the
+// ones of NewRateLimitedCluster and NewVersionedCluster. The version and
+// the optional interfaces of the wrapped cluster, e.g. BulkWriter,
+// Transactor and apisix.VersionGetter, are looked up through the wrappers,
+// so that wrapping a cluster doesn't disable them.
+type Unwrapper interface {
+	// Unwrap returns the wrapped cluster.
+	Unwrap() apisix.Cluster
+}
+
+// findCluster returns the first cluster of type T in the chain of wrappers
+// starting at cluster, see Unwrapper.
+func findCluster[T any](cluster apisix.Cluster) (T, bool) {
+	for {
+		if found, ok := cluster.(T); ok {
+			return found, true
+		}
+		wrapper, ok := cluster.(Unwrapper)
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		cluster = wrapper.Unwrap()
+	}
+}
@@ -0,0 +1,77 @@
+// Package tracing lets the callers trace the changes applied by adc with
+// the tracer of their choice, e.g. OpenTelemetry, without adc depending on
+// it. Spans are only created when a Tracer is set in the context.
+package tracing
+
+import (
+	"context"
+)
+
+// Attribute is a key and value describing a span.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// String returns the attribute of the key and value.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a traced operation.
+type Span interface {
+	// SetAttributes adds the attributes to the span.
+	SetAttributes(attrs ...Attribute)
+	// RecordError records the error of the operation, and marks the span
+	// as failed.
+	RecordError(err error)
+	// End ends the span.
+	End()
+}
+
+// Tracer starts the spans, the returned context carries the span so that
+// the spans started from it are its children.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type tracerKey struct{}
+
+// ContextWithTracer returns a copy of ctx with the tracer.
+func ContextWithTracer(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// TracerFromContext returns the tracer of ctx, or nil if there is none.
+func TracerFromContext(ctx context.Context) Tracer {
+	tracer, _ := ctx.Value(tracerKey{}).(Tracer)
+	return tracer
+}
+
+// Start starts a span with the attributes using the tracer of ctx. Without
+// a tracer, it returns ctx and a span doing nothing.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	tracer := TracerFromContext(ctx)
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := tracer.Start(ctx, name)
+	if len(attrs) > 0 {
+		span.SetAttributes(attrs...)
+	}
+	return ctx, span
+}
+
+// End records err if not nil, and ends the span.
+func End(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
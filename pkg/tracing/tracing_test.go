@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSpan struct {
+	name   string
+	attrs  []Attribute
+	err    error
+	ended  bool
+	parent *fakeSpan
+}
+
+func (s *fakeSpan) SetAttributes(attrs ...Attribute) { s.attrs = append(s.attrs, attrs...) }
+func (s *fakeSpan) RecordError(err error)            { s.err = err }
+func (s *fakeSpan) End()                             { s.ended = true }
+
+type spanKey struct{}
+
+type fakeTracer struct {
+	spans []*fakeSpan
+}
+
+func (t *fakeTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(spanKey{}).(*fakeSpan)
+	span := &fakeSpan{name: name, parent: parent}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func TestStart(t *testing.T) {
+	// Test case 1: no tracer
+	ctx := context.Background()
+	spanCtx, span := Start(ctx, "apply", String("name", "route"))
+	assert.Equal(t, ctx, spanCtx, "should return the same context")
+	End(span, errors.New("failed"))
+
+	// Test case 2: the spans are nested
+	tracer := &fakeTracer{}
+	ctx = ContextWithTracer(context.Background(), tracer)
+	assert.Equal(t, tracer, TracerFromContext(ctx))
+	parentCtx, parent := Start(ctx, "apply_all")
+	_, child := Start(parentCtx, "apply", String("name", "route"))
+	End(child, errors.New("failed"))
+	End(parent, nil)
+
+	assert.Len(t, tracer.spans, 2)
+	assert.Equal(t, tracer.spans[0], tracer.spans[1].parent, "should be a child span")
+	assert.Equal(t, []Attribute{{Key: "name", Value: "route"}}, tracer.spans[1].attrs)
+	assert.EqualError(t, tracer.spans[1].err, "failed")
+	assert.Nil(t, tracer.spans[0].err)
+	assert.True(t, tracer.spans[0].ended && tracer.spans[1].ended, "should end the spans")
+}